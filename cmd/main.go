@@ -2,45 +2,97 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"flag"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"time"
 
 	"github.com/Perezonance/article-management-service/internal/controllers"
 	"github.com/Perezonance/article-management-service/internal/server"
 	"github.com/Perezonance/article-management-service/internal/storage"
 	l "github.com/Perezonance/article-management-service/internal/util/logger"
-	"github.com/gorilla/mux"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
+//storageConfig holds the command line options used to build a storage backend
+type storageConfig struct {
+	backend        string
+	dynamoTable    string
+	dynamoEndpoint string
+	sqlDSN         string
+	autoMigrate    bool
+	boltPath       string
+}
+
 func main() {
-	var wait time.Duration
+	var (
+		wait          time.Duration
+		schedule      time.Duration
+		purge         time.Duration
+		viewFlush     time.Duration
+		retentionDays int
+		sc            storageConfig
+	)
 	flag.DurationVar(&wait, "graceful-timeout", time.Second*15, "the duration for which the server gracefully wait for existing connections to finish - e.g. 15s or 1m")
+	flag.DurationVar(&schedule, "schedule-interval", time.Second*30, "how often due scheduled publishing and unpublishing is applied - e.g. 30s or 1m")
+	flag.DurationVar(&purge, "purge-interval", time.Hour, "how often trashed articles past their retention are purged - e.g. 1h")
+	flag.DurationVar(&viewFlush, "view-flush-interval", time.Second*10, "how often counted article views are written to storage - e.g. 10s")
+	flag.IntVar(&retentionDays, "trash-retention-days", 30, "the number of days a deleted article stays in the trash before it is purged")
+	flag.StringVar(&sc.backend, "storage", "mock", "the storage backend to use - mock, dynamo, sqlite, postgres or bolt")
+	flag.StringVar(&sc.dynamoTable, "dynamo-table", storage.DefaultArticlesTable, "the DynamoDB table holding articles")
+	flag.StringVar(&sc.dynamoEndpoint, "dynamo-endpoint", "", "overrides the DynamoDB endpoint, e.g. http://localhost:8000 for DynamoDB Local")
+	flag.StringVar(&sc.sqlDSN, "sql-dsn", "articles.db", "the data source name for the sqlite or postgres backend")
+	flag.BoolVar(&sc.autoMigrate, "auto-migrate", true, "apply pending schema migrations on startup for the sqlite, postgres or dynamo backend")
+	flag.StringVar(&sc.boltPath, "bolt-path", "articles.bolt", "the data file for the bolt backend")
 	flag.Parse()
-	r := mux.NewRouter()
 
-	db := storage.NewMockDynamo()
+	//`migrate` subcommand applies schema migrations and exits without serving
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrations(sc); err != nil {
+			l.ErrorLog("Failed to apply migrations", err)
+			os.Exit(1)
+		}
+		l.InfoLog("Migrations applied")
+		os.Exit(0)
+	}
+
+	db, err := newStorage(sc)
+	if err != nil {
+		l.ErrorLog("Failed to initialize storage", err)
+		os.Exit(1)
+	}
 
 	s := server.NewServer(db)
+	indexed, err := s.RebuildSearchIndex(context.Background())
+	if err != nil {
+		l.ErrorLog("Failed to build search index", err)
+		os.Exit(1)
+	}
+	l.InfoLog(fmt.Sprintf("Search index built with %v articles", indexed))
 
 	c := controllers.NewController(s)
 
-	r.HandleFunc("/articles", c.GetArticlesHandler).Methods(http.MethodGet)
-	r.HandleFunc("/articles", c.PostArticleHandler).Methods(http.MethodPost)
-
-	r.HandleFunc("/articles/{articleID}", c.GetArticleByIDHandler).Methods(http.MethodGet)
-	r.HandleFunc("/articles/{articleID}", c.UpdateArticleByIDHandler).Methods(http.MethodPut)
-	r.HandleFunc("/articles/{articleID}", c.DeleteArticleByIDHandler).Methods(http.MethodDelete)
-
-	r.HandleFunc("/articles/{userID}", c.GetArticleByUserIDHandler).Methods(http.MethodGet)
+	r := controllers.NewRouter(c)
 
 	l.InfoLog("Server Initialized")
 	//Graceful shut down procedure...
+	//Request contexts derive from baseCtx so in-flight storage calls are aborted once the
+	//graceful shutdown window has passed
+	baseCtx, cancelBase := context.WithCancel(context.Background())
 	srv := &http.Server{
-		Addr:    "0.0.0.0:8081",
-		Handler: r,
+		Addr:        "0.0.0.0:8081",
+		Handler:     r,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
 	}
 	go func() {
 		if err := srv.ListenAndServe(); err != nil {
@@ -48,6 +100,22 @@ func main() {
 		}
 	}()
 
+	//Background workers stop with baseCtx and are waited on before the storage is closed
+	var workers sync.WaitGroup
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		s.RunScheduler(baseCtx, schedule)
+	}()
+	go func() {
+		defer workers.Done()
+		s.RunPurger(baseCtx, purge, time.Duration(retentionDays)*24*time.Hour)
+	}()
+	go func() {
+		defer workers.Done()
+		s.RunViewFlusher(baseCtx, viewFlush)
+	}()
+
 	ch := make(chan os.Signal, 1)
 
 	signal.Notify(ch, os.Interrupt)
@@ -59,5 +127,111 @@ func main() {
 	defer cancel()
 
 	srv.Shutdown(ctx)
+	cancelBase()
+	workers.Wait()
+	if closer, ok := db.(io.Closer); ok {
+		closer.Close()
+	}
 	os.Exit(0)
 }
+
+//newStorage builds the storage backend selected on the command line
+func newStorage(sc storageConfig) (storage.Storage, error) {
+	switch sc.backend {
+	case "mock":
+		return storage.NewMockDynamo(), nil
+	case "dynamo":
+		db, err := openDynamo(sc)
+		if err != nil {
+			return nil, err
+		}
+		if sc.autoMigrate {
+			if err := migrateDynamo(context.Background(), db); err != nil {
+				return nil, err
+			}
+		} else if sc.dynamoEndpoint != "" {
+			//Local stand-ins start empty so create the table on startup
+			if err := db.CreateTable(context.Background()); err != nil {
+				return nil, err
+			}
+		}
+		return db, nil
+	case storage.DialectSQLite, storage.DialectPostgres:
+		db, err := openSQL(sc)
+		if err != nil {
+			return nil, err
+		}
+		if sc.autoMigrate {
+			if err := storage.Migrate(context.Background(), db, sc.backend); err != nil {
+				return nil, err
+			}
+		}
+		return storage.NewSQLStorage(db, sc.backend), nil
+	case "bolt":
+		return storage.NewBoltStorage(sc.boltPath)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", sc.backend)
+	}
+}
+
+//runMigrations applies the schema migrations for the configured SQL or DynamoDB backend
+func runMigrations(sc storageConfig) error {
+	if sc.backend == "dynamo" {
+		db, err := openDynamo(sc)
+		if err != nil {
+			return err
+		}
+		return migrateDynamo(context.Background(), db)
+	}
+	db, err := openSQL(sc)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return storage.Migrate(context.Background(), db, sc.backend)
+}
+
+func openDynamo(sc storageConfig) (*storage.DynamoStorage, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		if sc.dynamoEndpoint != "" {
+			o.BaseEndpoint = aws.String(sc.dynamoEndpoint)
+		}
+	})
+	return storage.NewDynamoStorage(client, sc.dynamoTable), nil
+}
+
+//migrateDynamo creates the tables and indexes missing from older releases and backfills the
+//articles those releases stored
+func migrateDynamo(ctx context.Context, db *storage.DynamoStorage) error {
+	if err := db.CreateTable(ctx); err != nil {
+		return err
+	}
+	n, err := db.Backfill(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		l.InfoLog(fmt.Sprintf("Backfilled %v articles stored by an older release", n))
+	}
+	return nil
+}
+
+func openSQL(sc storageConfig) (*sql.DB, error) {
+	driver, ok := storage.SQLDrivers[sc.backend]
+	if !ok {
+		return nil, fmt.Errorf("storage backend %q is not a SQL backend", sc.backend)
+	}
+	db, err := sql.Open(driver, sc.sqlDSN)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -3,9 +3,12 @@ package controllers
 import (
 	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Perezonance/article-management-service/internal/models"
 	"github.com/Perezonance/article-management-service/internal/server"
@@ -24,8 +27,9 @@ func NewController(s *server.Server) *Controller {
 	return &Controller{s: s}
 }
 
-//GetArticlesHandler processes request and calls server to fetch all articles
-//GET /articles
+//GetArticlesHandler processes request and calls server to fetch a page of articles or the
+//articles with the given ids
+//GET /articles?userID=1&status=published&tag=go&category=news&createdAfter=t&updatedBefore=t&sort=-createdAt&limit=20&cursor=c
 //GET /articles?ids=1,3,127, 13048203
 func (c *Controller) GetArticlesHandler(w http.ResponseWriter, r *http.Request) {
 	var (
@@ -36,63 +40,50 @@ func (c *Controller) GetArticlesHandler(w http.ResponseWriter, r *http.Request)
 
 	log.DebugLog(fmt.Sprintf("Number of Ids requested:%v", len(ids)))
 
-	for i, s := range ids {
-		intIDs[i], _ = strconv.Atoi(s)
+	if len(ids) == 1 && ids[0] == "" {
+		log.InfoLog("Request recieved: returning page of articles.")
+		c.writeArticlePage(w, r, storage.PageQuery{})
+		return
 	}
 
-	if len(ids) == 1 && intIDs[0] == 0 {
-		log.InfoLog("Request recieved: returning all articles.")
-		a, err := c.s.GetArticles()
+	for i, s := range ids {
+		id, err := strconv.Atoi(strings.TrimSpace(s))
 		if err != nil {
-			if err == storage.ErrResourceNotFound {
-				log.ErrorLog("Article not found 404 Response", err)
-				writeRes(http.StatusNotFound, http.StatusText(http.StatusNotFound), w)
-				return
-			}
-			log.ErrorLog("Error while retrieving articles", err)
-			writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+			log.ErrorLog(fmt.Sprintf("Error while parsing ids query parameter:%v", s), err)
+			writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("ids must be a comma separated list of integers, got %q", s))
 			return
 		}
-		log.DebugLog(fmt.Sprintf("Request processing: retrieved all articles"))
-		arts = a
-	} else if len(ids) > 1 {
+		intIDs[i] = id
+	}
+
+	if len(ids) > 1 {
 		log.InfoLog(fmt.Sprintf("Request recieved: returning articles for ids:%v", intIDs))
 
-		a, err := c.s.GetArticlesByIDs(intIDs)
+		a, err := c.s.GetArticlesByIDs(r.Context(), intIDs)
 		if err != nil {
-			if err == storage.ErrResourceNotFound {
-				log.ErrorLog("Article not found 404 Response", err)
-				writeRes(http.StatusNotFound, http.StatusText(http.StatusNotFound), w)
-				return
-			}
 			log.ErrorLog("Error while retrieving articles", err)
-			writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+			writeError(w, r, err)
 			return
 		}
 		log.InfoLog(fmt.Sprintf("Request processing: retrieved articles for ids:%v", intIDs))
 		arts = a
 	} else {
 		log.InfoLog(fmt.Sprintf("Request recieved: returning articles for ids:%v", intIDs))
-		a, err := c.s.GetArticleByID(intIDs[0])
+		a, err := c.s.GetArticleByID(r.Context(), intIDs[0])
 		if err != nil {
-			if err == storage.ErrResourceNotFound {
-				log.ErrorLog("Article not found 404 Response", err)
-				writeRes(http.StatusNotFound, http.StatusText(http.StatusNotFound), w)
-				return
-			}
 			log.ErrorLog("Error while retrieving articles", err)
-			writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+			writeError(w, r, err)
 			return
-
 		}
-		log.InfoLog(fmt.Sprintf("Request processing: retrieved all articles:"))
-		arts[0] = a
+		log.InfoLog(fmt.Sprintf("Request processing: retrieved article for id:%v", intIDs[0]))
+		arts = []models.Article{a}
 	}
 
+	c.withReactions(r, arts)
 	res, err := json.Marshal(arts)
 	if err != nil {
 		log.ErrorLog("Error while marshaling response", err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		writeError(w, r, err)
 		return
 	}
 	writeRes(http.StatusOK, string(res), w)
@@ -107,20 +98,31 @@ func (c *Controller) PostArticleHandler(w http.ResponseWriter, r *http.Request)
 
 	log.InfoLog("Request recieved: creating new article(s).")
 
-	err := json.NewDecoder(r.Body).Decode(&a)
-	if err != nil {
-		log.ErrorLog("Error while decoding request payload", err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+	if !decodeBody(w, r, &a) {
 		return
 	}
 	log.InfoLog(fmt.Sprintf("decoded request payload:\n%v", a))
 
+	if len(a) == 0 {
+		log.ErrorLog("Error while validating request payload", fmt.Errorf("no articles in payload"))
+		writeProblem(w, r, http.StatusBadRequest, problemTypeMalformedBody, "request body must contain at least one article")
+		return
+	}
+
+	payloads := make([]interface{}, len(a))
+	for i := range a {
+		payloads[i] = a[i]
+	}
+	if !validate(w, r, true, payloads...) {
+		return
+	}
+
 	if len(a) > 1 {
 		log.DebugLog("multiple articles input...")
-		aIDs, err := c.s.CreateArticles(a)
+		aIDs, err := c.s.CreateArticles(r.Context(), a)
 		if err != nil {
 			log.ErrorLog(fmt.Sprintf("Error while creating new article: payload\n%v\n", a), err)
-			writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+			writeError(w, r, err)
 			return
 		}
 
@@ -129,7 +131,7 @@ func (c *Controller) PostArticleHandler(w http.ResponseWriter, r *http.Request)
 		res, err := json.Marshal(aIDs)
 		if err != nil {
 			log.ErrorLog("Error while marshaling response", err)
-			writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+			writeError(w, r, err)
 			return
 		}
 
@@ -139,10 +141,10 @@ func (c *Controller) PostArticleHandler(w http.ResponseWriter, r *http.Request)
 	} else {
 		log.DebugLog("single article input...")
 
-		aID, err := c.s.CreateArticle(a[0])
+		aID, err := c.s.CreateArticle(r.Context(), a[0])
 		if err != nil {
 			log.ErrorLog(fmt.Sprintf("Error while creating new article: payload\n%v\n", a), err)
-			writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+			writeError(w, r, err)
 			return
 		}
 
@@ -151,7 +153,7 @@ func (c *Controller) PostArticleHandler(w http.ResponseWriter, r *http.Request)
 		res, err := json.Marshal(aID)
 		if err != nil {
 			log.ErrorLog("Error while marshaling response", err)
-			writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+			writeError(w, r, err)
 			return
 		}
 
@@ -162,39 +164,60 @@ func (c *Controller) PostArticleHandler(w http.ResponseWriter, r *http.Request)
 	return
 }
 
-//GetArticleByIDHandler processes request and makes server call to fetch an article with given artID
-//GET /articles/{articleID}
+//GetArticleByIDHandler processes request and makes server call to fetch an article with given
+//artID, counting a view when it is served. The article is rendered as HTML when asked for with
+//format=html or an Accept header preferring text/html
+//GET /articles/{articleID}?format=html
 func (c *Controller) GetArticleByIDHandler(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	artID, err := strconv.Atoi(params["articleID"])
 	if err != nil {
 		log.ErrorLog("Error while parsing path URL", err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("articleID must be an integer, got %q", params["articleID"]))
 		return
 	}
 
 	log.InfoLog(fmt.Sprintf("Request received: retrieving article with id%v", artID))
+	asHTML, ok := wantsHTML(w, r)
+	if !ok {
+		return
+	}
 
-	art, err := c.s.GetArticleByID(artID)
+	art, err := c.s.GetArticleByID(r.Context(), artID)
 	if err != nil {
-		if err == storage.ErrResourceNotFound {
-			log.ErrorLog(fmt.Sprintf("Error while retrieving article with id:%v", artID), err)
-			writeRes(http.StatusNotFound, http.StatusText(http.StatusNotFound), w)
-			return
-		}
 		log.ErrorLog(fmt.Sprintf("Error while retrieving article with id:%v", artID), err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		writeError(w, r, err)
 		return
 	}
+	c.writeArticleRead(w, r, art, asHTML)
+}
 
-	res, err := json.Marshal(art)
+//GetArticleBySlugHandler processes request and makes server call to fetch the article holding
+//the given slug, counting a view when it is served. Slugs an article held before its title
+//changed redirect to its current one
+//GET /articles/by-slug/{slug}
+func (c *Controller) GetArticleBySlugHandler(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+
+	log.InfoLog(fmt.Sprintf("Request received: retrieving article with slug:%q", slug))
+	asHTML, ok := wantsHTML(w, r)
+	if !ok {
+		return
+	}
+
+	art, err := c.s.GetArticleBySlug(r.Context(), slug)
 	if err != nil {
-		log.ErrorLog("Error while marshaling response", err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		log.ErrorLog(fmt.Sprintf("Error while retrieving article with slug:%q", slug), err)
+		writeError(w, r, err)
 		return
 	}
-	writeRes(http.StatusOK, string(res), w)
-	return
+
+	if art.Slug != slug {
+		loc := url.URL{Path: "/articles/by-slug/" + art.Slug, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, loc.String(), http.StatusMovedPermanently)
+		return
+	}
+	c.writeArticleRead(w, r, art, asHTML)
 }
 
 //UpdateArticleByIDHandler processes request and makes server call to update an article with given artID
@@ -204,7 +227,7 @@ func (c *Controller) UpdateArticleByIDHandler(w http.ResponseWriter, r *http.Req
 	artID, err := strconv.Atoi(params["articleID"])
 	if err != nil {
 		log.ErrorLog("Error while parsing path URL", err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("articleID must be an integer, got %q", params["articleID"]))
 		return
 	}
 
@@ -212,88 +235,293 @@ func (c *Controller) UpdateArticleByIDHandler(w http.ResponseWriter, r *http.Req
 
 	log.InfoLog(fmt.Sprintf("Request received: updating article with id%v\n", artID))
 
-	err = json.NewDecoder(r.Body).Decode(&a)
-	if err != nil {
-		log.ErrorLog("Error while decoding request payload", err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+	if !decodeBody(w, r, &a) {
 		return
 	}
 
-	err = c.s.UpdateArticle(a)
+	//The path identifies the article, a body naming a different one is rejected
+	if a.ArticleID != 0 && a.ArticleID != artID {
+		log.ErrorLog(fmt.Sprintf("Error while updating article with id:%v", artID), fmt.Errorf("payload articleID %v does not match path", a.ArticleID))
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, "articleID in the request body does not match the path")
+		return
+	}
+	a.ArticleID = artID
+
+	if !validate(w, r, false, a) {
+		return
+	}
+
+	//A version from the preconditions overrides the one in the body
+	version, ok := c.expectedVersion(w, r, artID)
+	if !ok {
+		return
+	}
+	if version != storage.AnyVersion {
+		a.Version = version
+	}
+
+	art, err := c.s.UpdateArticle(r.Context(), a)
 	if err != nil {
 		log.ErrorLog(fmt.Sprintf("Error while updating article with id:%v", artID), err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		writeError(w, r, err)
 		return
 	}
+	c.writeArticle(w, r, http.StatusAccepted, art)
+}
 
-	art, err := c.s.GetArticleByID(artID)
+//PatchArticleByIDHandler processes request and makes server call to apply a JSON Merge Patch or
+//JSON Patch document to an article with given artID
+//PATCH /articles/{articleID}
+func (c *Controller) PatchArticleByIDHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	artID, err := strconv.Atoi(params["articleID"])
 	if err != nil {
-		log.ErrorLog(fmt.Sprintf("Error while returning article with id:%v", artID), err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		log.ErrorLog("Error while parsing path URL", err)
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("articleID must be an integer, got %q", params["articleID"]))
 		return
 	}
 
-	res, err := json.Marshal(art)
+	log.InfoLog(fmt.Sprintf("Request received: patching article with id%v\n", artID))
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil {
-		log.ErrorLog("Error while marshaling response", err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		mediaType = ""
+	}
+
+	p, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+
+	version, ok := c.expectedVersion(w, r, artID)
+	if !ok {
 		return
 	}
-	writeRes(http.StatusAccepted, string(res), w)
+
+	art, err := c.s.PatchArticle(r.Context(), artID, version, mediaType, p)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while patching article with id:%v", artID), err)
+		writeError(w, r, err)
+		return
+	}
+	c.writeArticle(w, r, http.StatusAccepted, art)
 }
 
-//DeleteArticleByIDHandler processes request and makes server call to delete an article with given artID
+//TransitionArticleHandler processes request and makes server call to move an article with given
+//artID to a new status, honoring If-Match like PUT
+//POST /articles/{articleID}/transitions
+func (c *Controller) TransitionArticleHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+
+	var t models.TransitionRequest
+
+	log.InfoLog(fmt.Sprintf("Request received: transitioning article with id%v\n", artID))
+
+	if !decodeBody(w, r, &t) {
+		return
+	}
+	if !validate(w, r, false, t) {
+		return
+	}
+
+	version, ok := c.expectedVersion(w, r, artID)
+	if !ok {
+		return
+	}
+
+	art, err := c.s.TransitionArticle(r.Context(), artID, version, t)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while transitioning article with id:%v", artID), err)
+		writeError(w, r, err)
+		return
+	}
+	c.writeArticle(w, r, http.StatusAccepted, art)
+}
+
+//DeleteArticleByIDHandler processes request and makes server call to move an article with given
+//artID to the trash
 //DELETE /articles/{articleID}
 func (c *Controller) DeleteArticleByIDHandler(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	artID, err := strconv.Atoi(params["articleID"])
 	if err != nil {
 		log.ErrorLog("Error while parsing path URL", err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("articleID must be an integer, got %q", params["articleID"]))
 		return
 	}
 
 	log.InfoLog(fmt.Sprintf("Request received: deleting article with id%v\n", artID))
 
-	err = c.s.DeleteArticle(artID)
+	version, ok := c.expectedVersion(w, r, artID)
+	if !ok {
+		return
+	}
+
+	err = c.s.DeleteArticle(r.Context(), artID, version)
 	if err != nil {
 		log.ErrorLog(fmt.Sprintf("Error while deleting article with id:%v", artID), err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		writeError(w, r, err)
 		return
 	}
 	writeRes(http.StatusOK, http.StatusText(http.StatusOK), w)
 }
 
-//GetArticleByUserIDHandler processes request and makes server call to fetch an article filtered
-//with given userID
-//GET /articles/{userID}
+//GetArticleByUserIDHandler processes request and makes server call to fetch a page of articles
+//filtered with given userID
+//GET /users/{userID}/articles?status=published&limit=20&cursor=c
 func (c *Controller) GetArticleByUserIDHandler(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	userID, err := strconv.Atoi(params["userID"])
 	if err != nil {
 		log.ErrorLog("Error while parsing path URL", err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("userID must be an integer, got %q", params["userID"]))
 		return
 	}
 
 	log.InfoLog(fmt.Sprintf("Request received: retrieving article with user id%v\n", userID))
 
-	arts, err := c.s.GetArticlesByUser(userID)
+	c.writeArticlePage(w, r, storage.PageQuery{UserID: userID})
+}
+
+//writeArticlePage fetches the page of articles selected by the filter, sort, limit and cursor
+//query parameters, restricted to the user and tag of scope taken from the path when they are
+//set, and writes it to the response. Listings are public so only published articles are included
+//unless another status, or all, is asked for
+func (c *Controller) writeArticlePage(w http.ResponseWriter, r *http.Request, scope storage.PageQuery) {
+	q, ok := pageQuery(w, r)
+	if !ok {
+		return
+	}
+	if scope.UserID != 0 {
+		q.UserID = scope.UserID
+	}
+	if scope.Tag != "" {
+		q.Tag = scope.Tag
+	}
+
+	page, err := c.s.ListArticles(r.Context(), q, r.URL.Query().Get("cursor"))
 	if err != nil {
-		log.ErrorLog(fmt.Sprintf("Error while retrieving articles with user id:%v", userID), err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		log.ErrorLog("Error while retrieving page of articles", err)
+		writeError(w, r, err)
 		return
 	}
+	c.writePage(w, r, page)
+}
 
-	res, err := json.Marshal(arts)
+//pageQuery parses the userID, status, tag, category, createdAfter, createdBefore, updatedAfter,
+//updatedBefore, sort and limit query parameters of a listing. It writes the problem response and returns false
+//when one of them is invalid
+func pageQuery(w http.ResponseWriter, r *http.Request) (storage.PageQuery, bool) {
+	var (
+		q  storage.PageQuery
+		ok bool
+	)
+	if u := r.URL.Query().Get("userID"); u != "" {
+		id, err := strconv.Atoi(u)
+		if err != nil || id < 1 {
+			log.ErrorLog(fmt.Sprintf("Error while parsing userID query parameter:%v", u), err)
+			writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("userID must be a positive integer, got %q", u))
+			return q, false
+		}
+		q.UserID = id
+	}
+	if q.Status, ok = queryStatus(w, r); !ok {
+		return q, false
+	}
+	q.Tag = server.NormalizeTag(r.URL.Query().Get("tag"))
+	q.Category = strings.TrimSpace(r.URL.Query().Get("category"))
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{
+		{"createdAfter", &q.CreatedAfter},
+		{"createdBefore", &q.CreatedBefore},
+		{"updatedAfter", &q.UpdatedAfter},
+		{"updatedBefore", &q.UpdatedBefore},
+	} {
+		if *p.t, ok = queryTime(w, r, p.name); !ok {
+			return q, false
+		}
+	}
+	if q.Sort, q.Desc, ok = querySort(w, r); !ok {
+		return q, false
+	}
+	if q.Limit, ok = queryLimit(w, r); !ok {
+		return q, false
+	}
+	return q, true
+}
+
+//querySort parses the sort query parameter of a listing, a field optionally prefixed with - for
+//descending order. Listings are ordered by id when it is absent
+func querySort(w http.ResponseWriter, r *http.Request) (string, bool, bool) {
+	s := r.URL.Query().Get("sort")
+	field := strings.TrimPrefix(s, "-")
+	switch field {
+	case "":
+		if s == "" {
+			return "", false, true
+		}
+	case storage.SortByCreatedAt, storage.SortByUpdatedAt, storage.SortByTitle:
+		return field, field != s, true
+	}
+	log.ErrorLog(fmt.Sprintf("Error while parsing sort query parameter:%v", s), fmt.Errorf("unknown sort field"))
+	writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("sort must be createdAt, updatedAt or title, optionally prefixed with -, got %q", s))
+	return "", false, false
+}
+
+//queryStatus parses the status query parameter of a listing, published when it is absent and
+//"" for all. It writes the problem response and returns false when it is not a known status
+func queryStatus(w http.ResponseWriter, r *http.Request) (string, bool) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		return models.StatusPublished, true
+	case "all":
+		return "", true
+	case models.StatusDraft, models.StatusInReview, models.StatusPublished, models.StatusArchived:
+		return status, true
+	}
+	log.ErrorLog(fmt.Sprintf("Error while parsing status query parameter:%v", status), fmt.Errorf("unknown status"))
+	writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("status must be draft, in_review, published, archived or all, got %q", status))
+	return "", false
+}
+
+//writePage writes a page of articles along with their reactions to the response
+func (c *Controller) writePage(w http.ResponseWriter, r *http.Request, page models.ArticlePage) {
+	c.withReactions(r, page.Articles)
+	res, err := json.Marshal(page)
 	if err != nil {
 		log.ErrorLog("Error while marshaling response", err)
-		writeRes(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		writeError(w, r, err)
 		return
 	}
 	writeRes(http.StatusOK, string(res), w)
 }
 
+//writeArticle writes a single article along with its reactions and an ETag of the version
+//followed by a digest of the reactions, which change without changing the version. The
+//response varies by X-User-ID as the reactions include the user's own
+func (c *Controller) writeArticle(w http.ResponseWriter, r *http.Request, statusCode int, art models.Article) {
+	if art.Reactions == nil {
+		arts := []models.Article{art}
+		c.withReactions(r, arts)
+		art = arts[0]
+	}
+	res, err := json.Marshal(art)
+	if err != nil {
+		log.ErrorLog("Error while marshaling response", err)
+		writeError(w, r, err)
+		return
+	}
+	vary(w, UserIDHeader)
+	w.Header().Set("ETag", articleETag(art))
+	writeRes(statusCode, string(res), w)
+}
+
 func writeRes(statusCode int, message string, w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
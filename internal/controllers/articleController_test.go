@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/server"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+func TestListArticlesPagination(t *testing.T) {
+	h := NewRouter(NewController(server.NewServer(storage.NewMockDynamo())))
+	do := func(method, path, body string, hdr ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for i := 0; i+1 < len(hdr); i += 2 {
+			req.Header.Set(hdr[i], hdr[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	list := func(query string) models.ArticlePage {
+		t.Helper()
+		w := do(http.MethodGet, "/articles?"+query, "")
+		var page models.ArticlePage
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &page) != nil {
+			t.Fatalf("GET /articles?%v returned %v %s", query, w.Code, w.Body)
+		}
+		return page
+	}
+	n := server.MaxPageLimit + 5
+	for i := 1; i <= n; i++ {
+		if w := do(http.MethodPost, "/articles", fmt.Sprintf(`[{"userID":1,"title":"t%v","body":"b"}]`, i)); w.Code >= 300 {
+			t.Fatalf("POST returned %v %s", w.Code, w.Body)
+		}
+	}
+
+	for _, tc := range []struct {
+		limit string
+		size  int
+	}{
+		{"", server.DefaultPageLimit},
+		{"0", server.DefaultPageLimit},
+		{"3", 3},
+		{"100000", server.MaxPageLimit},
+	} {
+		if page := list("status=all&limit=" + tc.limit); len(page.Articles) != tc.size {
+			t.Fatalf("GET with limit %q returned %v articles, want %v", tc.limit, len(page.Articles), tc.size)
+		}
+	}
+
+	//Following nextCursor visits every article once, in title order, the last page carrying none
+	seen, prev, pages := map[int]bool{}, "", 0
+	for cur := ""; ; {
+		page := list("status=all&sort=title&limit=30&cursor=" + url.QueryEscape(cur))
+		pages++
+		for _, a := range page.Articles {
+			if seen[a.ArticleID] || a.Title < prev {
+				t.Fatalf("page %v returned %q after %q", pages, a.Title, prev)
+			}
+			seen[a.ArticleID], prev = true, a.Title
+		}
+		if cur = page.NextCursor; cur == "" {
+			break
+		}
+	}
+	if len(seen) != n || pages != (n+29)/30 {
+		t.Fatalf("following nextCursor returned %v articles over %v pages, want %v over %v", len(seen), pages, n, (n+29)/30)
+	}
+
+	valid := list("status=all&limit=5").NextCursor
+	for _, tc := range []struct {
+		query string
+		code  int
+	}{
+		{"limit=-1", http.StatusBadRequest},
+		{"limit=ten", http.StatusBadRequest},
+		{"cursor=" + url.QueryEscape(valid), http.StatusOK},
+		{"cursor=" + url.QueryEscape(valid[:len(valid)-2]+"!!"), http.StatusBadRequest},
+		{"cursor=" + url.QueryEscape("x"+valid), http.StatusBadRequest},
+		{"cursor=bm90IGpzb24", http.StatusBadRequest},
+		//A cursor only resumes the order it was handed out for
+		{"sort=-title&cursor=" + url.QueryEscape(valid), http.StatusBadRequest},
+	} {
+		w := do(http.MethodGet, "/articles?status=all&"+tc.query, "")
+		if w.Code != tc.code {
+			t.Fatalf("GET /articles?%v returned %v, want %v: %s", tc.query, w.Code, tc.code, w.Body)
+		}
+		if tc.code == http.StatusBadRequest && w.Header().Get("Content-Type") != "application/problem+json" {
+			t.Fatalf("GET /articles?%v returned Content-Type %q, want a problem", tc.query, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestGetArticleBySlugRedirectsOldSlugs(t *testing.T) {
+	h := NewRouter(NewController(server.NewServer(storage.NewMockDynamo())))
+	do := func(method, path, body string, hdr ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for i := 0; i+1 < len(hdr); i += 2 {
+			req.Header.Set(hdr[i], hdr[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	do(http.MethodPost, "/articles", `[{"userID":1,"title":"Hello World","body":"b"}]`)
+	for _, title := range []string{"Goodbye World", "Third Title"} {
+		if w := do(http.MethodPut, "/articles/1", fmt.Sprintf(`{"userID":1,"title":%q,"body":"b"}`, title)); w.Code != http.StatusAccepted {
+			t.Fatalf("PUT returned %v %s", w.Code, w.Body)
+		}
+	}
+	//A title change keeping the slug adds no history
+	if w := do(http.MethodPut, "/articles/1", `{"userID":1,"title":"Third title!","body":"b"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("PUT returned %v %s", w.Code, w.Body)
+	}
+
+	if w := do(http.MethodGet, "/articles/by-slug/third-title", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"slug":"third-title"`) {
+		t.Fatalf("GET by the current slug returned %v %s", w.Code, w.Body)
+	}
+	for _, old := range []string{"hello-world", "goodbye-world"} {
+		w := do(http.MethodGet, "/articles/by-slug/"+old+"?format=html", "")
+		if loc := w.Header().Get("Location"); w.Code != http.StatusMovedPermanently || loc != "/articles/by-slug/third-title?format=html" {
+			t.Fatalf("GET by the old slug %v returned %v to %q, want 301 to the current slug with the query kept", old, w.Code, loc)
+		}
+	}
+	if w := do(http.MethodGet, "/articles/by-slug/never-held", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("GET by a slug never held returned %v, want 404", w.Code)
+	}
+
+	//Trashed articles are not found by any of their slugs
+	if w := do(http.MethodDelete, "/articles/1", ""); w.Code >= 300 {
+		t.Fatalf("DELETE returned %v %s", w.Code, w.Body)
+	}
+	for _, slug := range []string{"third-title", "hello-world"} {
+		if w := do(http.MethodGet, "/articles/by-slug/"+slug, ""); w.Code != http.StatusNotFound {
+			t.Fatalf("GET of a trashed article by %v returned %v, want 404", slug, w.Code)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+//GetCommentsHandler processes request and makes server call to fetch a page of the top level
+//comments on the article with given artID
+//GET /articles/{articleID}/comments?limit=20&cursor=c
+func (c *Controller) GetCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: retrieving comments on article with id%v", artID))
+
+	c.writeCommentPage(w, r, artID, 0)
+}
+
+//GetRepliesHandler processes request and makes server call to fetch a page of the replies to
+//the comment with given commentID
+//GET /articles/{articleID}/comments/{commentID}/replies?limit=20&cursor=c
+func (c *Controller) GetRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+	commentID, ok := pathInt(w, r, "commentID")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: retrieving replies to comment with id%v", commentID))
+
+	c.writeCommentPage(w, r, artID, commentID)
+}
+
+//PostCommentHandler processes request and makes server call to comment on the article with
+//given artID
+//POST /articles/{articleID}/comments
+func (c *Controller) PostCommentHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: commenting on article with id%v", artID))
+
+	c.createComment(w, r, artID, 0)
+}
+
+//PostReplyHandler processes request and makes server call to reply to the comment with given
+//commentID
+//POST /articles/{articleID}/comments/{commentID}/replies
+func (c *Controller) PostReplyHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+	commentID, ok := pathInt(w, r, "commentID")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: replying to comment with id%v", commentID))
+
+	c.createComment(w, r, artID, commentID)
+}
+
+//GetCommentHandler processes request and makes server call to fetch the comment with given
+//commentID
+//GET /articles/{articleID}/comments/{commentID}
+func (c *Controller) GetCommentHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+	commentID, ok := pathInt(w, r, "commentID")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: retrieving comment with id%v", commentID))
+
+	cm, err := c.s.GetComment(r.Context(), artID, commentID)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while retrieving comment with id:%v", commentID), err)
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, cm)
+}
+
+//UpdateCommentHandler processes request and makes server call to edit the body of the comment
+//with given commentID
+//PUT /articles/{articleID}/comments/{commentID}
+func (c *Controller) UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+	commentID, ok := pathInt(w, r, "commentID")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: updating comment with id%v", commentID))
+
+	var u models.CommentUpdate
+	if !decodeBody(w, r, &u) || !validate(w, r, false, u) {
+		return
+	}
+
+	cm, err := c.s.UpdateComment(r.Context(), artID, commentID, u.Body)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while updating comment with id:%v", commentID), err)
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, cm)
+}
+
+//DeleteCommentHandler processes request and makes server call to delete the comment with given
+//commentID
+//DELETE /articles/{articleID}/comments/{commentID}
+func (c *Controller) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+	commentID, ok := pathInt(w, r, "commentID")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: deleting comment with id%v", commentID))
+
+	if err := c.s.DeleteComment(r.Context(), artID, commentID); err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while deleting comment with id:%v", commentID), err)
+		writeError(w, r, err)
+		return
+	}
+	writeRes(http.StatusOK, http.StatusText(http.StatusOK), w)
+}
+
+//createComment decodes a new comment from the request payload, adds it to the article replying
+//to parentID unless it is 0 and writes it back along with its location
+func (c *Controller) createComment(w http.ResponseWriter, r *http.Request, artID, parentID int) {
+	var nc models.NewComment
+	if !decodeBody(w, r, &nc) || !validate(w, r, false, nc) {
+		return
+	}
+
+	cm, err := c.s.CreateComment(r.Context(), artID, parentID, nc)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while commenting on article with id:%v", artID), err)
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/articles/%v/comments/%v", artID, cm.CommentID))
+	writeJSON(w, r, http.StatusCreated, cm)
+}
+
+//writeCommentPage fetches the page of comments replying to parentID, or the top level ones when
+//it is 0, selected by the limit and cursor query parameters and writes it to the response
+func (c *Controller) writeCommentPage(w http.ResponseWriter, r *http.Request, artID, parentID int) {
+	limit, ok := queryLimit(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := c.s.ListComments(r.Context(), artID, parentID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while retrieving page of comments on article with id:%v", artID), err)
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, page)
+}
+
+//writeJSON writes v marshaled as JSON to the response
+func writeJSON(w http.ResponseWriter, r *http.Request, statusCode int, v interface{}) {
+	res, err := json.Marshal(v)
+	if err != nil {
+		log.ErrorLog("Error while marshaling response", err)
+		writeError(w, r, err)
+		return
+	}
+	writeRes(statusCode, string(res), w)
+}
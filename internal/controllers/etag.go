@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+//etag returns the strong entity tag of an article version
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+//articleETag returns the strong entity tag of the JSON representation of an article. Reactions
+//change without changing the version, so when they are included a digest of them follows it
+func articleETag(a models.Article) string {
+	if a.Reactions == nil {
+		return etag(a.Version)
+	}
+	h := fnv.New32a()
+	for _, k := range models.ReactionKinds {
+		fmt.Fprintf(h, "%v=%v;", k, a.Reactions.Counts[k])
+	}
+	mine := append([]string(nil), a.Reactions.Mine...)
+	sort.Strings(mine)
+	fmt.Fprint(h, strings.Join(mine, ","))
+	return fmt.Sprintf(`"%v-%08x"`, a.Version, h.Sum32())
+}
+
+//parseETag returns the article version named by a strong entity tag, ignoring the reaction
+//digest of the tag of a JSON representation
+func parseETag(tag string) (int, bool) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	v, err := strconv.Atoi(strings.SplitN(tag[1:len(tag)-1], "-", 2)[0])
+	if err != nil || v < 1 {
+		return 0, false
+	}
+	return v, true
+}
+
+//etagMatches reports whether an If-Match or If-None-Match header value lists tag or is *.
+//If-None-Match uses weak comparison, which ignores the W/ prefix
+func etagMatches(header, tag string, weak bool) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if weak {
+			t = strings.TrimPrefix(t, "W/")
+		}
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+//listsVersion reports whether an If-Match or If-None-Match header value lists a tag of the
+//given article version or is *. Writes are guarded by the version alone so reaction digests are
+//ignored, If-None-Match uses weak comparison
+func listsVersion(header string, version int, weak bool) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if weak {
+			t = strings.TrimPrefix(t, "W/")
+		}
+		if t == "*" {
+			return true
+		}
+		if v, ok := parseETag(t); ok && v == version {
+			return true
+		}
+	}
+	return false
+}
+
+//vary adds header names to the Vary header of a response unless it lists them already
+func vary(w http.ResponseWriter, names ...string) {
+	listed := strings.Join(w.Header().Values("Vary"), ",")
+	for _, name := range names {
+		found := false
+		for _, v := range strings.Split(listed, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), name) {
+				found = true
+			}
+		}
+		if !found {
+			w.Header().Add("Vary", name)
+		}
+	}
+}
+
+//expectedVersion evaluates the If-Match and If-None-Match headers of a write to the article with
+//the given id and returns the version the write must apply to. A lone If-Match tag goes straight
+//to storage, any other condition is checked against the current article whose version then
+//guards the write. It writes the problem response and returns false when a condition fails
+func (c *Controller) expectedVersion(w http.ResponseWriter, r *http.Request, id int) (int, bool) {
+	return conditionalVersion(w, r, id, c.s.GetArticleByID)
+}
+
+//conditionalVersion is expectedVersion for an article looked up through get
+func conditionalVersion(w http.ResponseWriter, r *http.Request, id int, get func(context.Context, int) (models.Article, error)) (int, bool) {
+	ifMatch, ifNoneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+	if ifNoneMatch == "" {
+		if ifMatch == "" || strings.TrimSpace(ifMatch) == "*" {
+			return storage.AnyVersion, true
+		}
+		if !strings.Contains(ifMatch, ",") {
+			if v, ok := parseETag(ifMatch); ok {
+				return v, true
+			}
+			writeError(w, r, storage.ErrVersionConflict)
+			return 0, false
+		}
+	}
+
+	art, err := get(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return 0, false
+	}
+	if (ifMatch != "" && !listsVersion(ifMatch, art.Version, false)) || (ifNoneMatch != "" && listsVersion(ifNoneMatch, art.Version, true)) {
+		writeError(w, r, storage.ErrVersionConflict)
+		return 0, false
+	}
+	return art.Version, true
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/server"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+func TestArticleETagCoversReactions(t *testing.T) {
+	h := NewRouter(NewController(server.NewServer(storage.NewMockDynamo())))
+	do := func(method, path, body string, hdr ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for i := 0; i+1 < len(hdr); i += 2 {
+			req.Header.Set(hdr[i], hdr[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	do(http.MethodPost, "/articles", `[{"userID":1,"title":"t","body":"b"}]`)
+
+	w := do(http.MethodGet, "/articles/1", "", UserIDHeader, "2")
+	before := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasPrefix(before, `"1-`) {
+		t.Fatalf("GET returned %v with ETag %v, want 200 with a version 1 tag", w.Code, before)
+	}
+	if v := strings.Join(w.Header().Values("Vary"), ", "); !strings.Contains(v, "Accept") || !strings.Contains(v, UserIDHeader) {
+		t.Fatalf("GET returned Vary %q, want Accept and %v", v, UserIDHeader)
+	}
+	if w := do(http.MethodGet, "/articles/1", "", UserIDHeader, "2", "If-None-Match", before); w.Code != http.StatusNotModified {
+		t.Fatalf("GET with the current ETag returned %v, want 304", w.Code)
+	}
+
+	if w := do(http.MethodPut, "/articles/1/reactions/like", "", UserIDHeader, "2"); w.Code != http.StatusOK {
+		t.Fatalf("reacting returned %v %s", w.Code, w.Body)
+	}
+	w = do(http.MethodGet, "/articles/1", "", UserIDHeader, "2", "If-None-Match", before)
+	after := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || after == before || !strings.Contains(w.Body.String(), `"mine":["like"]`) {
+		t.Fatalf("GET with the ETag from before the reaction returned %v with ETag %v: %s", w.Code, after, w.Body)
+	}
+	if w := do(http.MethodGet, "/articles/1", "", UserIDHeader, "3", "If-None-Match", after); w.Code != http.StatusOK {
+		t.Fatalf("GET by another user with the reacting user's ETag returned %v, want 200", w.Code)
+	}
+
+	//Writes are guarded by the version alone, whatever the reactions
+	w = do(http.MethodPut, "/articles/1", `{"userID":1,"title":"n","body":"b"}`, "If-Match", before)
+	if w.Code != http.StatusAccepted || !strings.HasPrefix(w.Header().Get("ETag"), `"2-`) {
+		t.Fatalf("PUT with a version 1 ETag returned %v with ETag %v", w.Code, w.Header().Get("ETag"))
+	}
+	if w := do(http.MethodPut, "/articles/1", `{"userID":1,"title":"n","body":"b"}`, "If-Match", after); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("PUT with a stale ETag returned %v, want 412", w.Code)
+	}
+}
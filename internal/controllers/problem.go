@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/server"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+	"github.com/Perezonance/article-management-service/internal/util/patch"
+	"github.com/Perezonance/article-management-service/internal/util/search"
+)
+
+//Problem types identify the kind of failure described by a problem+json response
+const (
+	problemTypeNotFound         = "/problems/not-found"
+	problemTypeInvalidParameter = "/problems/invalid-parameter"
+	problemTypeMalformedBody    = "/problems/malformed-body"
+	problemTypeBodyTooLarge     = "/problems/body-too-large"
+	problemTypeValidation       = "/problems/validation-failed"
+	problemTypeMethodNotAllowed = "/problems/method-not-allowed"
+	problemTypeUnsupportedMedia = "/problems/unsupported-media-type"
+	problemTypePatchConflict    = "/problems/patch-test-failed"
+	problemTypePatchTarget      = "/problems/patch-path-not-found"
+	problemTypePrecondition     = "/problems/precondition-failed"
+	problemTypeTransition       = "/problems/illegal-transition"
+	problemTypeSlugTaken        = "/problems/slug-taken"
+	problemTypeCommentDeleted   = "/problems/comment-deleted"
+	problemTypeForbidden        = "/problems/forbidden"
+	problemTypeInternal         = "/problems/internal-error"
+)
+
+//writeError reports an error returned by the server, mapping domain errors onto their 4xx
+//status and everything else onto a 500 that does not leak internals
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *server.ValidationError
+	switch {
+	case errors.Is(err, storage.ErrResourceNotFound):
+		writeProblem(w, r, http.StatusNotFound, problemTypeNotFound, err.Error())
+	case errors.Is(err, storage.ErrVersionConflict):
+		writeProblem(w, r, http.StatusPreconditionFailed, problemTypePrecondition, "the article has been modified, fetch it again and retry with its current ETag")
+	case errors.Is(err, server.ErrIllegalTransition):
+		writeProblem(w, r, http.StatusConflict, problemTypeTransition, err.Error())
+	case errors.Is(err, server.ErrCommentDeleted):
+		writeProblem(w, r, http.StatusConflict, problemTypeCommentDeleted, err.Error())
+	case errors.Is(err, storage.ErrSlugTaken):
+		writeProblem(w, r, http.StatusConflict, problemTypeSlugTaken, "no free slug could be derived from the title, retry or choose another title")
+	case errors.Is(err, server.ErrInvalidCursor), errors.Is(err, search.ErrInvalidQuery):
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, err.Error())
+	case errors.Is(err, server.ErrUnknownReaction):
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("kind must be one of %v", strings.Join(models.ReactionKinds, ", ")))
+	case errors.Is(err, server.ErrNotCommentAuthor):
+		writeProblem(w, r, http.StatusForbidden, problemTypeForbidden, err.Error())
+	case errors.Is(err, server.ErrNoActor):
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("the %v header must name the user making the request", UserIDHeader))
+	case errors.As(err, &validationErr):
+		params := make([]models.InvalidParam, len(validationErr.Fields))
+		for i, fe := range validationErr.Fields {
+			params[i] = models.InvalidParam{Name: fe.Field, Reason: fe.Reason}
+		}
+		writeProblem(w, r, http.StatusUnprocessableEntity, problemTypeValidation, "the resulting article failed validation", params...)
+	case errors.Is(err, server.ErrUnsupportedPatchType):
+		w.Header().Set("Accept-Patch", patch.MergePatchType+", "+patch.JSONPatchType)
+		writeProblem(w, r, http.StatusUnsupportedMediaType, problemTypeUnsupportedMedia, err.Error())
+	case errors.Is(err, patch.ErrInvalidPatch):
+		writeProblem(w, r, http.StatusBadRequest, problemTypeMalformedBody, err.Error())
+	case errors.Is(err, patch.ErrPathNotFound):
+		writeProblem(w, r, http.StatusUnprocessableEntity, problemTypePatchTarget, err.Error())
+	case errors.Is(err, patch.ErrTestFailed):
+		writeProblem(w, r, http.StatusConflict, problemTypePatchConflict, err.Error())
+	default:
+		writeProblem(w, r, http.StatusInternalServerError, problemTypeInternal, "the server could not process the request")
+	}
+}
+
+//writeProblem writes an RFC 7807 problem details body with the given status, listing any
+//request fields that failed validation
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, problemType, detail string, params ...models.InvalidParam) {
+	p := models.Problem{
+		Type:          problemType,
+		Title:         http.StatusText(status),
+		Status:        status,
+		Detail:        detail,
+		Instance:      r.URL.RequestURI(),
+		InvalidParams: params,
+	}
+	res, err := json.Marshal(p)
+	if err != nil {
+		log.ErrorLog("Error while marshaling problem", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	if _, err := w.Write(res); err != nil {
+		log.ErrorLog("Error while writing to ResponseWriter", err)
+	}
+}
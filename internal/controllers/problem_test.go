@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/server"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	"github.com/Perezonance/article-management-service/internal/util/patch"
+	"github.com/Perezonance/article-management-service/internal/util/validator"
+)
+
+func TestWriteError(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		status int
+		typ    string
+		params []models.InvalidParam
+	}{
+		{storage.ErrResourceNotFound, http.StatusNotFound, problemTypeNotFound, nil},
+		{fmt.Errorf("loading article: %w", storage.ErrResourceNotFound), http.StatusNotFound, problemTypeNotFound, nil},
+		{storage.ErrVersionConflict, http.StatusPreconditionFailed, problemTypePrecondition, nil},
+		{server.ErrIllegalTransition, http.StatusConflict, problemTypeTransition, nil},
+		{server.ErrCommentDeleted, http.StatusConflict, problemTypeCommentDeleted, nil},
+		{storage.ErrSlugTaken, http.StatusConflict, problemTypeSlugTaken, nil},
+		{patch.ErrTestFailed, http.StatusConflict, problemTypePatchConflict, nil},
+		{
+			&server.ValidationError{Fields: []validator.FieldError{{Field: "title", Reason: "is required"}, {Field: "userID", Reason: "cannot be changed"}}},
+			http.StatusUnprocessableEntity, problemTypeValidation,
+			[]models.InvalidParam{{Name: "title", Reason: "is required"}, {Name: "userID", Reason: "cannot be changed"}},
+		},
+		{patch.ErrPathNotFound, http.StatusUnprocessableEntity, problemTypePatchTarget, nil},
+		{server.ErrInvalidCursor, http.StatusBadRequest, problemTypeInvalidParameter, nil},
+		{server.ErrNotCommentAuthor, http.StatusForbidden, problemTypeForbidden, nil},
+		{server.ErrUnsupportedPatchType, http.StatusUnsupportedMediaType, problemTypeUnsupportedMedia, nil},
+		{errors.New("dial tcp 10.0.0.1:5432: connection refused"), http.StatusInternalServerError, problemTypeInternal, nil},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/articles/1?x=y", nil)
+		w := httptest.NewRecorder()
+		writeError(w, r, tc.err)
+
+		if w.Code != tc.status {
+			t.Fatalf("writeError(%v) wrote status %v, want %v", tc.err, w.Code, tc.status)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Fatalf("writeError(%v) wrote Content-Type %q, want application/problem+json", tc.err, ct)
+		}
+		var p models.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatalf("writeError(%v) wrote %s: %v", tc.err, w.Body, err)
+		}
+		if p.Type != tc.typ || p.Title != http.StatusText(tc.status) || p.Status != tc.status || p.Instance != "/articles/1?x=y" || p.Detail == "" {
+			t.Fatalf("writeError(%v) wrote %+v, want type %v titled %q", tc.err, p, tc.typ, http.StatusText(tc.status))
+		}
+		if !reflect.DeepEqual(p.InvalidParams, tc.params) {
+			t.Fatalf("writeError(%v) listed invalid params %+v, want %+v", tc.err, p.InvalidParams, tc.params)
+		}
+		//Unexpected errors are not passed on to the client
+		if strings.Contains(w.Body.String(), "10.0.0.1") {
+			t.Fatalf("writeError(%v) leaked the error: %s", tc.err, w.Body)
+		}
+	}
+}
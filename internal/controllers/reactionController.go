@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+	"github.com/gorilla/mux"
+)
+
+//GetReactionsHandler processes request and makes server call to fetch the reactions to the
+//article with given artID, including those of the user named by the X-User-ID header
+//GET /articles/{articleID}/reactions
+func (c *Controller) GetReactionsHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: retrieving reactions to article with id%v", artID))
+
+	reactions, err := c.s.GetReactions(r.Context(), artID)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while retrieving reactions to article with id:%v", artID), err)
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, reactions)
+}
+
+//PutReactionHandler processes request and makes server call to react to the article with given
+//artID on behalf of the user named by the X-User-ID header
+//PUT /articles/{articleID}/reactions/{kind}
+func (c *Controller) PutReactionHandler(w http.ResponseWriter, r *http.Request) {
+	c.react(w, r, "adding", c.s.AddReaction)
+}
+
+//DeleteReactionHandler processes request and makes server call to withdraw the reaction to the
+//article with given artID of the user named by the X-User-ID header
+//DELETE /articles/{articleID}/reactions/{kind}
+func (c *Controller) DeleteReactionHandler(w http.ResponseWriter, r *http.Request) {
+	c.react(w, r, "removing", c.s.RemoveReaction)
+}
+
+//react applies the reaction change to the article and kind named by the path and writes back the
+//article's reactions
+func (c *Controller) react(w http.ResponseWriter, r *http.Request, action string,
+	change func(ctx context.Context, id int, kind string) (models.Reactions, error)) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+	kind := mux.Vars(r)["kind"]
+
+	log.InfoLog(fmt.Sprintf("Request received: %v %v reaction to article with id%v", action, kind, artID))
+
+	reactions, err := change(r.Context(), artID, kind)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while %v %v reaction to article with id:%v", action, kind, artID), err)
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, reactions)
+}
+
+//withReactions fills in the reactions to each article, including those of the user making the
+//request. A failure is only logged, the articles are written without their reactions
+func (c *Controller) withReactions(r *http.Request, arts []models.Article) {
+	if err := c.s.FillReactions(r.Context(), arts); err != nil {
+		log.ErrorLog("Error while retrieving reactions to articles", err)
+	}
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+//htmlPolicy is the Content-Security-Policy sent with rendered HTML. The HTML is sanitized
+//already, the policy keeps it from running scripts or loading anything but images should it be
+//opened on its own
+const htmlPolicy = "default-src 'none'; img-src http: https:; style-src 'unsafe-inline'"
+
+//PreviewHandler processes request and makes server call to render the Markdown of an unsaved
+//article body as sanitized HTML. The body comes from a JSON payload unless it is sent as
+//text/markdown or text/plain, and the HTML is returned as JSON unless text/html is preferred
+//POST /render/preview
+func (c *Controller) PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	log.InfoLog("Request received: rendering preview")
+
+	asHTML, ok := wantsHTML(w, r)
+	if !ok {
+		return
+	}
+	var req models.PreviewRequest
+	switch mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt {
+	case "text/markdown", "text/plain":
+		b, ok := readBody(w, r)
+		if !ok {
+			return
+		}
+		req.Body = string(b)
+	default:
+		if !decodeBody(w, r, &req) {
+			return
+		}
+	}
+	if !validate(w, r, false, req) {
+		return
+	}
+
+	out := c.s.RenderMarkdown(req.Body)
+	if asHTML {
+		writeHTML(w, http.StatusOK, out)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, models.Preview{HTML: out})
+}
+
+//writeArticleRead answers a read of a single article, with 304 Not Modified when the client's
+//If-None-Match lists its ETag and otherwise with the article as JSON or rendered as HTML,
+//counting a view only when the article is served. The JSON representation includes the
+//reactions of the user named by the request, so its ETag covers them, while the HTML one
+//carries the version alone as a weak ETag
+func (c *Controller) writeArticleRead(w http.ResponseWriter, r *http.Request, art models.Article, asHTML bool) {
+	tag := "W/" + etag(art.Version)
+	if !asHTML {
+		arts := []models.Article{art}
+		c.withReactions(r, arts)
+		art = arts[0]
+		tag = articleETag(art)
+	}
+	vary(w, "Accept", UserIDHeader)
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, strings.TrimPrefix(tag, "W/"), true) {
+		w.Header().Set("ETag", tag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	c.s.RecordView(art.ArticleID, viewClient(r))
+	if !asHTML {
+		c.writeArticle(w, r, http.StatusOK, art)
+		return
+	}
+	w.Header().Set("ETag", tag)
+	writeHTML(w, http.StatusOK, c.s.RenderArticle(art))
+}
+
+//wantsHTML reports whether a read asks for HTML, through a format query parameter of html or
+//json or else through its Accept header. It writes the problem response and returns false when
+//the format is neither
+func wantsHTML(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	switch f := r.URL.Query().Get("format"); f {
+	case "html":
+		return true, true
+	case "json":
+		return false, true
+	case "":
+		return prefersHTML(r.Header.Get("Accept")), true
+	default:
+		log.ErrorLog(fmt.Sprintf("Error while parsing format query parameter:%v", f), fmt.Errorf("unknown format"))
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("format must be html or json, got %q", f))
+		return false, false
+	}
+}
+
+//prefersHTML reports whether an Accept header ranks text/html above application/json. The most
+//specific range matching each type sets its quality and ties go to JSON, so that */* alone or
+//listing both types equally keeps the JSON default
+func prefersHTML(accept string) bool {
+	htmlQ, htmlRank := quality(accept, "text", "html")
+	jsonQ, jsonRank := quality(accept, "application", "json")
+	return htmlRank > 0 && htmlQ > 0 && (htmlQ > jsonQ || (htmlQ == jsonQ && htmlRank > jsonRank))
+}
+
+//quality returns the quality an Accept header gives a media type along with how specific the
+//range setting it is, 2 for the type itself, 1 for its wildcard subtype, 0 for */* and -1 when
+//no range matches
+func quality(accept, typ, subtype string) (float64, int) {
+	q, rank := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		r := -1
+		switch mt {
+		case typ + "/" + subtype:
+			r = 2
+		case typ + "/*":
+			r = 1
+		case "*/*":
+			r = 0
+		}
+		if r <= rank {
+			continue
+		}
+		q, rank = 1, r
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+	}
+	return q, rank
+}
+
+//writeHTML writes rendered HTML to the response
+func writeHTML(w http.ResponseWriter, statusCode int, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", htmlPolicy)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write([]byte(body)); err != nil {
+		log.ErrorLog("Error while writing to ResponseWriter", err)
+	}
+}
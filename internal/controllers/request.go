@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/server"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+	"github.com/Perezonance/article-management-service/internal/util/validator"
+	"github.com/gorilla/mux"
+)
+
+const (
+	//maxBodyBytes caps the size of request payloads
+	maxBodyBytes = 1 << 20
+	//UserIDHeader names the user making a request, they are recorded as the creator or last
+	//editor of the articles it writes
+	UserIDHeader = "X-User-ID"
+)
+
+//withActor hands the user named by the UserIDHeader of a request down to the server. Requests
+//without the header are served on behalf of nobody in particular
+func withActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := r.Header.Get(UserIDHeader)
+		if v == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			log.ErrorLog(fmt.Sprintf("Error while parsing %v header:%v", UserIDHeader, v), err)
+			writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("%v must be a positive integer, got %q", UserIDHeader, v))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(server.WithActor(r.Context(), id)))
+	})
+}
+
+//decodeBody decodes the JSON request body into v, rejecting oversized payloads and fields the
+//model does not define. It writes the problem response and returns false when the body is unusable
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err == nil {
+		return true
+	}
+	log.ErrorLog("Error while decoding request payload", err)
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, problemTypeBodyTooLarge, fmt.Sprintf("request body must not exceed %v bytes", maxBodyBytes))
+		return false
+	}
+	if field, ok := validator.UnknownField(err); ok {
+		writeProblem(w, r, http.StatusBadRequest, problemTypeMalformedBody, "request body contains fields that are not allowed",
+			models.InvalidParam{Name: field, Reason: "is not allowed"})
+		return false
+	}
+	writeProblem(w, r, http.StatusBadRequest, problemTypeMalformedBody, "request body is not valid JSON for this resource")
+	return false
+}
+
+//readBody reads the raw request body, rejecting oversized payloads. It writes the problem
+//response and returns false when the body is unusable
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err == nil {
+		return b, true
+	}
+	log.ErrorLog("Error while reading request payload", err)
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, problemTypeBodyTooLarge, fmt.Sprintf("request body must not exceed %v bytes", maxBodyBytes))
+		return nil, false
+	}
+	writeProblem(w, r, http.StatusBadRequest, problemTypeMalformedBody, "request body could not be read")
+	return nil, false
+}
+
+//validate checks each payload against the validate rules on its model. It writes a 422 problem
+//listing every failing field and returns false when any rule fails. Fields of payloads sent in a
+//batch are prefixed with their index
+func validate(w http.ResponseWriter, r *http.Request, batch bool, payloads ...interface{}) bool {
+	var params []models.InvalidParam
+	for i, p := range payloads {
+		for _, fe := range validator.Struct(p) {
+			name := fe.Field
+			if batch {
+				name = fmt.Sprintf("[%v].%v", i, fe.Field)
+			}
+			params = append(params, models.InvalidParam{Name: name, Reason: fe.Reason})
+		}
+	}
+	if len(params) == 0 {
+		return true
+	}
+	log.ErrorLog("Error while validating request payload", fmt.Errorf("%v invalid fields", len(params)))
+	writeProblem(w, r, http.StatusUnprocessableEntity, problemTypeValidation, "request body failed validation", params...)
+	return false
+}
+
+//pathInt parses the named path variable as an integer. It writes the problem response and
+//returns false when the variable is not one
+func pathInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	v := mux.Vars(r)[name]
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.ErrorLog("Error while parsing path URL", err)
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("%v must be an integer, got %q", name, v))
+		return 0, false
+	}
+	return n, true
+}
+
+//queryInt parses the named query parameter as an integer. It writes the problem response and
+//returns false when the parameter is missing or not an integer
+func queryInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	v := r.URL.Query().Get(name)
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while parsing %v query parameter:%v", name, v), err)
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("%v must be an integer, got %q", name, v))
+		return 0, false
+	}
+	return n, true
+}
+
+//queryTime parses an optional RFC 3339 timestamp query parameter, the zero time when it is
+//absent. It writes the problem response and returns false when it is malformed
+func queryTime(w http.ResponseWriter, r *http.Request, name string) (time.Time, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while parsing %v query parameter:%v", name, v), err)
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("%v must be an RFC 3339 timestamp, got %q", name, v))
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+//queryLimit parses the optional limit query parameter of a listing, 0 when it is absent. It
+//writes the problem response and returns false when it is not a non-negative integer
+func queryLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	l := r.URL.Query().Get("limit")
+	if l == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(l)
+	if err != nil || limit < 0 {
+		log.ErrorLog(fmt.Sprintf("Error while parsing limit query parameter:%v", l), err)
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, fmt.Sprintf("limit must be a non-negative integer, got %q", l))
+		return 0, false
+	}
+	return limit, true
+}
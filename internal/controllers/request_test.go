@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/server"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+func TestRequestBodyChecks(t *testing.T) {
+	h := NewRouter(NewController(server.NewServer(storage.NewMockDynamo())))
+	do := func(method, path, body string, hdr ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for i := 0; i+1 < len(hdr); i += 2 {
+			req.Header.Set(hdr[i], hdr[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	do(http.MethodPost, "/articles", `[{"userID":1,"title":"t","body":"b"}]`)
+	huge := strings.Repeat("x", maxBodyBytes)
+
+	for _, tc := range []struct {
+		name, method, path, body string
+		hdr                      []string
+		status                   int
+		typ                      string
+		params                   []models.InvalidParam
+	}{
+		{
+			name: "oversized JSON", method: http.MethodPost, path: "/articles",
+			body:   `[{"userID":1,"title":"t","body":"` + huge + `"}]`,
+			status: http.StatusRequestEntityTooLarge, typ: problemTypeBodyTooLarge,
+		},
+		{
+			name: "oversized patch", method: http.MethodPatch, path: "/articles/1",
+			body:   `{"body":"` + huge + `"}`,
+			hdr:    []string{"Content-Type", "application/merge-patch+json"},
+			status: http.StatusRequestEntityTooLarge, typ: problemTypeBodyTooLarge,
+		},
+		{
+			name: "oversized Markdown", method: http.MethodPost, path: "/render/preview",
+			body:   huge + "x",
+			hdr:    []string{"Content-Type", "text/markdown"},
+			status: http.StatusRequestEntityTooLarge, typ: problemTypeBodyTooLarge,
+		},
+		{
+			name: "unknown field", method: http.MethodPut, path: "/articles/1",
+			body:   `{"userID":1,"title":"t","body":"b","extra":true}`,
+			status: http.StatusBadRequest, typ: problemTypeMalformedBody,
+			params: []models.InvalidParam{{Name: "extra", Reason: "is not allowed"}},
+		},
+		{
+			name: "batch validation", method: http.MethodPost, path: "/articles",
+			body:   `[{"userID":1,"title":"t","body":"b"},{"userID":0,"title":" ","body":"b","category":"` + strings.Repeat("c", 51) + `"}]`,
+			status: http.StatusUnprocessableEntity, typ: problemTypeValidation,
+			params: []models.InvalidParam{
+				{Name: "[1].userID", Reason: "is required"},
+				{Name: "[1].title", Reason: "is required"},
+				{Name: "[1].category", Reason: "must be at most 50 characters"},
+			},
+		},
+		{
+			name: "comment validation", method: http.MethodPost, path: "/articles/1/comments",
+			body:   `{"body":"` + strings.Repeat("é", 5001) + `"}`,
+			hdr:    []string{UserIDHeader, "2"},
+			status: http.StatusUnprocessableEntity, typ: problemTypeValidation,
+			params: []models.InvalidParam{{Name: "body", Reason: "must be at most 5000 characters"}},
+		},
+	} {
+		w := do(tc.method, tc.path, tc.body, tc.hdr...)
+		if w.Code != tc.status || w.Header().Get("Content-Type") != "application/problem+json" {
+			t.Fatalf("%v: %v %v returned %v with Content-Type %q, want a %v problem", tc.name, tc.method, tc.path, w.Code, w.Header().Get("Content-Type"), tc.status)
+		}
+		var p models.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%v: response %s: %v", tc.name, w.Body, err)
+		}
+		if p.Type != tc.typ || !reflect.DeepEqual(p.InvalidParams, tc.params) {
+			t.Fatalf("%v: returned %+v, want type %v listing %+v", tc.name, p, tc.typ, tc.params)
+		}
+	}
+
+	//A body of exactly the limit is still read
+	body := `{"body":"` + strings.Repeat("x", maxBodyBytes-len(`{"body":""}`)) + `"}`
+	if w := do(http.MethodPost, "/render/preview", body); w.Code == http.StatusRequestEntityTooLarge {
+		t.Fatalf("POST of a body at the limit returned 413")
+	}
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+//GetRevisionsHandler processes request and makes server call to fetch every revision of an
+//article with given artID
+//GET /articles/{articleID}/revisions
+func (c *Controller) GetRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: retrieving revisions of article with id%v", artID))
+
+	revs, err := c.s.GetRevisions(r.Context(), artID)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while retrieving revisions of article with id:%v", artID), err)
+		writeError(w, r, err)
+		return
+	}
+
+	res, err := json.Marshal(revs)
+	if err != nil {
+		log.ErrorLog("Error while marshaling response", err)
+		writeError(w, r, err)
+		return
+	}
+	writeRes(http.StatusOK, string(res), w)
+}
+
+//GetRevisionHandler processes request and makes server call to fetch a single revision of an
+//article with given artID
+//GET /articles/{articleID}/revisions/{rev}
+func (c *Controller) GetRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+	rev, ok := pathInt(w, r, "rev")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: retrieving revision %v of article with id%v", rev, artID))
+
+	a, err := c.s.GetRevision(r.Context(), artID, rev)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while retrieving revision %v of article with id:%v", rev, artID), err)
+		writeError(w, r, err)
+		return
+	}
+
+	res, err := json.Marshal(a)
+	if err != nil {
+		log.ErrorLog("Error while marshaling response", err)
+		writeError(w, r, err)
+		return
+	}
+	writeRes(http.StatusOK, string(res), w)
+}
+
+//GetRevisionDiffHandler processes request and makes server call to compare two revisions of an
+//article with given artID
+//GET /articles/{articleID}/revisions/diff?from=1&to=2
+func (c *Controller) GetRevisionDiffHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+	from, ok := queryInt(w, r, "from")
+	if !ok {
+		return
+	}
+	to, ok := queryInt(w, r, "to")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: comparing revisions %v and %v of article with id%v", from, to, artID))
+
+	d, err := c.s.DiffRevisions(r.Context(), artID, from, to)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while comparing revisions of article with id:%v", artID), err)
+		writeError(w, r, err)
+		return
+	}
+
+	res, err := json.Marshal(d)
+	if err != nil {
+		log.ErrorLog("Error while marshaling response", err)
+		writeError(w, r, err)
+		return
+	}
+	writeRes(http.StatusOK, string(res), w)
+}
+
+//RestoreRevisionHandler processes request and makes server call to roll an article with given
+//artID back to an earlier revision, honoring If-Match like PUT
+//POST /articles/{articleID}/revisions/{rev}/restore
+func (c *Controller) RestoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+	rev, ok := pathInt(w, r, "rev")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: restoring revision %v of article with id%v", rev, artID))
+
+	version, ok := c.expectedVersion(w, r, artID)
+	if !ok {
+		return
+	}
+
+	art, err := c.s.RestoreRevision(r.Context(), artID, rev, version)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while restoring revision %v of article with id:%v", rev, artID), err)
+		writeError(w, r, err)
+		return
+	}
+	c.writeArticle(w, r, http.StatusAccepted, art)
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/server"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+func TestRevisionHandlers(t *testing.T) {
+	h := NewRouter(NewController(server.NewServer(storage.NewMockDynamo())))
+	do := func(method, path, body string, hdr ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for i := 0; i+1 < len(hdr); i += 2 {
+			req.Header.Set(hdr[i], hdr[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	do(http.MethodPost, "/articles", `[{"userID":1,"title":"First","body":"a\nb"}]`)
+	if w := do(http.MethodPut, "/articles/1", `{"userID":1,"title":"First","body":"a\nc"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("PUT returned %v %s", w.Code, w.Body)
+	}
+
+	w := do(http.MethodGet, "/articles/1/revisions/diff?from=1&to=2", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET diff returned %v %s", w.Code, w.Body)
+	}
+	var d models.ArticleDiff
+	if err := json.Unmarshal(w.Body.Bytes(), &d); err != nil {
+		t.Fatalf("GET diff returned %s: %v", w.Body, err)
+	}
+	want := models.ArticleDiff{ArticleID: 1, From: 1, To: 2, Fields: []models.FieldDiff{{Field: "body", Lines: []models.DiffLine{
+		{Op: "equal", Text: "a"},
+		{Op: "delete", Text: "b"},
+		{Op: "insert", Text: "c"},
+	}}}}
+	if !reflect.DeepEqual(d, want) {
+		t.Fatalf("GET diff returned %+v, want %+v", d, want)
+	}
+	for _, tc := range []struct {
+		path string
+		code int
+	}{
+		{"/articles/1/revisions/diff?from=1", http.StatusBadRequest},
+		{"/articles/1/revisions/diff?from=x&to=2", http.StatusBadRequest},
+		{"/articles/1/revisions/diff?from=1&to=9", http.StatusNotFound},
+		{"/articles/9/revisions/diff?from=1&to=2", http.StatusNotFound},
+	} {
+		if w := do(http.MethodGet, tc.path, ""); w.Code != tc.code {
+			t.Fatalf("GET %v returned %v, want %v", tc.path, w.Code, tc.code)
+		}
+	}
+
+	current := do(http.MethodGet, "/articles/1", "").Header().Get("ETag")
+	if w := do(http.MethodPost, "/articles/1/revisions/1/restore", "", "If-Match", `"1"`); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("restore with a stale If-Match returned %v, want 412", w.Code)
+	}
+	if w := do(http.MethodPost, "/articles/1/revisions/9/restore", "", "If-Match", current); w.Code != http.StatusNotFound {
+		t.Fatalf("restore of a missing revision returned %v, want 404", w.Code)
+	}
+	w = do(http.MethodPost, "/articles/1/revisions/1/restore", "", "If-Match", current)
+	if w.Code != http.StatusAccepted || !strings.HasPrefix(w.Header().Get("ETag"), `"3-`) || !strings.Contains(w.Body.String(), `"body":"a\nb"`) {
+		t.Fatalf("restore with the current If-Match returned %v with ETag %v: %s", w.Code, w.Header().Get("ETag"), w.Body)
+	}
+	if w := do(http.MethodPost, "/articles/1/revisions/1/restore", "", "If-Match", current); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("restore with the ETag from before the last restore returned %v, want 412", w.Code)
+	}
+
+	var revs []models.Article
+	if err := json.Unmarshal(do(http.MethodGet, "/articles/1/revisions", "").Body.Bytes(), &revs); err != nil {
+		t.Fatalf("GET revisions failed to decode: %v", err)
+	}
+	if len(revs) != 3 || revs[2].Version != 3 || revs[2].Body != "a\nb" {
+		t.Fatalf("GET revisions returned %+v, want the restore as revision 3", revs)
+	}
+}
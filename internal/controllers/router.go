@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+//NewRouter registers every route of the API against the controller's handlers. Each route is
+//named after its handler so the routing table can be checked with mux's Match
+func NewRouter(c *Controller) *mux.Router {
+	r := mux.NewRouter()
+	r.Use(withActor)
+
+	r.HandleFunc("/articles", c.GetArticlesHandler).Methods(http.MethodGet).Name("GetArticlesHandler")
+	r.HandleFunc("/articles", c.PostArticleHandler).Methods(http.MethodPost).Name("PostArticleHandler")
+	//The search and popular routes are registered ahead of {articleID} so that they are not taken
+	//for an id
+	r.HandleFunc("/articles/search", c.SearchArticlesHandler).Methods(http.MethodGet).Name("SearchArticlesHandler")
+	r.HandleFunc("/articles/popular", c.GetPopularArticlesHandler).Methods(http.MethodGet).Name("GetPopularArticlesHandler")
+	r.HandleFunc("/articles/by-slug/{slug}", c.GetArticleBySlugHandler).Methods(http.MethodGet).Name("GetArticleBySlugHandler")
+
+	r.HandleFunc("/articles/{articleID}", c.GetArticleByIDHandler).Methods(http.MethodGet).Name("GetArticleByIDHandler")
+	r.HandleFunc("/articles/{articleID}", c.UpdateArticleByIDHandler).Methods(http.MethodPut).Name("UpdateArticleByIDHandler")
+	r.HandleFunc("/articles/{articleID}", c.PatchArticleByIDHandler).Methods(http.MethodPatch).Name("PatchArticleByIDHandler")
+	r.HandleFunc("/articles/{articleID}", c.DeleteArticleByIDHandler).Methods(http.MethodDelete).Name("DeleteArticleByIDHandler")
+	r.HandleFunc("/articles/{articleID}/transitions", c.TransitionArticleHandler).Methods(http.MethodPost).Name("TransitionArticleHandler")
+	r.HandleFunc("/articles/{articleID}/tags", c.AddTagsHandler).Methods(http.MethodPost).Name("AddTagsHandler")
+	r.HandleFunc("/articles/{articleID}/tags", c.RemoveTagsHandler).Methods(http.MethodDelete).Name("RemoveTagsHandler")
+
+	r.HandleFunc("/articles/{articleID}/reactions", c.GetReactionsHandler).Methods(http.MethodGet).Name("GetReactionsHandler")
+	r.HandleFunc("/articles/{articleID}/reactions/{kind}", c.PutReactionHandler).Methods(http.MethodPut).Name("PutReactionHandler")
+	r.HandleFunc("/articles/{articleID}/reactions/{kind}", c.DeleteReactionHandler).Methods(http.MethodDelete).Name("DeleteReactionHandler")
+
+	r.HandleFunc("/articles/{articleID}/comments", c.GetCommentsHandler).Methods(http.MethodGet).Name("GetCommentsHandler")
+	r.HandleFunc("/articles/{articleID}/comments", c.PostCommentHandler).Methods(http.MethodPost).Name("PostCommentHandler")
+	r.HandleFunc("/articles/{articleID}/comments/{commentID}", c.GetCommentHandler).Methods(http.MethodGet).Name("GetCommentHandler")
+	r.HandleFunc("/articles/{articleID}/comments/{commentID}", c.UpdateCommentHandler).Methods(http.MethodPut).Name("UpdateCommentHandler")
+	r.HandleFunc("/articles/{articleID}/comments/{commentID}", c.DeleteCommentHandler).Methods(http.MethodDelete).Name("DeleteCommentHandler")
+	r.HandleFunc("/articles/{articleID}/comments/{commentID}/replies", c.GetRepliesHandler).Methods(http.MethodGet).Name("GetRepliesHandler")
+	r.HandleFunc("/articles/{articleID}/comments/{commentID}/replies", c.PostReplyHandler).Methods(http.MethodPost).Name("PostReplyHandler")
+
+	//The diff route is registered ahead of {rev} so that it is not taken for a revision number
+	r.HandleFunc("/articles/{articleID}/revisions", c.GetRevisionsHandler).Methods(http.MethodGet).Name("GetRevisionsHandler")
+	r.HandleFunc("/articles/{articleID}/revisions/diff", c.GetRevisionDiffHandler).Methods(http.MethodGet).Name("GetRevisionDiffHandler")
+	r.HandleFunc("/articles/{articleID}/revisions/{rev}", c.GetRevisionHandler).Methods(http.MethodGet).Name("GetRevisionHandler")
+	r.HandleFunc("/articles/{articleID}/revisions/{rev}/restore", c.RestoreRevisionHandler).Methods(http.MethodPost).Name("RestoreRevisionHandler")
+
+	r.HandleFunc("/render/preview", c.PreviewHandler).Methods(http.MethodPost).Name("PreviewHandler")
+
+	r.HandleFunc("/tags", c.GetTagsHandler).Methods(http.MethodGet).Name("GetTagsHandler")
+	r.HandleFunc("/tags/{tag}/articles", c.GetTagArticlesHandler).Methods(http.MethodGet).Name("GetTagArticlesHandler")
+
+	r.HandleFunc("/trash", c.GetTrashHandler).Methods(http.MethodGet).Name("GetTrashHandler")
+	r.HandleFunc("/trash/{articleID}/restore", c.RestoreTrashedArticleHandler).Methods(http.MethodPost).Name("RestoreTrashedArticleHandler")
+
+	r.HandleFunc("/users/{userID}/articles", c.GetArticleByUserIDHandler).Methods(http.MethodGet).Name("GetArticleByUserIDHandler")
+
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusNotFound, problemTypeNotFound, "no route matches the request path")
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problemTypeMethodNotAllowed, r.Method+" is not supported on this resource")
+	})
+
+	return r
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/server"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+//documentedRoutes maps every route of the README's API reference, as METHOD and path template, onto
+//the name of the route that should serve it
+var documentedRoutes = map[string]string{
+	"GET /articles":                                           "GetArticlesHandler",
+	"POST /articles":                                          "PostArticleHandler",
+	"GET /articles/search":                                    "SearchArticlesHandler",
+	"GET /articles/popular":                                   "GetPopularArticlesHandler",
+	"GET /articles/by-slug/{slug}":                            "GetArticleBySlugHandler",
+	"GET /articles/{articleId}":                               "GetArticleByIDHandler",
+	"PUT /articles/{articleId}":                               "UpdateArticleByIDHandler",
+	"PATCH /articles/{articleId}":                             "PatchArticleByIDHandler",
+	"DELETE /articles/{articleId}":                            "DeleteArticleByIDHandler",
+	"POST /articles/{articleId}/transitions":                  "TransitionArticleHandler",
+	"POST /articles/{articleId}/tags":                         "AddTagsHandler",
+	"DELETE /articles/{articleId}/tags":                       "RemoveTagsHandler",
+	"GET /articles/{articleId}/reactions":                     "GetReactionsHandler",
+	"PUT /articles/{articleId}/reactions/{kind}":              "PutReactionHandler",
+	"DELETE /articles/{articleId}/reactions/{kind}":           "DeleteReactionHandler",
+	"GET /articles/{articleId}/comments":                      "GetCommentsHandler",
+	"POST /articles/{articleId}/comments":                     "PostCommentHandler",
+	"GET /articles/{articleId}/comments/{commentId}":          "GetCommentHandler",
+	"PUT /articles/{articleId}/comments/{commentId}":          "UpdateCommentHandler",
+	"DELETE /articles/{articleId}/comments/{commentId}":       "DeleteCommentHandler",
+	"GET /articles/{articleId}/comments/{commentId}/replies":  "GetRepliesHandler",
+	"POST /articles/{articleId}/comments/{commentId}/replies": "PostReplyHandler",
+	"GET /articles/{articleId}/revisions":                     "GetRevisionsHandler",
+	"GET /articles/{articleId}/revisions/{rev}":               "GetRevisionHandler",
+	"GET /articles/{articleId}/revisions/diff":                "GetRevisionDiffHandler",
+	"POST /articles/{articleId}/revisions/{rev}/restore":      "RestoreRevisionHandler",
+	"POST /render/preview":                                    "PreviewHandler",
+	"GET /tags":                                               "GetTagsHandler",
+	"GET /tags/{tag}/articles":                                "GetTagArticlesHandler",
+	"GET /trash":                                              "GetTrashHandler",
+	"POST /trash/{articleId}/restore":                         "RestoreTrashedArticleHandler",
+	"GET /users/{userId}/articles":                            "GetArticleByUserIDHandler",
+}
+
+//pathValues fills in the path variables of the README's templates along with the mux variable
+//each should arrive as
+var pathValues = map[string][2]string{
+	"{articleId}": {"11", "articleID"},
+	"{commentId}": {"22", "commentID"},
+	"{userId}":    {"33", "userID"},
+	"{rev}":       {"4", "rev"},
+	"{kind}":      {"like", "kind"},
+	"{tag}":       {"golang", "tag"},
+	"{slug}":      {"hello-world", "slug"},
+}
+
+//readmeRoute matches a route line of the README's API reference
+var readmeRoute = regexp.MustCompile(`^\s+(GET|POST|PUT|PATCH|DELETE)\s+(/\S*)`)
+
+//apiReference is the heading of the README section the routes are read from, the planning
+//notes elsewhere list routes that were never built
+const apiReference = "## API Reference"
+
+func TestRoutingTable(t *testing.T) {
+	f, err := os.Open("../../README.md")
+	if err != nil {
+		t.Fatalf("opening README returned %v", err)
+	}
+	defer f.Close()
+
+	r := NewRouter(NewController(server.NewServer(storage.NewMockDynamo())))
+	reached := map[string]bool{}
+	sc := bufio.NewScanner(f)
+	inReference := false
+	for sc.Scan() {
+		if line := sc.Text(); strings.HasPrefix(line, "## ") {
+			inReference = line == apiReference
+		}
+		m := readmeRoute.FindStringSubmatch(sc.Text())
+		if !inReference || m == nil {
+			continue
+		}
+		method, template := m[1], m[2]
+		if i := strings.IndexByte(template, '?'); i >= 0 {
+			template = template[:i]
+		}
+		want, ok := documentedRoutes[method+" "+template]
+		if !ok {
+			t.Errorf("README documents %v %v which the routing table test does not know", method, template)
+			continue
+		}
+
+		path, vars := template, map[string]string{}
+		for placeholder, v := range pathValues {
+			if strings.Contains(path, placeholder) {
+				path = strings.ReplaceAll(path, placeholder, v[0])
+				vars[v[1]] = v[0]
+			}
+		}
+		var match mux.RouteMatch
+		if !r.Match(httptest.NewRequest(method, path, nil), &match) {
+			t.Errorf("%v %v matched no route, want %v", method, path, want)
+			continue
+		}
+		if got := match.Route.GetName(); got != want {
+			t.Errorf("%v %v reached %v, want %v", method, path, got, want)
+			continue
+		}
+		for name, v := range vars {
+			if match.Vars[name] != v {
+				t.Errorf("%v %v set %v to %q, want %q", method, path, name, match.Vars[name], v)
+			}
+		}
+		if len(match.Vars) != len(vars) {
+			t.Errorf("%v %v set path variables %v, want %v", method, path, match.Vars, vars)
+		}
+		reached[want] = true
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("reading README returned %v", err)
+	}
+
+	r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if name := route.GetName(); !reached[name] {
+			tmpl, _ := route.GetPathTemplate()
+			t.Errorf("route %v for %v is not documented in the README", name, tmpl)
+		}
+		return nil
+	})
+}
+
+func TestRoutingTableRejectsUnknownMethods(t *testing.T) {
+	r := NewRouter(NewController(server.NewServer(storage.NewMockDynamo())))
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPatch, "/articles"},
+		{http.MethodPost, "/articles/search"},
+		{http.MethodPut, "/users/1/articles"},
+	} {
+		var match mux.RouteMatch
+		if r.Match(httptest.NewRequest(tc.method, tc.path, nil), &match) && match.MatchErr != mux.ErrMethodMismatch {
+			t.Errorf("%v %v reached %v, want a method mismatch", tc.method, tc.path, match.Route.GetName())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+//SearchArticlesHandler processes request and makes server call to fetch a page of articles
+//matching the q query parameter, ranked best match first. Like listings only published articles
+//are searched unless another status, or all, is asked for
+//GET /articles/search?q="exact phrase" prefix*&status=published&limit=20&cursor=c
+func (c *Controller) SearchArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	log.InfoLog(fmt.Sprintf("Request received: searching articles for %q", q))
+
+	if q == "" {
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, "q is required")
+		return
+	}
+	status, ok := queryStatus(w, r)
+	if !ok {
+		return
+	}
+	limit, ok := queryLimit(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := c.s.SearchArticles(r.Context(), q, status, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while searching articles for %q", q), err)
+		writeError(w, r, err)
+		return
+	}
+	arts := make([]models.Article, len(page.Results))
+	for i, res := range page.Results {
+		arts[i] = res.Article
+	}
+	c.withReactions(r, arts)
+	for i := range page.Results {
+		page.Results[i].Article = arts[i]
+	}
+
+	res, err := json.Marshal(page)
+	if err != nil {
+		log.ErrorLog("Error while marshaling response", err)
+		writeError(w, r, err)
+		return
+	}
+	writeRes(http.StatusOK, string(res), w)
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/server"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+	"github.com/gorilla/mux"
+)
+
+//AddTagsHandler processes request and makes server call to tag the article with given artID
+//POST /articles/{articleID}/tags
+func (c *Controller) AddTagsHandler(w http.ResponseWriter, r *http.Request) {
+	c.retagArticle(w, r, "tagging", c.s.AddTags)
+}
+
+//RemoveTagsHandler processes request and makes server call to remove tags from the article with
+//given artID
+//DELETE /articles/{articleID}/tags
+func (c *Controller) RemoveTagsHandler(w http.ResponseWriter, r *http.Request) {
+	c.retagArticle(w, r, "untagging", c.s.RemoveTags)
+}
+
+//retagArticle decodes the tags of the request payload, hands them to retag along with the
+//article's id and writes back the article
+func (c *Controller) retagArticle(w http.ResponseWriter, r *http.Request, action string,
+	retag func(ctx context.Context, id int, tags []string) (models.Article, error)) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: %v article with id%v", action, artID))
+
+	var req models.TagsRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	art, err := retag(r.Context(), artID, req.Tags)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while %v article with id:%v", action, artID), err)
+		writeError(w, r, err)
+		return
+	}
+	c.writeArticle(w, r, http.StatusOK, art)
+}
+
+//GetTagsHandler processes request and makes server call to fetch every tag in use along with
+//the number of articles carrying it. Only published articles are counted unless another status,
+//or all, is asked for
+//GET /tags?status=published
+func (c *Controller) GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	log.InfoLog("Request received: retrieving tags")
+
+	status, ok := queryStatus(w, r)
+	if !ok {
+		return
+	}
+
+	tags, err := c.s.GetTags(r.Context(), status)
+	if err != nil {
+		log.ErrorLog("Error while retrieving tags", err)
+		writeError(w, r, err)
+		return
+	}
+	if tags == nil {
+		tags = []models.TagCount{}
+	}
+
+	res, err := json.Marshal(models.TagList{Tags: tags})
+	if err != nil {
+		log.ErrorLog("Error while marshaling response", err)
+		writeError(w, r, err)
+		return
+	}
+	writeRes(http.StatusOK, string(res), w)
+}
+
+//GetTagArticlesHandler processes request and makes server call to fetch a page of articles
+//carrying the given tag, taking the same query parameters as GET /articles
+//GET /tags/{tag}/articles?status=published&limit=20&cursor=c
+func (c *Controller) GetTagArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	tag := server.NormalizeTag(mux.Vars(r)["tag"])
+	if tag == "" {
+		log.ErrorLog("Error while parsing path URL", fmt.Errorf("empty tag"))
+		writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter, "tag must not be blank")
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: retrieving articles tagged:%q", tag))
+
+	c.writeArticlePage(w, r, storage.PageQuery{Tag: tag})
+}
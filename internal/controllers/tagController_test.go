@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/server"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+func TestTagHandlers(t *testing.T) {
+	s := server.NewServer(storage.NewMockDynamo())
+	h := NewRouter(NewController(s))
+	do := func(method, path, body string, hdr ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for i := 0; i+1 < len(hdr); i += 2 {
+			req.Header.Set(hdr[i], hdr[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	do(http.MethodPost, "/articles", `[{"userID":1,"title":"a","body":"b"},{"userID":1,"title":"b","body":"b"},{"userID":1,"title":"c","body":"b"}]`)
+	for _, tc := range []struct {
+		path, body, want string
+	}{
+		{"/articles/1/tags", `{"tags":["Go","  Web  Dev ","go"]}`, "[go web dev]"},
+		{"/articles/2/tags", `{"tags":["GO"]}`, "[go]"},
+		{"/articles/3/tags", `{"tags":["web dev","rust"]}`, "[rust web dev]"},
+	} {
+		w := do(http.MethodPost, tc.path, tc.body)
+		var a models.Article
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &a) != nil || fmt.Sprint(a.Tags) != tc.want {
+			t.Fatalf("POST %v returned %v %s, want tags %v", tc.path, w.Code, w.Body, tc.want)
+		}
+	}
+	ctx := server.WithActor(context.Background(), 9)
+	for _, status := range []string{models.StatusInReview, models.StatusPublished} {
+		if _, err := s.TransitionArticle(ctx, 1, storage.AnyVersion, models.TransitionRequest{Status: status}); err != nil {
+			t.Fatalf("TransitionArticle to %v returned %v", status, err)
+		}
+	}
+	if w := do(http.MethodDelete, "/articles/3/tags", `{"tags":["Rust","unknown"]}`); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"tags":["web dev"]`) {
+		t.Fatalf("DELETE /articles/3/tags returned %v %s, want the article tagged web dev", w.Code, w.Body)
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  []models.TagCount
+	}{
+		{"", []models.TagCount{{Tag: "go", Count: 1}, {Tag: "web dev", Count: 1}}},
+		{"?status=all", []models.TagCount{{Tag: "go", Count: 2}, {Tag: "web dev", Count: 2}}},
+		{"?status=draft", []models.TagCount{{Tag: "go", Count: 1}, {Tag: "web dev", Count: 1}}},
+		{"?status=archived", []models.TagCount{}},
+	} {
+		w := do(http.MethodGet, "/tags"+tc.query, "")
+		var list models.TagList
+		if err := json.Unmarshal(w.Body.Bytes(), &list); w.Code != http.StatusOK || err != nil {
+			t.Fatalf("GET /tags%v returned %v %s", tc.query, w.Code, w.Body)
+		}
+		if !reflect.DeepEqual(list.Tags, tc.want) {
+			t.Fatalf("GET /tags%v listed %v, want %v", tc.query, list.Tags, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		path string
+		want []int
+	}{
+		{"/tags/go/articles", []int{1}},
+		{"/tags/GO/articles?status=all", []int{1, 2}},
+		{"/tags/web%20%20DEV/articles?status=draft", []int{3}},
+		{"/tags/rust/articles?status=all", []int{}},
+	} {
+		w := do(http.MethodGet, tc.path, "")
+		var page models.ArticlePage
+		if err := json.Unmarshal(w.Body.Bytes(), &page); w.Code != http.StatusOK || err != nil {
+			t.Fatalf("GET %v returned %v %s", tc.path, w.Code, w.Body)
+		}
+		got := []int{}
+		for _, a := range page.Articles {
+			got = append(got, a.ArticleID)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("GET %v listed articles %v, want %v", tc.path, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+		typ                string
+	}{
+		{http.MethodGet, "/tags?status=bogus", "", http.StatusBadRequest, problemTypeInvalidParameter},
+		{http.MethodGet, "/tags/%20/articles", "", http.StatusBadRequest, problemTypeInvalidParameter},
+		{http.MethodPost, "/articles/1/tags", `{"tags":[]}`, http.StatusUnprocessableEntity, problemTypeValidation},
+		{http.MethodPost, "/articles/1/tags", `{"tags":["a/b"]}`, http.StatusUnprocessableEntity, problemTypeValidation},
+		{http.MethodDelete, "/articles/1/tags", `{"tags":[" "]}`, http.StatusUnprocessableEntity, problemTypeValidation},
+		{http.MethodPost, "/articles/99/tags", `{"tags":["go"]}`, http.StatusNotFound, problemTypeNotFound},
+		{http.MethodPost, "/articles/1/tags", `{"tags":"go"}`, http.StatusBadRequest, problemTypeMalformedBody},
+	} {
+		w := do(tc.method, tc.path, tc.body)
+		var p models.Problem
+		if w.Code != tc.status || json.Unmarshal(w.Body.Bytes(), &p) != nil || p.Type != tc.typ {
+			t.Fatalf("%v %v returned %v %s, want a %v problem of type %v", tc.method, tc.path, w.Code, w.Body, tc.status, tc.typ)
+		}
+	}
+}
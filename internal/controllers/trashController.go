@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+//GetTrashHandler processes request and makes server call to fetch a page of trashed articles
+//GET /trash?limit=20&cursor=c
+func (c *Controller) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	log.InfoLog("Request received: retrieving trashed articles")
+
+	limit, ok := queryLimit(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := c.s.ListTrash(r.Context(), r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		log.ErrorLog("Error while retrieving page of trashed articles", err)
+		writeError(w, r, err)
+		return
+	}
+	c.writePage(w, r, page)
+}
+
+//RestoreTrashedArticleHandler processes request and makes server call to bring an article with
+//given artID back out of the trash, honoring If-Match like PUT
+//POST /trash/{articleID}/restore
+func (c *Controller) RestoreTrashedArticleHandler(w http.ResponseWriter, r *http.Request) {
+	artID, ok := pathInt(w, r, "articleID")
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: restoring trashed article with id%v", artID))
+
+	version, ok := conditionalVersion(w, r, artID, c.s.GetTrashedArticle)
+	if !ok {
+		return
+	}
+
+	art, err := c.s.RestoreArticle(r.Context(), artID, version)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while restoring trashed article with id:%v", artID), err)
+		writeError(w, r, err)
+		return
+	}
+	c.writeArticle(w, r, http.StatusOK, art)
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/server"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+//defaultPopularWindow is the window popular articles are ranked over when none is asked for
+const defaultPopularWindow = 24 * time.Hour
+
+//GetPopularArticlesHandler processes request and makes server call to fetch the published
+//articles viewed most during the window, a duration such as 90m or 24h ending now
+//GET /articles/popular?window=24h&limit=10
+func (c *Controller) GetPopularArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	window := defaultPopularWindow
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > server.MaxPopularWindow {
+			log.ErrorLog(fmt.Sprintf("Error while parsing window query parameter:%v", v), err)
+			writeProblem(w, r, http.StatusBadRequest, problemTypeInvalidParameter,
+				fmt.Sprintf("window must be a positive duration of at most %v such as 24h, got %q", server.MaxPopularWindow, v))
+			return
+		}
+		window = d
+	}
+	limit, ok := queryLimit(w, r)
+	if !ok {
+		return
+	}
+
+	log.InfoLog(fmt.Sprintf("Request received: retrieving articles viewed most in the last %v", window))
+
+	popular, err := c.s.PopularArticles(r.Context(), window, limit)
+	if err != nil {
+		log.ErrorLog("Error while retrieving popular articles", err)
+		writeError(w, r, err)
+		return
+	}
+	arts := make([]models.Article, len(popular))
+	for i, p := range popular {
+		arts[i] = p.Article
+	}
+	c.withReactions(r, arts)
+	for i := range popular {
+		popular[i].Article = arts[i]
+	}
+	writeJSON(w, r, http.StatusOK, models.PopularArticles{Window: window.String(), Articles: popular})
+}
+
+//viewClient identifies the client reading an article for view counting, the user named by the
+//UserIDHeader or else the address the request came from
+func viewClient(r *http.Request) string {
+	if u := r.Header.Get(UserIDHeader); u != "" {
+		return "user:" + u
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
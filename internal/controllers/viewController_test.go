@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/server"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+func TestArticleReadCountsViewOnlyWhenServed(t *testing.T) {
+	s := server.NewServer(storage.NewMockDynamo())
+	h := NewRouter(NewController(s))
+	do := func(method, path, body string, hdr ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for i := 0; i+1 < len(hdr); i += 2 {
+			req.Header.Set(hdr[i], hdr[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	do(http.MethodPost, "/articles", `[{"userID":1,"title":"Hello World","body":"b"}]`)
+	tag := do(http.MethodGet, "/articles/1", "", UserIDHeader, "2").Header().Get("ETag")
+	if _, err := s.FlushViews(context.Background()); err != nil {
+		t.Fatalf("FlushViews returned %v", err)
+	}
+
+	//Every request comes from another user so none is deduplicated
+	for _, tc := range []struct {
+		path, user, ifNoneMatch string
+		code, views             int
+	}{
+		{"/articles/1", "3", tag, http.StatusNotModified, 0},
+		{"/articles/by-slug/hello-world", "4", tag, http.StatusNotModified, 0},
+		{"/articles/1", "5", `"0-0"`, http.StatusOK, 1},
+		{"/articles/by-slug/hello-world", "6", "", http.StatusOK, 1},
+	} {
+		if w := do(http.MethodGet, tc.path, "", UserIDHeader, tc.user, "If-None-Match", tc.ifNoneMatch); w.Code != tc.code {
+			t.Fatalf("GET %v with If-None-Match %q returned %v, want %v", tc.path, tc.ifNoneMatch, w.Code, tc.code)
+		}
+		n, err := s.FlushViews(context.Background())
+		if err != nil {
+			t.Fatalf("FlushViews returned %v", err)
+		}
+		if n != tc.views {
+			t.Fatalf("GET %v answering %v counted %v views, want %v", tc.path, tc.code, n, tc.views)
+		}
+	}
+}
@@ -1,18 +1,63 @@
 package models
 
+import "time"
+
+//Article statuses, an article moves between them through transitions
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
 type (
 	//Article provides the data model for an Article resource
 	Article struct {
-		UserID    int    `json:"userID"`
-		ArticleID int    `json:"articleID"`
-		Title     string `json:"title"`
-		Body      string `json:"body"`
+		UserID         int         `json:"userID" validate:"required,min=1"`
+		ArticleID      int         `json:"articleID"`
+		Title          string      `json:"title" validate:"required,max=200"`
+		Slug           string      `json:"slug"`
+		Body           string      `json:"body" validate:"required,max=50000"`
+		Category       string      `json:"category,omitempty" validate:"max=50"`
+		Tags           []string    `json:"tags,omitempty"`
+		Reactions      *Reactions  `json:"reactions,omitempty"`
+		Version        int         `json:"version"`
+		Status         string      `json:"status"`
+		CreatedAt      time.Time   `json:"createdAt"`
+		UpdatedAt      time.Time   `json:"updatedAt"`
+		CreatedBy      int         `json:"createdBy,omitempty"`
+		UpdatedBy      int         `json:"updatedBy,omitempty"`
+		LastTransition *Transition `json:"lastTransition,omitempty"`
+		PublishAt      *time.Time  `json:"publishAt,omitempty"`
+		UnpublishAt    *time.Time  `json:"unpublishAt,omitempty"`
+		DeletedAt      *time.Time  `json:"deletedAt,omitempty"`
+	}
+
+	//Transition provides the data model for a change of an article's status
+	Transition struct {
+		From string    `json:"from"`
+		To   string    `json:"to"`
+		By   int       `json:"by"`
+		At   time.Time `json:"at"`
+	}
+
+	//TransitionRequest provides the data model for the request payload moving an article to a
+	//new status, the user making the move is named by the request's X-User-ID header
+	TransitionRequest struct {
+		Status string `json:"status" validate:"required,oneof=draft in_review published archived"`
+	}
+
+	//ArticlePage provides the data model for one page of an article listing
+	ArticlePage struct {
+		Articles   []Article `json:"articles"`
+		NextCursor string    `json:"nextCursor,omitempty"`
 	}
 
 	//NewArticle provices the data model for the request paylod of a new Article
 	NewArticle struct {
-		UserID int    `json:"userID"`
-		Title  string `json:"title"`
-		Body   string `json:"body"`
+		UserID   int    `json:"userID" validate:"required,min=1"`
+		Title    string `json:"title" validate:"required,max=200"`
+		Body     string `json:"body" validate:"required,max=50000"`
+		Category string `json:"category,omitempty" validate:"max=50"`
 	}
 )
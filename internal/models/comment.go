@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type (
+	//Comment provides the data model for a comment on an article. Comments replying to another
+	//one name it as their parent, top level comments have none. A deleted comment that still has
+	//replies is kept with its body blanked so that the thread stays whole
+	Comment struct {
+		CommentID int       `json:"commentID"`
+		ArticleID int       `json:"articleID"`
+		ParentID  int       `json:"parentID,omitempty"`
+		UserID    int       `json:"userID"`
+		Body      string    `json:"body"`
+		Replies   int       `json:"replies"`
+		Deleted   bool      `json:"deleted,omitempty"`
+		CreatedAt time.Time `json:"createdAt"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+
+	//NewComment provides the data model for the request payload of a new comment or reply, its
+	//author is named by the request's X-User-ID header
+	NewComment struct {
+		Body string `json:"body" validate:"required,max=5000"`
+	}
+
+	//CommentUpdate provides the data model for the request payload editing a comment
+	CommentUpdate struct {
+		Body string `json:"body" validate:"required,max=5000"`
+	}
+
+	//CommentPage provides the data model for one page of a comment listing
+	CommentPage struct {
+		Comments   []Comment `json:"comments"`
+		NextCursor string    `json:"nextCursor,omitempty"`
+	}
+)
@@ -0,0 +1,22 @@
+package models
+
+//Reaction kinds a reader can react to an article with, clients show them as the emoji 👍, ❤️,
+//😂, 😮, 😢 and 🎉
+const (
+	ReactionLike      = "like"
+	ReactionLove      = "love"
+	ReactionLaugh     = "laugh"
+	ReactionWow       = "wow"
+	ReactionSad       = "sad"
+	ReactionCelebrate = "celebrate"
+)
+
+//ReactionKinds lists every reaction kind in the order clients show them
+var ReactionKinds = []string{ReactionLike, ReactionLove, ReactionLaugh, ReactionWow, ReactionSad, ReactionCelebrate}
+
+//Reactions provides the data model for the reactions to an article, the number of readers
+//reacting with each kind and the kinds the user making the request reacted with
+type Reactions struct {
+	Counts map[string]int `json:"counts"`
+	Mine   []string       `json:"mine,omitempty"`
+}
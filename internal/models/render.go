@@ -0,0 +1,14 @@
+package models
+
+type (
+	//PreviewRequest provides the data model for the request payload rendering an unsaved article
+	//body
+	PreviewRequest struct {
+		Body string `json:"body" validate:"max=50000"`
+	}
+
+	//Preview provides the data model for an article body rendered as sanitized HTML
+	Preview struct {
+		HTML string `json:"html"`
+	}
+)
@@ -0,0 +1,23 @@
+package models
+
+type (
+	//ArticleDiff provides the data model for the changes between two revisions of an article
+	ArticleDiff struct {
+		ArticleID int         `json:"articleID"`
+		From      int         `json:"from"`
+		To        int         `json:"to"`
+		Fields    []FieldDiff `json:"fields"`
+	}
+
+	//FieldDiff provides the data model for the line by line changes to a single article field
+	FieldDiff struct {
+		Field string     `json:"field"`
+		Lines []DiffLine `json:"lines"`
+	}
+
+	//DiffLine provides the data model for one line of a field diff, op is equal, delete or insert
+	DiffLine struct {
+		Op   string `json:"op"`
+		Text string `json:"text"`
+	}
+)
@@ -0,0 +1,18 @@
+package models
+
+type (
+	//SearchResult provides the data model for an article matching a search. Highlights holds a
+	//snippet of each matching field, HTML escaped with the matched words wrapped in <mark> tags
+	SearchResult struct {
+		Article    Article           `json:"article"`
+		Score      float64           `json:"score"`
+		Highlights map[string]string `json:"highlights"`
+	}
+
+	//SearchPage provides the data model for one page of search results, best match first
+	SearchPage struct {
+		Results    []SearchResult `json:"results"`
+		Total      int            `json:"total"`
+		NextCursor string         `json:"nextCursor,omitempty"`
+	}
+)
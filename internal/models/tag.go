@@ -0,0 +1,20 @@
+package models
+
+type (
+	//TagsRequest provides the data model for the request payload adding tags to or removing
+	//them from an article
+	TagsRequest struct {
+		Tags []string `json:"tags"`
+	}
+
+	//TagCount provides the data model for a tag along with the number of articles carrying it
+	TagCount struct {
+		Tag   string `json:"tag"`
+		Count int    `json:"count"`
+	}
+
+	//TagList provides the data model for the listing of every tag in use
+	TagList struct {
+		Tags []TagCount `json:"tags"`
+	}
+)
@@ -0,0 +1,17 @@
+package models
+
+type (
+	//PopularArticle provides the data model for an article along with the number of times it was
+	//viewed during a window
+	PopularArticle struct {
+		Article Article `json:"article"`
+		Views   int     `json:"views"`
+	}
+
+	//PopularArticles provides the data model for the articles viewed most during a window, most
+	//viewed first
+	PopularArticles struct {
+		Window   string           `json:"window"`
+		Articles []PopularArticle `json:"articles"`
+	}
+)
@@ -0,0 +1,17 @@
+package server
+
+import "context"
+
+type actorKey struct{}
+
+//WithActor returns a context telling the server which user is making the request, it is
+//recorded as the creator or last editor of the articles the request writes
+func WithActor(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+//actor returns the user making the request, or 0 when the context names none
+func actor(ctx context.Context) int {
+	id, _ := ctx.Value(actorKey{}).(int)
+	return id
+}
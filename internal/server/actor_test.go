@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	"github.com/Perezonance/article-management-service/internal/util/patch"
+)
+
+func TestWritesCreditRequestUserOnly(t *testing.T) {
+	ctx := context.Background()
+	db := storage.NewMockDynamo()
+	s := NewServer(db)
+
+	id, err := s.CreateArticle(ctx, models.NewArticle{UserID: 5, Title: "t", Body: "b"})
+	if err != nil {
+		t.Fatalf("CreateArticle returned %v", err)
+	}
+	if a := article(t, db, id); a.CreatedBy != 0 || a.UpdatedBy != 0 {
+		t.Fatalf("article created without an actor credited %v and %v, want neither", a.CreatedBy, a.UpdatedBy)
+	}
+
+	id, err = s.CreateArticle(WithActor(ctx, 7), models.NewArticle{UserID: 5, Title: "t", Body: "b"})
+	if err != nil {
+		t.Fatalf("CreateArticle returned %v", err)
+	}
+	if a := article(t, db, id); a.CreatedBy != 7 || a.UpdatedBy != 7 {
+		t.Fatalf("article created by user 7 credited %v and %v", a.CreatedBy, a.UpdatedBy)
+	}
+
+	a, err := s.UpdateArticle(ctx, models.Article{ArticleID: id, UserID: 5, Title: "n", Body: "b", Version: storage.AnyVersion})
+	if err != nil {
+		t.Fatalf("UpdateArticle returned %v", err)
+	}
+	if a.CreatedBy != 7 || a.UpdatedBy != 0 {
+		t.Fatalf("update without an actor credited %v and %v, want 7 and no one", a.CreatedBy, a.UpdatedBy)
+	}
+}
+
+func TestAuthorCannotChange(t *testing.T) {
+	ctx := context.Background()
+	db := storage.NewMockDynamo()
+	s := NewServer(db)
+	id, err := s.CreateArticle(ctx, models.NewArticle{UserID: 5, Title: "t", Body: "b"})
+	if err != nil {
+		t.Fatalf("CreateArticle returned %v", err)
+	}
+
+	for name, write := range map[string]func() error{
+		"PUT": func() error {
+			_, err := s.UpdateArticle(ctx, models.Article{ArticleID: id, UserID: 6, Title: "t", Body: "b", Version: storage.AnyVersion})
+			return err
+		},
+		"merge patch": func() error {
+			_, err := s.PatchArticle(ctx, id, storage.AnyVersion, patch.MergePatchType, []byte(`{"userID":6}`))
+			return err
+		},
+		"JSON patch": func() error {
+			_, err := s.PatchArticle(ctx, id, storage.AnyVersion, patch.JSONPatchType, []byte(`[{"op":"replace","path":"/userID","value":6}]`))
+			return err
+		},
+	} {
+		var verr *ValidationError
+		if err := write(); !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Field != "userID" {
+			t.Errorf("%v changing the author returned %v, want a userID validation error", name, err)
+		}
+	}
+	if a := article(t, db, id); a.UserID != 5 || a.Version != 1 {
+		t.Fatalf("article is by user %v at version %v after rejected writes, want user 5 at version 1", a.UserID, a.Version)
+	}
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+//ListComments returns one page of the comments on an article outside the trash that reply to
+//the comment parentID, or of its top level comments when parentID is 0, oldest first and
+//resuming after the given opaque cursor
+//GET /articles/{articleId}/comments
+//GET /articles/{articleId}/comments/{commentId}/replies
+func (s *Server) ListComments(ctx context.Context, articleID, parentID int, cur string, limit int) (models.CommentPage, error) {
+	c, err := decodeCursor(cur)
+	if err != nil {
+		return models.CommentPage{}, err
+	}
+	if parentID != 0 {
+		_, err = s.GetComment(ctx, articleID, parentID)
+	} else {
+		_, err = s.GetArticleByID(ctx, articleID)
+	}
+	if err != nil {
+		return models.CommentPage{}, err
+	}
+
+	page, err := s.comments.ListComments(ctx, storage.CommentQuery{ArticleID: articleID, ParentID: parentID, AfterID: c.AfterID, Limit: pageLimit(limit)})
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error fetching page of comments on article with id:%v", articleID), err)
+		return models.CommentPage{}, err
+	}
+	res := models.CommentPage{Comments: page.Comments}
+	if res.Comments == nil {
+		res.Comments = []models.Comment{}
+	}
+	if page.More {
+		res.NextCursor = encodeCursor(cursor{AfterID: page.Comments[len(page.Comments)-1].CommentID})
+	}
+	return res, nil
+}
+
+//GetComment returns the comment with the given id on an article outside the trash
+//GET /articles/{articleId}/comments/{commentId}
+func (s *Server) GetComment(ctx context.Context, articleID, id int) (models.Comment, error) {
+	if _, err := s.GetArticleByID(ctx, articleID); err != nil {
+		return models.Comment{}, err
+	}
+	c, err := s.comments.GetComment(ctx, id)
+	if err == nil && c.ArticleID != articleID {
+		err = storage.ErrResourceNotFound
+	}
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while requesting comment with id:%v", id), err)
+		return models.Comment{}, err
+	}
+	return c, nil
+}
+
+//CreateComment adds a comment by the user making the request to an article outside the trash
+//and returns it. The comment replies to the comment parentID unless it is 0, deleted comments
+//take no replies
+//POST /articles/{articleId}/comments
+//POST /articles/{articleId}/comments/{commentId}/replies
+func (s *Server) CreateComment(ctx context.Context, articleID, parentID int, nc models.NewComment) (models.Comment, error) {
+	userID := actor(ctx)
+	if userID == 0 {
+		return models.Comment{}, ErrNoActor
+	}
+	var err error
+	if parentID != 0 {
+		var parent models.Comment
+		if parent, err = s.GetComment(ctx, articleID, parentID); err == nil && parent.Deleted {
+			err = ErrCommentDeleted
+		}
+	} else {
+		_, err = s.GetArticleByID(ctx, articleID)
+	}
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	now := s.clock.Now().UTC()
+	c := models.Comment{ArticleID: articleID, ParentID: parentID, UserID: userID, Body: nc.Body, CreatedAt: now, UpdatedAt: now}
+	if c.CommentID, err = s.comments.CreateComment(ctx, c); err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while creating comment on article with id:%v", articleID), err)
+		return models.Comment{}, err
+	}
+	return c, nil
+}
+
+//UpdateComment replaces the body of a comment by the user making the request that has not been
+//deleted and returns it
+//PUT /articles/{articleId}/comments/{commentId}
+func (s *Server) UpdateComment(ctx context.Context, articleID, id int, body string) (models.Comment, error) {
+	c, err := s.authoredComment(ctx, articleID, id)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if c.Deleted {
+		return models.Comment{}, ErrCommentDeleted
+	}
+
+	c.Body, c.UpdatedAt = body, s.clock.Now().UTC()
+	if err := s.comments.UpdateComment(ctx, c); err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while updating comment with id:%v", id), err)
+		return models.Comment{}, err
+	}
+	return c, nil
+}
+
+//DeleteComment removes a comment by the user making the request. A comment others reply to is
+//blanked and marked deleted instead so that its replies keep their place in the thread, and it
+//is removed along with its last reply
+//DELETE /articles/{articleId}/comments/{commentId}
+func (s *Server) DeleteComment(ctx context.Context, articleID, id int) error {
+	c, err := s.authoredComment(ctx, articleID, id)
+	if err != nil {
+		return err
+	}
+	for {
+		err := s.comments.DeleteComment(ctx, c.CommentID)
+		if err == storage.ErrCommentHasReplies {
+			c.Body, c.Deleted, c.UpdatedAt = "", true, s.clock.Now().UTC()
+			return s.comments.UpdateComment(ctx, c)
+		}
+		if err != nil {
+			log.ErrorLog(fmt.Sprintf("Error while deleting comment with id:%v", c.CommentID), err)
+			return err
+		}
+		if c.ParentID == 0 {
+			return nil
+		}
+
+		parent, err := s.comments.GetComment(ctx, c.ParentID)
+		if err != nil || !parent.Deleted || parent.Replies > 0 {
+			return nil
+		}
+		c = parent
+	}
+}
+
+//authoredComment returns a comment on an article outside the trash provided it was written by
+//the user making the request
+func (s *Server) authoredComment(ctx context.Context, articleID, id int) (models.Comment, error) {
+	userID := actor(ctx)
+	if userID == 0 {
+		return models.Comment{}, ErrNoActor
+	}
+	c, err := s.GetComment(ctx, articleID, id)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if c.UserID != userID {
+		return models.Comment{}, ErrNotCommentAuthor
+	}
+	return c, nil
+}
+
+//deleteArticleComments removes the comments of an article that has been deleted. A failure is
+//only logged, the comments of a missing article can no longer be reached
+func (s *Server) deleteArticleComments(ctx context.Context, articleID int) {
+	n, err := s.comments.DeleteArticleComments(ctx, articleID)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while deleting the comments of article with id:%v", articleID), err)
+		return
+	}
+	if n > 0 {
+		log.InfoLog(fmt.Sprintf("Deleted %v comments of article with id:%v", n, articleID))
+	}
+}
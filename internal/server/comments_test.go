@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+func TestCommentsActForRequestUser(t *testing.T) {
+	ctx := context.Background()
+	clk := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	db := storage.NewMockDynamo()
+	s := NewServerWithStores(db, storage.NewMemoryCommentStorage(), storage.NewMemoryReactionStorage(), storage.NewMemoryViewStorage(), clk)
+	a, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "a", Body: "b"})
+	if err != nil {
+		t.Fatalf("CreateArticle returned %v", err)
+	}
+	author, other := WithActor(ctx, 2), WithActor(ctx, 3)
+
+	if _, err := s.CreateComment(ctx, a, 0, models.NewComment{Body: "hi"}); err != ErrNoActor {
+		t.Fatalf("CreateComment without an actor returned %v, want ErrNoActor", err)
+	}
+	c, err := s.CreateComment(author, a, 0, models.NewComment{Body: "hi"})
+	if err != nil || c.UserID != 2 {
+		t.Fatalf("CreateComment returned %+v, %v, want a comment by user 2", c, err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{"without an actor", ctx, ErrNoActor},
+		{"by another user", other, ErrNotCommentAuthor},
+	} {
+		if _, err := s.UpdateComment(tc.ctx, a, c.CommentID, "edited"); err != tc.want {
+			t.Fatalf("UpdateComment %v returned %v, want %v", tc.name, err, tc.want)
+		}
+		if err := s.DeleteComment(tc.ctx, a, c.CommentID); err != tc.want {
+			t.Fatalf("DeleteComment %v returned %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	if c, err = s.UpdateComment(author, a, c.CommentID, "edited"); err != nil || c.Body != "edited" {
+		t.Fatalf("UpdateComment by its author returned %+v, %v", c, err)
+	}
+	if err := s.DeleteComment(author, a, c.CommentID); err != nil {
+		t.Fatalf("DeleteComment by its author returned %v", err)
+	}
+	if _, err := s.GetComment(ctx, a, c.CommentID); err != storage.ErrResourceNotFound {
+		t.Fatalf("GetComment after delete returned %v, want storage.ErrResourceNotFound", err)
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+const (
+	//DefaultPageLimit is the page size used when the client does not ask for one
+	DefaultPageLimit = 20
+	//MaxPageLimit caps the page size a client can ask for
+	MaxPageLimit = 100
+)
+
+//cursor is the position in a listing a client resumes from, the last article seen along with
+//the value it was sorted by for listings and the number of results seen for ranked searches.
+//The sort order is kept so that a cursor is not resumed under another one. It is handed out
+//base64 encoded so clients treat it as opaque
+type cursor struct {
+	AfterID    int        `json:"a,omitempty"`
+	AfterTitle string     `json:"t,omitempty"`
+	AfterTime  *time.Time `json:"c,omitempty"`
+	Sort       string     `json:"s,omitempty"`
+	Desc       bool       `json:"d,omitempty"`
+	Offset     int        `json:"o,omitempty"`
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.AfterID < 0 || c.Offset < 0 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+//pageLimit clamps a requested page size into [1, MaxPageLimit], 0 selects the default
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return limit
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2024, 1, 1, 9, 30, 0, 123, time.UTC)
+	for _, c := range []cursor{
+		{},
+		{AfterID: 42},
+		{AfterID: 7, AfterTitle: "Hello, \"world\" / é", Sort: storage.SortByTitle, Desc: true},
+		{AfterID: 3, AfterTime: &at, Sort: storage.SortByCreatedAt},
+		{Offset: 60},
+	} {
+		s := encodeCursor(c)
+		got, err := decodeCursor(s)
+		if err != nil {
+			t.Fatalf("decodeCursor(encodeCursor(%+v)) returned %v", c, err)
+		}
+		if !reflect.DeepEqual(got, c) {
+			t.Fatalf("decodeCursor(encodeCursor(%+v)) = %+v", c, got)
+		}
+	}
+}
+
+func TestDecodeCursorRejectsTampering(t *testing.T) {
+	enc := base64.RawURLEncoding.EncodeToString
+	for _, s := range []string{
+		"not base64!",
+		encodeCursor(cursor{AfterID: 5}) + "=",
+		enc([]byte("not json")),
+		enc([]byte(`{"a":"five"}`)),
+		enc([]byte(`{"a":-1}`)),
+		enc([]byte(`{"o":-20}`)),
+	} {
+		if _, err := decodeCursor(s); err != ErrInvalidCursor {
+			t.Fatalf("decodeCursor(%q) returned %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestPageLimit(t *testing.T) {
+	for _, tc := range []struct{ in, want int }{
+		{-5, DefaultPageLimit},
+		{0, DefaultPageLimit},
+		{1, 1},
+		{MaxPageLimit, MaxPageLimit},
+		{MaxPageLimit + 1, MaxPageLimit},
+		{1 << 30, MaxPageLimit},
+	} {
+		if got := pageLimit(tc.in); got != tc.want {
+			t.Fatalf("pageLimit(%v) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestListArticlesFollowsNextCursor(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	s := NewServerWithClock(storage.NewMockDynamo(), clk)
+	ctx := context.Background()
+	n := MaxPageLimit + 10
+	for i := 0; i < n; i++ {
+		//Titles run backwards and every other article shares its creation time with the next
+		if _, err := s.CreateArticle(ctx, models.NewArticle{UserID: 1, Title: fmt.Sprintf("title %03d", n-i), Body: "b"}); err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+		if i%2 == 1 {
+			clk.t = clk.t.Add(time.Minute)
+		}
+	}
+
+	for _, tc := range []struct {
+		limit, size int
+	}{
+		{0, DefaultPageLimit},
+		{7, 7},
+		{MaxPageLimit * 5, MaxPageLimit},
+	} {
+		page, err := s.ListArticles(ctx, storage.PageQuery{Limit: tc.limit}, "")
+		if err != nil {
+			t.Fatalf("ListArticles returned %v", err)
+		}
+		if len(page.Articles) != tc.size || page.NextCursor == "" {
+			t.Fatalf("ListArticles with limit %v returned %v articles and cursor %q, want %v and a cursor", tc.limit, len(page.Articles), page.NextCursor, tc.size)
+		}
+	}
+
+	for _, q := range []storage.PageQuery{
+		{},
+		{Sort: storage.SortByTitle},
+		{Sort: storage.SortByCreatedAt, Desc: true},
+	} {
+		q.Limit = 15
+		seen := map[int]bool{}
+		var prev *models.Article
+		cur := ""
+		for pages := 0; ; pages++ {
+			if pages > n {
+				t.Fatalf("listing sorted by %q never ended", q.Sort)
+			}
+			page, err := s.ListArticles(ctx, q, cur)
+			if err != nil {
+				t.Fatalf("ListArticles sorted by %q returned %v", q.Sort, err)
+			}
+			for i, a := range page.Articles {
+				if seen[a.ArticleID] {
+					t.Fatalf("listing sorted by %q returned article %v twice", q.Sort, a.ArticleID)
+				}
+				seen[a.ArticleID] = true
+				if prev != nil && !q.Less(*prev, a) {
+					t.Fatalf("listing sorted by %q returned article %v after %v", q.Sort, a.ArticleID, prev.ArticleID)
+				}
+				prev = &page.Articles[i]
+			}
+			if cur = page.NextCursor; cur == "" {
+				break
+			}
+		}
+		if len(seen) != n {
+			t.Fatalf("listing sorted by %q returned %v articles, want %v", q.Sort, len(seen), n)
+		}
+	}
+
+	//A cursor only resumes the order it was handed out for
+	page, err := s.ListArticles(ctx, storage.PageQuery{Sort: storage.SortByTitle, Limit: 5}, "")
+	if err != nil {
+		t.Fatalf("ListArticles returned %v", err)
+	}
+	if _, err := s.ListArticles(ctx, storage.PageQuery{Sort: storage.SortByTitle, Desc: true, Limit: 5}, page.NextCursor); err != ErrInvalidCursor {
+		t.Fatalf("ListArticles with a cursor of another order returned %v, want ErrInvalidCursor", err)
+	}
+	if _, err := s.ListArticles(ctx, storage.PageQuery{Limit: 5}, "x"+page.NextCursor); err != ErrInvalidCursor {
+		t.Fatalf("ListArticles with a tampered cursor returned %v, want ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Perezonance/article-management-service/internal/util/validator"
+)
+
+var (
+	//ErrInvalidCursor is thrown when a pagination cursor cannot be decoded
+	ErrInvalidCursor = errors.New("pagination cursor is invalid")
+	//ErrUnsupportedPatchType is thrown when a patch document is neither a JSON Merge Patch nor a JSON Patch
+	ErrUnsupportedPatchType = errors.New("patch media type is not supported")
+	//ErrIllegalTransition is thrown when an article cannot move from its status to the one requested
+	ErrIllegalTransition = errors.New("status transition is not allowed")
+	//ErrCommentDeleted is thrown when editing or replying to a comment that has been deleted
+	ErrCommentDeleted = errors.New("comment has been deleted")
+	//ErrUnknownReaction is thrown when reacting to an article with a kind that is not in models.ReactionKinds
+	ErrUnknownReaction = errors.New("reaction kind is not supported")
+	//ErrNoActor is thrown when a request acting on behalf of a user does not say which user it is
+	ErrNoActor = errors.New("request does not name the user making it")
+	//ErrNotCommentAuthor is thrown when a user edits or deletes a comment written by another user
+	ErrNotCommentAuthor = errors.New("comment was written by another user")
+)
+
+//ValidationError is returned when an article produced by the server fails its model's rules
+type ValidationError struct {
+	Fields []validator.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v fields failed validation", len(e.Fields))
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+//GetReactions returns the reactions to an article outside the trash, including those of the
+//user making the request
+//GET /articles/{articleId}/reactions
+func (s *Server) GetReactions(ctx context.Context, id int) (models.Reactions, error) {
+	if _, err := s.GetArticleByID(ctx, id); err != nil {
+		return models.Reactions{}, err
+	}
+	return s.articleReactions(ctx, id)
+}
+
+//AddReaction records that the user making the request reacted to an article outside the trash
+//with the given kind and returns the article's reactions. Reacting again with the same kind
+//changes nothing
+//PUT /articles/{articleId}/reactions/{kind}
+func (s *Server) AddReaction(ctx context.Context, id int, kind string) (models.Reactions, error) {
+	return s.react(ctx, id, kind, "adding", s.reactions.AddReaction)
+}
+
+//RemoveReaction withdraws the reaction of the user making the request to an article outside the
+//trash with the given kind and returns the article's reactions. Withdrawing a reaction the user
+//never made changes nothing
+//DELETE /articles/{articleId}/reactions/{kind}
+func (s *Server) RemoveReaction(ctx context.Context, id int, kind string) (models.Reactions, error) {
+	return s.react(ctx, id, kind, "removing", s.reactions.RemoveReaction)
+}
+
+//FillReactions sets the Reactions of each article, including those of the user making the
+//request
+func (s *Server) FillReactions(ctx context.Context, arts []models.Article) error {
+	if len(arts) == 0 {
+		return nil
+	}
+	ids := make([]int, len(arts))
+	for i, a := range arts {
+		ids[i] = a.ArticleID
+	}
+	reactions, err := s.reactions.GetReactions(ctx, actor(ctx), ids)
+	if err != nil {
+		return err
+	}
+	for i := range arts {
+		r := withEveryKind(reactions[arts[i].ArticleID])
+		arts[i].Reactions = &r
+	}
+	return nil
+}
+
+//react applies change to the reaction of the user making the request to the article and returns
+//the article's reactions
+func (s *Server) react(ctx context.Context, id int, kind, action string, change func(context.Context, int, int, string) (bool, error)) (models.Reactions, error) {
+	if !knownReaction(kind) {
+		return models.Reactions{}, ErrUnknownReaction
+	}
+	userID := actor(ctx)
+	if userID == 0 {
+		return models.Reactions{}, ErrNoActor
+	}
+	if _, err := s.GetArticleByID(ctx, id); err != nil {
+		return models.Reactions{}, err
+	}
+	if _, err := change(ctx, id, userID, kind); err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while %v %v reaction of user %v to article with id:%v", action, kind, userID, id), err)
+		return models.Reactions{}, err
+	}
+	return s.articleReactions(ctx, id)
+}
+
+//articleReactions looks up the reactions to a single article
+func (s *Server) articleReactions(ctx context.Context, id int) (models.Reactions, error) {
+	reactions, err := s.reactions.GetReactions(ctx, actor(ctx), []int{id})
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while requesting reactions to article with id:%v", id), err)
+		return models.Reactions{}, err
+	}
+	return withEveryKind(reactions[id]), nil
+}
+
+//deleteArticleReactions removes the reactions to an article that has been deleted. A failure is
+//only logged, the reactions to a missing article can no longer be reached
+func (s *Server) deleteArticleReactions(ctx context.Context, articleID int) {
+	n, err := s.reactions.DeleteArticleReactions(ctx, articleID)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while deleting the reactions to article with id:%v", articleID), err)
+		return
+	}
+	if n > 0 {
+		log.InfoLog(fmt.Sprintf("Deleted %v reactions to article with id:%v", n, articleID))
+	}
+}
+
+//withEveryKind returns the reactions with a count, possibly 0, for every kind so that clients
+//need not special case kinds nobody reacted with yet
+func withEveryKind(r models.Reactions) models.Reactions {
+	counts := make(map[string]int, len(models.ReactionKinds))
+	for _, k := range models.ReactionKinds {
+		counts[k] = r.Counts[k]
+	}
+	r.Counts = counts
+	return r
+}
+
+func knownReaction(kind string) bool {
+	for _, k := range models.ReactionKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
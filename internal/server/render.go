@@ -0,0 +1,22 @@
+package server
+
+import (
+	"html"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/util/markdown"
+)
+
+//RenderMarkdown renders an article body written in Markdown as sanitized HTML, so that editors
+//can preview content they have not saved yet
+//POST /render/preview
+func (s *Server) RenderMarkdown(body string) string {
+	return markdown.Render(body)
+}
+
+//RenderArticle renders an article as a sanitized HTML fragment, its title as the top heading
+//followed by its Markdown body
+//GET /articles/{articleId}?format=html
+func (s *Server) RenderArticle(a models.Article) string {
+	return "<article>\n<h1>" + html.EscapeString(a.Title) + "</h1>\n" + markdown.Render(a.Body) + "</article>\n"
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/util/diff"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+//GetRevisions returns every revision of an article outside the trash ordered by version
+//GET /articles/{articleId}/revisions
+func (s *Server) GetRevisions(ctx context.Context, id int) ([]models.Article, error) {
+	if _, err := s.GetArticleByID(ctx, id); err != nil {
+		return nil, err
+	}
+	revs, err := s.db.GetRevisions(ctx, id)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while fetching revisions of article with id:%v", id), err)
+		return nil, err
+	}
+	return revs, nil
+}
+
+//GetRevision returns the revision of an article outside the trash at the given version
+//GET /articles/{articleId}/revisions/{rev}
+func (s *Server) GetRevision(ctx context.Context, id, rev int) (models.Article, error) {
+	if _, err := s.GetArticleByID(ctx, id); err != nil {
+		return models.Article{}, err
+	}
+	a, err := s.db.GetRevision(ctx, id, rev)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while fetching revision %v of article with id:%v", rev, id), err)
+		return models.Article{}, err
+	}
+	return a, nil
+}
+
+//DiffRevisions compares two revisions of an article and returns a line diff of every field
+//that differs between them
+//GET /articles/{articleId}/revisions/diff?from=1&to=2
+func (s *Server) DiffRevisions(ctx context.Context, id, from, to int) (models.ArticleDiff, error) {
+	a, err := s.GetRevision(ctx, id, from)
+	if err != nil {
+		return models.ArticleDiff{}, err
+	}
+	b, err := s.GetRevision(ctx, id, to)
+	if err != nil {
+		return models.ArticleDiff{}, err
+	}
+
+	res := models.ArticleDiff{ArticleID: id, From: from, To: to, Fields: []models.FieldDiff{}}
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"userID", strconv.Itoa(a.UserID), strconv.Itoa(b.UserID)},
+		{"title", a.Title, b.Title},
+		{"body", a.Body, b.Body},
+		{"category", a.Category, b.Category},
+	}
+	for _, f := range fields {
+		if f.old == f.new {
+			continue
+		}
+		fd := models.FieldDiff{Field: f.name}
+		for _, l := range diff.Lines(f.old, f.new) {
+			fd.Lines = append(fd.Lines, models.DiffLine{Op: string(l.Op), Text: l.Text})
+		}
+		res.Fields = append(res.Fields, fd)
+	}
+	return res, nil
+}
+
+//RestoreRevision rolls the content of an article back to an earlier revision if it is still at
+//the expected version, keeping its current status. The restore is saved as a new revision so no
+//history is lost
+//POST /articles/{articleId}/revisions/{rev}/restore
+func (s *Server) RestoreRevision(ctx context.Context, id, rev, version int) (models.Article, error) {
+	r, err := s.GetRevision(ctx, id, rev)
+	if err != nil {
+		return models.Article{}, err
+	}
+	a, err := s.modify(ctx, id, version, func(cur *models.Article) error {
+		cur.UserID, cur.Title, cur.Body, cur.Category = r.UserID, r.Title, r.Body, r.Category
+		return nil
+	})
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while restoring revision %v of article with id:%v", rev, id), err)
+		return models.Article{}, err
+	}
+	return a, nil
+}
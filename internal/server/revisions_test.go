@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+//revised creates an article and saves one update to it, returning the server and its id
+func revised(t *testing.T) (*Server, int) {
+	t.Helper()
+	ctx := context.Background()
+	s := NewServerWithClock(storage.NewMockDynamo(), &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)})
+	id, err := s.CreateArticle(ctx, models.NewArticle{UserID: 1, Title: "First", Body: "a\nb\nc", Category: "news"})
+	if err != nil {
+		t.Fatalf("CreateArticle returned %v", err)
+	}
+	if _, err := s.UpdateArticle(ctx, models.Article{ArticleID: id, UserID: 1, Version: 1, Title: "Second", Body: "a\nB\nc\nd", Category: "news"}); err != nil {
+		t.Fatalf("UpdateArticle returned %v", err)
+	}
+	return s, id
+}
+
+func TestDiffRevisions(t *testing.T) {
+	s, id := revised(t)
+
+	d, err := s.DiffRevisions(context.Background(), id, 1, 2)
+	if err != nil {
+		t.Fatalf("DiffRevisions returned %v", err)
+	}
+	want := models.ArticleDiff{ArticleID: id, From: 1, To: 2, Fields: []models.FieldDiff{
+		{Field: "title", Lines: []models.DiffLine{{Op: "delete", Text: "First"}, {Op: "insert", Text: "Second"}}},
+		{Field: "body", Lines: []models.DiffLine{
+			{Op: "equal", Text: "a"},
+			{Op: "delete", Text: "b"},
+			{Op: "insert", Text: "B"},
+			{Op: "equal", Text: "c"},
+			{Op: "insert", Text: "d"},
+		}},
+	}}
+	if !reflect.DeepEqual(d, want) {
+		t.Fatalf("DiffRevisions returned %+v, want %+v", d, want)
+	}
+
+	//Unchanged fields are left out, so comparing a revision to itself lists none
+	if d, err := s.DiffRevisions(context.Background(), id, 2, 2); err != nil || d.Fields == nil || len(d.Fields) != 0 {
+		t.Fatalf("DiffRevisions of a revision with itself returned %+v, %v, want no fields", d, err)
+	}
+	if _, err := s.DiffRevisions(context.Background(), id, 1, 3); err != storage.ErrResourceNotFound {
+		t.Fatalf("DiffRevisions to a missing revision returned %v, want ErrResourceNotFound", err)
+	}
+}
+
+func TestRestoreRevision(t *testing.T) {
+	s, id := revised(t)
+	ctx := context.Background()
+
+	if _, err := s.RestoreRevision(ctx, id, 1, 1); err != storage.ErrVersionConflict {
+		t.Fatalf("RestoreRevision at a stale version returned %v, want ErrVersionConflict", err)
+	}
+	if _, err := s.RestoreRevision(ctx, id, 5, 2); err != storage.ErrResourceNotFound {
+		t.Fatalf("RestoreRevision of a missing revision returned %v, want ErrResourceNotFound", err)
+	}
+
+	a, err := s.RestoreRevision(ctx, id, 1, 2)
+	if err != nil {
+		t.Fatalf("RestoreRevision returned %v", err)
+	}
+	if a.Version != 3 || a.Title != "First" || a.Body != "a\nb\nc" || a.Status != models.StatusDraft {
+		t.Fatalf("RestoreRevision returned version %v %q %q in %v, want version 3 with the first content", a.Version, a.Title, a.Body, a.Status)
+	}
+	revs, err := s.GetRevisions(ctx, id)
+	if err != nil {
+		t.Fatalf("GetRevisions returned %v", err)
+	}
+	if len(revs) != 3 || revs[2].Version != 3 || revs[2].Title != "First" || revs[1].Title != "Second" {
+		t.Fatalf("GetRevisions returned %+v, want the restore saved as revision 3 after both others", revs)
+	}
+
+	//Restoring with AnyVersion works whatever the current version
+	if a, err := s.RestoreRevision(ctx, id, 2, storage.AnyVersion); err != nil || a.Version != 4 || a.Title != "Second" {
+		t.Fatalf("RestoreRevision at any version returned %+v, %v, want version 4 with the second content", a, err)
+	}
+}
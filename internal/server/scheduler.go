@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+	"github.com/Perezonance/article-management-service/internal/util/validator"
+)
+
+//SchedulerUserID is recorded as the author of transitions made by the scheduler and as the last
+//editor of the articles it changes
+const SchedulerUserID = 0
+
+//errNotDue stops a scheduled update when the article no longer has anything to do
+var errNotDue = errors.New("article has no scheduled change due")
+
+//RunScheduler publishes and unpublishes due articles every interval until ctx is done. All
+//schedule state lives in storage so a restarted process picks up where the last one stopped,
+//including changes that fell due while it was down
+func (s *Server) RunScheduler(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, "scheduled publishing", s.PublishDue)
+}
+
+//runEvery calls job straight away and then every interval until ctx is done, logging failures
+//under the given name
+func runEvery(ctx context.Context, interval time.Duration, name string, job func(context.Context) (int, error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := job(ctx); err != nil && ctx.Err() == nil {
+			log.ErrorLog("Error while running "+name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+//PublishDue applies every scheduled change that is due by the server clock and returns the
+//number of articles changed. An article in review whose publishAt has passed is published and
+//a published article whose unpublishAt has passed is archived, each timestamp being cleared once
+//it fires. Timestamps due on articles in other statuses wait until the article gets there, the
+//storage leaving those articles out
+func (s *Server) PublishDue(ctx context.Context) (int, error) {
+	now := s.clock.Now()
+	due, err := s.db.GetScheduledArticles(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx = WithActor(ctx, SchedulerUserID)
+	changed := 0
+	for _, a := range due {
+		_, err := s.modify(ctx, a.ArticleID, storage.AnyVersion, func(cur *models.Article) error {
+			return applySchedule(cur, now)
+		})
+		switch {
+		case err == nil:
+			changed++
+			log.InfoLog(fmt.Sprintf("Scheduler changed article with id:%v", a.ArticleID))
+		case err == errNotDue, err == storage.ErrResourceNotFound:
+		default:
+			log.ErrorLog(fmt.Sprintf("Error while applying schedule of article with id:%v", a.ArticleID), err)
+		}
+	}
+	return changed, nil
+}
+
+//applySchedule makes the status changes of an article that are due at now
+func applySchedule(a *models.Article, now time.Time) error {
+	fired := false
+	if a.PublishAt != nil && !a.PublishAt.After(now) && a.Status == models.StatusInReview {
+		transition(a, models.StatusPublished, SchedulerUserID, now)
+		a.PublishAt = nil
+		fired = true
+	}
+	if a.UnpublishAt != nil && !a.UnpublishAt.After(now) && a.Status == models.StatusPublished {
+		transition(a, models.StatusArchived, SchedulerUserID, now)
+		a.UnpublishAt = nil
+		fired = true
+	}
+	if !fired {
+		return errNotDue
+	}
+	return nil
+}
+
+//checkSchedule reports an unpublishAt that does not come after the publishAt
+func checkSchedule(a models.Article) []validator.FieldError {
+	if a.PublishAt != nil && a.UnpublishAt != nil && !a.UnpublishAt.After(*a.PublishAt) {
+		return []validator.FieldError{{Field: "unpublishAt", Reason: "must be after publishAt"}}
+	}
+	return nil
+}
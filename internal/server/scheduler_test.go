@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+//fakeClock is a Clock that stands still until a test moves it
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+//scheduled stores an article in the given status carrying the given schedule and returns its id
+func scheduled(t *testing.T, db storage.Storage, status string, publishAt, unpublishAt *time.Time) int {
+	t.Helper()
+	ctx := context.Background()
+	id, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "t", Body: "b"})
+	if err != nil {
+		t.Fatalf("CreateArticle returned %v", err)
+	}
+	a, err := db.GetArticleByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetArticleByID returned %v", err)
+	}
+	a.Status, a.PublishAt, a.UnpublishAt = status, publishAt, unpublishAt
+	if err := db.UpdateArticle(ctx, id, a); err != nil {
+		t.Fatalf("UpdateArticle returned %v", err)
+	}
+	return id
+}
+
+//publishDue runs the scheduler once and checks how many articles it changed
+func publishDue(t *testing.T, s *Server, want int) {
+	t.Helper()
+	n, err := s.PublishDue(context.Background())
+	if err != nil {
+		t.Fatalf("PublishDue returned %v", err)
+	}
+	if n != want {
+		t.Fatalf("PublishDue changed %v articles, want %v", n, want)
+	}
+}
+
+func article(t *testing.T, db storage.Storage, id int) models.Article {
+	t.Helper()
+	a, err := db.GetArticleByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetArticleByID returned %v", err)
+	}
+	return a
+}
+
+func TestPublishDuePublishesInReviewArticle(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	db := storage.NewMockDynamo()
+	s := NewServerWithClock(db, clk)
+	publishAt := clk.t.Add(time.Hour)
+	id := scheduled(t, db, models.StatusInReview, &publishAt, nil)
+
+	publishDue(t, s, 0)
+	if a := article(t, db, id); a.Status != models.StatusInReview {
+		t.Fatalf("article is %v before its publishAt, want in_review", a.Status)
+	}
+
+	clk.t = publishAt
+	publishDue(t, s, 1)
+	a := article(t, db, id)
+	if a.Status != models.StatusPublished || a.PublishAt != nil {
+		t.Fatalf("article is %v with publishAt %v after it fired, want published without one", a.Status, a.PublishAt)
+	}
+	if lt := a.LastTransition; lt == nil || lt.From != models.StatusInReview || lt.By != SchedulerUserID || !lt.At.Equal(publishAt) {
+		t.Fatalf("scheduled publish recorded transition %+v", a.LastTransition)
+	}
+	if a.UpdatedBy != SchedulerUserID {
+		t.Fatalf("scheduled publish credited user %v, want the scheduler", a.UpdatedBy)
+	}
+
+	publishDue(t, s, 0)
+}
+
+func TestPublishDueArchivesPublishedArticle(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	db := storage.NewMockDynamo()
+	s := NewServerWithClock(db, clk)
+	unpublishAt := clk.t.Add(time.Hour)
+	id := scheduled(t, db, models.StatusPublished, nil, &unpublishAt)
+
+	clk.t = unpublishAt.Add(-time.Nanosecond)
+	publishDue(t, s, 0)
+
+	clk.t = unpublishAt.Add(time.Minute)
+	publishDue(t, s, 1)
+	a := article(t, db, id)
+	if a.Status != models.StatusArchived || a.UnpublishAt != nil {
+		t.Fatalf("article is %v with unpublishAt %v after it fired, want archived without one", a.Status, a.UnpublishAt)
+	}
+	if lt := a.LastTransition; lt == nil || lt.From != models.StatusPublished || !lt.At.Equal(clk.t) {
+		t.Fatalf("scheduled unpublish recorded transition %+v", a.LastTransition)
+	}
+}
+
+func TestPublishDueLeavesDraftAlone(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	db := storage.NewMockDynamo()
+	s := NewServerWithClock(db, clk)
+	publishAt := clk.t.Add(-time.Hour)
+	id := scheduled(t, db, models.StatusDraft, &publishAt, nil)
+
+	publishDue(t, s, 0)
+	a := article(t, db, id)
+	if a.Status != models.StatusDraft || a.PublishAt == nil || !a.PublishAt.Equal(publishAt) || a.Version != 2 {
+		t.Fatalf("draft with a past publishAt became %v at version %v with publishAt %v", a.Status, a.Version, a.PublishAt)
+	}
+	//The draft is not read back on every tick while it waits
+	if due, err := db.GetScheduledArticles(context.Background(), clk.t); err != nil || len(due) != 0 {
+		t.Fatalf("GetScheduledArticles returned %v articles, %v, want none while the article is a draft", len(due), err)
+	}
+
+	//The publishAt waits for the article to reach review
+	a.Status = models.StatusInReview
+	if err := db.UpdateArticle(context.Background(), id, a); err != nil {
+		t.Fatalf("UpdateArticle returned %v", err)
+	}
+	publishDue(t, s, 1)
+	if a := article(t, db, id); a.Status != models.StatusPublished {
+		t.Fatalf("article is %v once in review past its publishAt, want published", a.Status)
+	}
+}
+
+func TestPublishDueCatchesUpAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "articles.bolt")
+	clk := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+
+	db, err := storage.NewBoltStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltStorage returned %v", err)
+	}
+	publishAt, unpublishAt, later := clk.t.Add(time.Hour), clk.t.Add(2*time.Hour), clk.t.Add(24*time.Hour)
+	both := scheduled(t, db, models.StatusInReview, &publishAt, &unpublishAt)
+	publishOnly := scheduled(t, db, models.StatusInReview, &publishAt, &later)
+	publishDue(t, NewServerWithClock(db, clk), 0)
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close returned %v", err)
+	}
+
+	//Both changes of the first article and the publish of the second fall due while the
+	//process is down
+	clk.t = clk.t.Add(3 * time.Hour)
+	db, err = storage.NewBoltStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltStorage returned %v", err)
+	}
+	defer db.Close()
+	s := NewServerWithClock(db, clk)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.RunScheduler(ctx, time.Hour)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for article(t, db, publishOnly).Status != models.StatusPublished || article(t, db, both).Status != models.StatusArchived {
+		if time.Now().After(deadline) {
+			t.Fatalf("restarted scheduler did not catch up, articles are %v and %v", article(t, db, both).Status, article(t, db, publishOnly).Status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if a := article(t, db, both); a.PublishAt != nil || a.UnpublishAt != nil {
+		t.Fatalf("caught up article kept publishAt %v and unpublishAt %v", a.PublishAt, a.UnpublishAt)
+	}
+	if a := article(t, db, publishOnly); a.UnpublishAt == nil || !a.UnpublishAt.Equal(later) {
+		t.Fatalf("caught up article lost its future unpublishAt, got %v", a.UnpublishAt)
+	}
+}
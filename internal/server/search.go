@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+	"github.com/Perezonance/article-management-service/internal/util/search"
+)
+
+//titleBoost weighs a match in an article's title against one in its body
+const titleBoost = 2
+
+//articleIndex keeps the search index in step with the articles written through the server. It
+//only sees this process's writes, other instances catch up when their index is rebuilt
+type articleIndex struct {
+	mu       sync.RWMutex
+	ix       *search.Index
+	articles map[int]models.Article
+	//versions holds the last version indexed of every article, trashed ones included, so that a
+	//slow writer cannot put back an older version
+	versions map[int]int
+}
+
+func newArticleIndex() *articleIndex {
+	return &articleIndex{ix: search.New(), articles: map[int]models.Article{}, versions: map[int]int{}}
+}
+
+//put indexes an article unless a newer version of it has been indexed already, trashed
+//articles are dropped from the index instead
+func (x *articleIndex) put(a models.Article) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if v, ok := x.versions[a.ArticleID]; ok && v > a.Version {
+		return
+	}
+	x.versions[a.ArticleID] = a.Version
+	if a.DeletedAt != nil {
+		x.ix.Remove(a.ArticleID)
+		delete(x.articles, a.ArticleID)
+		return
+	}
+	x.ix.Put(a.ArticleID,
+		search.Field{Name: "title", Text: a.Title, Boost: titleBoost},
+		search.Field{Name: "body", Text: a.Body},
+	)
+	x.articles[a.ArticleID] = a
+}
+
+//remove drops an article that no longer exists from the index
+func (x *articleIndex) remove(id int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.ix.Remove(id)
+	delete(x.articles, id)
+	delete(x.versions, id)
+}
+
+//search returns the articles matching q, restricted to a single status when status is non-empty
+func (x *articleIndex) search(q, status string) ([]models.SearchResult, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	hits, err := x.ix.Search(q, func(id int) bool {
+		return status == "" || x.articles[id].Status == status
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]models.SearchResult, len(hits))
+	for i, h := range hits {
+		res[i] = models.SearchResult{Article: x.articles[h.ID], Score: h.Score, Highlights: h.Highlights}
+	}
+	return res, nil
+}
+
+//SearchArticles returns one page of the articles whose title or body match q, best match first,
+//restricted to a single status when status is non-empty and resuming after the given opaque
+//cursor. Words are separated by spaces, "quoted words" match as a phrase and a word ending in *
+//matches every word it begins
+//GET /articles/search?q=text&limit=n&cursor=c
+func (s *Server) SearchArticles(ctx context.Context, q, status, cur string, limit int) (models.SearchPage, error) {
+	c, err := decodeCursor(cur)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return models.SearchPage{}, err
+	}
+
+	results, err := s.index.search(q, status)
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while searching articles for:%q", q), err)
+		return models.SearchPage{}, err
+	}
+
+	res := models.SearchPage{Results: []models.SearchResult{}, Total: len(results)}
+	if c.Offset >= len(results) {
+		return res, nil
+	}
+	end := c.Offset + pageLimit(limit)
+	if end < len(results) {
+		res.NextCursor = encodeCursor(cursor{Offset: end})
+	} else {
+		end = len(results)
+	}
+	res.Results = results[c.Offset:end]
+	return res, nil
+}
+
+//RebuildSearchIndex indexes every article outside the trash from storage and returns how many
+//were indexed. It is called on startup since the index is only held in memory
+func (s *Server) RebuildSearchIndex(ctx context.Context) (int, error) {
+	n := 0
+	q := storage.PageQuery{Limit: MaxPageLimit}
+	for {
+		page, err := s.db.ListArticles(ctx, q)
+		if err != nil {
+			log.ErrorLog("Error while rebuilding the search index", err)
+			return n, err
+		}
+		for _, a := range page.Articles {
+			s.index.put(a)
+		}
+		n += len(page.Articles)
+		if !page.More {
+			return n, nil
+		}
+		q = q.Next(page.Articles[len(page.Articles)-1])
+	}
+}
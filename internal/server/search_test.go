@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+//searchTotal runs a search and returns how many articles matched
+func searchTotal(t *testing.T, s *Server, q string) int {
+	t.Helper()
+	page, err := s.SearchArticles(context.Background(), q, "", "", MaxPageLimit)
+	if err != nil {
+		t.Fatalf("SearchArticles(%q) returned %v", q, err)
+	}
+	return page.Total
+}
+
+func TestRebuildSearchIndexAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "articles.bolt")
+	clk := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	ctx := context.Background()
+
+	db, err := storage.NewBoltStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltStorage returned %v", err)
+	}
+	s := NewServerWithClock(db, clk)
+	//More articles than fit on one page of storage so the rebuild has to follow the pages
+	n := MaxPageLimit + 5
+	for i := 1; i <= n; i++ {
+		if _, err := s.CreateArticle(ctx, models.NewArticle{UserID: 1, Title: fmt.Sprintf("Gopher number%v", i), Body: "shared body"}); err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+	}
+	if err := s.DeleteArticle(ctx, 1, storage.AnyVersion); err != nil {
+		t.Fatalf("DeleteArticle returned %v", err)
+	}
+	if got := searchTotal(t, s, "gopher"); got != n-1 {
+		t.Fatalf("search before the restart matched %v articles, want %v", got, n-1)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close returned %v", err)
+	}
+
+	db, err = storage.NewBoltStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltStorage returned %v", err)
+	}
+	defer db.Close()
+	s = NewServerWithClock(db, clk)
+	if got := searchTotal(t, s, "gopher"); got != 0 {
+		t.Fatalf("search before the rebuild matched %v articles, want none", got)
+	}
+
+	indexed, err := s.RebuildSearchIndex(ctx)
+	if err != nil {
+		t.Fatalf("RebuildSearchIndex returned %v", err)
+	}
+	if indexed != n-1 {
+		t.Fatalf("RebuildSearchIndex indexed %v articles, want %v", indexed, n-1)
+	}
+	for _, tc := range []struct {
+		q    string
+		want int
+	}{
+		{"gopher", n - 1},
+		{"shared body", n - 1},
+		{"number1", 0},
+		{"number2", 1},
+		{fmt.Sprintf("number%v", n), 1},
+	} {
+		if got := searchTotal(t, s, tc.q); got != tc.want {
+			t.Fatalf("search for %q after the rebuild matched %v articles, want %v", tc.q, got, tc.want)
+		}
+	}
+}
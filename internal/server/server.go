@@ -1,63 +1,160 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"sync"
+	"strings"
+	"time"
 
 	"github.com/Perezonance/article-management-service/internal/models"
 	"github.com/Perezonance/article-management-service/internal/storage"
 	log "github.com/Perezonance/article-management-service/internal/util/logger"
+	"github.com/Perezonance/article-management-service/internal/util/patch"
+	"github.com/Perezonance/article-management-service/internal/util/validator"
 )
 
 //Server processes the data models and handles business logic for the server
 type Server struct {
-	db storage.Storage
+	db        storage.Storage
+	comments  storage.CommentStorage
+	reactions storage.ReactionStorage
+	views     storage.ViewStorage
+	viewLog   *viewRecorder
+	clock     Clock
+	index     *articleIndex
 }
 
-//NewServer creates a server and returns it given a storage
+//Clock tells the server the current time so that tests can control it
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+//NewServer creates a server and returns it given a storage. Comments, reactions and view counts
+//are kept in the storage when it stores them and in memory otherwise
 func NewServer(d storage.Storage) *Server {
-	return &Server{db: d}
+	return NewServerWithClock(d, systemClock{})
+}
+
+//NewServerWithClock creates a server that reads the current time from the given clock
+func NewServerWithClock(d storage.Storage, c Clock) *Server {
+	var (
+		cs storage.CommentStorage  = storage.NewMemoryCommentStorage()
+		rs storage.ReactionStorage = storage.NewMemoryReactionStorage()
+		vs storage.ViewStorage     = storage.NewMemoryViewStorage()
+	)
+	if ds, ok := d.(storage.CommentStorage); ok {
+		cs = ds
+	}
+	if ds, ok := d.(storage.ReactionStorage); ok {
+		rs = ds
+	}
+	if ds, ok := d.(storage.ViewStorage); ok {
+		vs = ds
+	}
+	return NewServerWithStores(d, cs, rs, vs, c)
+}
+
+//NewServerWithStores creates a server keeping articles in d, their comments in cs, the reactions
+//to them in rs and their view counts in vs
+func NewServerWithStores(d storage.Storage, cs storage.CommentStorage, rs storage.ReactionStorage, vs storage.ViewStorage, c Clock) *Server {
+	return &Server{db: d, comments: cs, reactions: rs, views: vs, viewLog: newViewRecorder(), clock: c, index: newArticleIndex()}
 }
 
-//GetArticles returns all the articles in the db
+//GetArticles returns all the articles in the db outside the trash
 //GET /articles
-func (s *Server) GetArticles() ([]models.Article, error) {
-	articles, err := s.db.GetAllArticles()
+func (s *Server) GetArticles(ctx context.Context) ([]models.Article, error) {
+	articles, err := s.db.GetAllArticles(ctx)
 	if err != nil {
 		log.ErrorLog("Error fetching all articles from table", err)
 		return ([]models.Article{}), err
 	}
-	return articles, nil
+	return untrashed(articles), nil
+}
+
+//ListArticles returns one page of the articles outside the trash selected by the filters and
+//sort order of q, resuming after the given opaque cursor. The cursor must come from a listing
+//in the same sort order
+//GET /articles?limit=n&cursor=c
+func (s *Server) ListArticles(ctx context.Context, q storage.PageQuery, cur string) (models.ArticlePage, error) {
+	q.Trashed = false
+	return s.listPage(ctx, q, cur)
+}
+
+//listPage fetches the page of articles selected by q that follows the opaque cursor
+func (s *Server) listPage(ctx context.Context, q storage.PageQuery, cur string) (models.ArticlePage, error) {
+	c, err := decodeCursor(cur)
+	if err != nil {
+		return models.ArticlePage{}, err
+	}
+	if cur != "" && (c.Sort != q.Sort || c.Desc != q.Desc) {
+		return models.ArticlePage{}, ErrInvalidCursor
+	}
+
+	q.Limit = pageLimit(q.Limit)
+	q.AfterID, q.AfterTitle = c.AfterID, c.AfterTitle
+	if c.AfterTime != nil {
+		q.AfterTime = *c.AfterTime
+	}
+	page, err := s.db.ListArticles(ctx, q)
+	if err != nil {
+		log.ErrorLog("Error fetching page of articles from table", err)
+		return models.ArticlePage{}, err
+	}
+
+	res := models.ArticlePage{Articles: page.Articles}
+	if res.Articles == nil {
+		res.Articles = []models.Article{}
+	}
+	if page.More {
+		next := q.Next(page.Articles[len(page.Articles)-1])
+		c := cursor{AfterID: next.AfterID, AfterTitle: next.AfterTitle, Sort: q.Sort, Desc: q.Desc}
+		if !next.AfterTime.IsZero() {
+			c.AfterTime = &next.AfterTime
+		}
+		res.NextCursor = encodeCursor(c)
+	}
+	return res, nil
 }
 
 //GetArticleByID returns the article represented by the articleId given
 //GET /articles/{articleId}
-func (s *Server) GetArticleByID(id int) (models.Article, error) {
+func (s *Server) GetArticleByID(ctx context.Context, id int) (models.Article, error) {
 	var (
 		article models.Article
 	)
-	article, err := s.db.GetArticleByID(id)
+	article, err := s.db.GetArticleByID(ctx, id)
+	if err == nil && article.DeletedAt != nil {
+		err = storage.ErrResourceNotFound
+	}
 	if err != nil {
 		log.ErrorLog(fmt.Sprintf("Error while requesting article from db with id:%v", id), err)
-		return article, err
+		return models.Article{}, err
 	}
 	return article, nil
 }
 
 //GetArticlesByIDs returns all articles requested given a slice of ids
 //GET /articles?ids=id1,id2,id3,idn...
-func (s *Server) GetArticlesByIDs(ids []int) ([]models.Article, error) {
+func (s *Server) GetArticlesByIDs(ctx context.Context, ids []int) ([]models.Article, error) {
+	//Cancelling the derived context stops the remaining lookups once one has failed
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	arts := make([]models.Article, len(ids))
-	quit := make(chan bool)
 	errc := make(chan error)
 	done := make(chan error)
-	mapMu := &sync.Mutex{}
 
 	for i, v := range ids {
 		go func(i int, v int) {
-			mapMu.Lock()
-			art, err := s.GetArticleByID(v)
-			mapMu.Unlock()
+			art, err := s.GetArticleByID(ctx, v)
 			ch := done
 			arts[i] = art
 
@@ -69,7 +166,7 @@ func (s *Server) GetArticlesByIDs(ids []int) ([]models.Article, error) {
 			//Pass error channel
 			case ch <- err:
 				return
-			case <-quit:
+			case <-ctx.Done():
 				return
 			}
 		}(i, v)
@@ -80,9 +177,11 @@ func (s *Server) GetArticlesByIDs(ids []int) ([]models.Article, error) {
 	for {
 		select {
 		case err := <-errc:
-			//If err channel recieved any errors then close the quit channel
-			close(quit)
+			//If err channel recieved any errors then cancel the outstanding lookups
+			cancel()
 			return blank, err
+		case <-ctx.Done():
+			return blank, ctx.Err()
 		case <-done:
 			count++
 			if count == len(arts) {
@@ -95,31 +194,54 @@ func (s *Server) GetArticlesByIDs(ids []int) ([]models.Article, error) {
 	}
 }
 
-//CreateArticle creates a new article given the article data model and returns the newly issued ID
+//CreateArticle creates a new article given the article data model and returns the newly issued ID.
+//It is credited to the user making the request, left uncredited when the context names none,
+//and given a unique slug derived from its title
 //POST /articles
-func (s *Server) CreateArticle(a models.NewArticle) (int, error) {
-	id, err := s.db.CreateArticle(a)
+func (s *Server) CreateArticle(ctx context.Context, a models.NewArticle) (int, error) {
+	now, by := s.clock.Now().UTC(), actor(ctx)
+	art := models.Article{
+		UserID:    a.UserID,
+		Title:     a.Title,
+		Body:      a.Body,
+		Category:  strings.TrimSpace(a.Category),
+		CreatedAt: now,
+		UpdatedAt: now,
+		CreatedBy: by,
+		UpdatedBy: by,
+	}
+	var id int
+	err := saveSlugged(&art, func(art models.Article) error {
+		var err error
+		id, err = s.db.CreateArticle(ctx, art)
+		return err
+	})
 	if err != nil {
 		log.ErrorLog("Error while creating new log", err)
 		return 0, err
 	}
+	created, err := s.db.GetArticleByID(ctx, id)
+	if err != nil {
+		//The article is saved so the request still succeeds, the next rebuild will index it
+		log.ErrorLog(fmt.Sprintf("Error while indexing new article with id:%v", id), err)
+		return id, nil
+	}
+	s.index.put(created)
 	return id, nil
 }
 
 //CreateArticles creates a new article given the article data model and returns the newly issued ID
 //POST /articles
-func (s *Server) CreateArticles(arts []models.NewArticle) ([]int, error) {
+func (s *Server) CreateArticles(ctx context.Context, arts []models.NewArticle) ([]int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	ids := make([]int, len(arts))
-	quit := make(chan bool)
 	errc := make(chan error)
 	done := make(chan error)
-	mu := &sync.Mutex{}
 
 	for i, v := range arts {
 		go func(i int, v models.NewArticle) {
-			mu.Lock()
-			id, err := s.CreateArticle(v)
-			mu.Unlock()
+			id, err := s.CreateArticle(ctx, v)
 			ch := done
 			ids[i] = id
 
@@ -130,7 +252,7 @@ func (s *Server) CreateArticles(arts []models.NewArticle) ([]int, error) {
 			select {
 			case ch <- err:
 				return
-			case <-quit:
+			case <-ctx.Done():
 				return
 			}
 		}(i, v)
@@ -141,8 +263,10 @@ func (s *Server) CreateArticles(arts []models.NewArticle) ([]int, error) {
 	for {
 		select {
 		case err := <-errc:
-			close(quit)
+			cancel()
 			return blank, err
+		case <-ctx.Done():
+			return blank, ctx.Err()
 		case <-done:
 			count++
 			if count == len(arts) {
@@ -155,39 +279,220 @@ func (s *Server) CreateArticles(arts []models.NewArticle) ([]int, error) {
 	}
 }
 
-//UpdateArticle updates an existing article with the given data model and id
+//UpdateArticle replaces the content of an existing article with the given data model and id.
+//a.Version is the version the caller expects to replace, storage.AnyVersion skips the check.
+//The author cannot change and the status is left alone, it only changes through
+//TransitionArticle, and the server managed
+//timestamps, authorship and slug given in a are ignored as are its tags, which change through
+//AddTags and RemoveTags
 //PUT /articles/{articleId}
-func (s *Server) UpdateArticle(a models.Article) error {
-	err := s.db.UpdateArticle(a.ArticleID, a)
+func (s *Server) UpdateArticle(ctx context.Context, a models.Article) (models.Article, error) {
+	res, err := s.modify(ctx, a.ArticleID, a.Version, func(cur *models.Article) error {
+		var fields []validator.FieldError
+		if a.UserID != cur.UserID {
+			fields = append(fields, validator.FieldError{Field: "userID", Reason: "cannot be changed"})
+		}
+		if a.Status != "" && a.Status != cur.Status {
+			fields = append(fields, validator.FieldError{Field: "status", Reason: "can only be changed through a transition"})
+		}
+		fields = append(fields, checkSchedule(a)...)
+		if len(fields) > 0 {
+			return &ValidationError{Fields: fields}
+		}
+		cur.Title, cur.Body, cur.Category = a.Title, a.Body, strings.TrimSpace(a.Category)
+		cur.PublishAt, cur.UnpublishAt = a.PublishAt, a.UnpublishAt
+		return nil
+	})
 	if err != nil {
-		//TODO: Check for 404
-		log.ErrorLog(fmt.Sprintf("Error while updating log with id:%v", a.UserID), err)
-		return err
+		log.ErrorLog(fmt.Sprintf("Error while updating log with id:%v", a.ArticleID), err)
+		return models.Article{}, err
 	}
-	return nil
+	return res, nil
+}
+
+//PatchArticle applies a JSON Merge Patch or JSON Patch document, identified by its media type,
+//to the stored article with the given id and saves the result if the article is still at the
+//expected version. The articleID, author, version, status, timestamps, authorship, slug, tags,
+//reactions, last transition and deletedAt cannot be patched
+//PATCH /articles/{articleId}
+func (s *Server) PatchArticle(ctx context.Context, id, version int, mediaType string, p []byte) (models.Article, error) {
+	if mediaType != patch.MergePatchType && mediaType != patch.JSONPatchType {
+		return models.Article{}, ErrUnsupportedPatchType
+	}
+
+	res, err := s.modify(ctx, id, version, func(cur *models.Article) error {
+		doc, err := json.Marshal(cur)
+		if err != nil {
+			return err
+		}
+		var patched []byte
+		if mediaType == patch.MergePatchType {
+			patched, err = patch.Merge(doc, p)
+		} else {
+			patched, err = patch.Apply(doc, p)
+		}
+		if err != nil {
+			return err
+		}
+
+		a, err := decodePatchedArticle(patched)
+		if err != nil {
+			return err
+		}
+		var fields []validator.FieldError
+		if a.ArticleID != cur.ArticleID {
+			fields = append(fields, validator.FieldError{Field: "articleID", Reason: "cannot be changed"})
+		}
+		if a.UserID != cur.UserID {
+			fields = append(fields, validator.FieldError{Field: "userID", Reason: "cannot be changed"})
+		}
+		if a.Version != cur.Version {
+			fields = append(fields, validator.FieldError{Field: "version", Reason: "cannot be changed"})
+		}
+		if a.Status != cur.Status {
+			fields = append(fields, validator.FieldError{Field: "status", Reason: "can only be changed through a transition"})
+		}
+		if !a.CreatedAt.Equal(cur.CreatedAt) {
+			fields = append(fields, validator.FieldError{Field: "createdAt", Reason: "cannot be changed"})
+		}
+		if !a.UpdatedAt.Equal(cur.UpdatedAt) {
+			fields = append(fields, validator.FieldError{Field: "updatedAt", Reason: "cannot be changed"})
+		}
+		if a.CreatedBy != cur.CreatedBy {
+			fields = append(fields, validator.FieldError{Field: "createdBy", Reason: "cannot be changed"})
+		}
+		if a.UpdatedBy != cur.UpdatedBy {
+			fields = append(fields, validator.FieldError{Field: "updatedBy", Reason: "cannot be changed"})
+		}
+		if a.Slug != cur.Slug {
+			fields = append(fields, validator.FieldError{Field: "slug", Reason: "is derived from the title"})
+		}
+		if !sameTags(a.Tags, cur.Tags) {
+			fields = append(fields, validator.FieldError{Field: "tags", Reason: "can only be changed through the article's tags"})
+		}
+		if a.Reactions != nil {
+			fields = append(fields, validator.FieldError{Field: "reactions", Reason: "can only be changed through the article's reactions"})
+		}
+		if !sameTransition(a.LastTransition, cur.LastTransition) {
+			fields = append(fields, validator.FieldError{Field: "lastTransition", Reason: "cannot be changed"})
+		}
+		if a.DeletedAt != nil {
+			fields = append(fields, validator.FieldError{Field: "deletedAt", Reason: "can only be set by deleting the article"})
+		}
+		fields = append(fields, validator.Struct(a)...)
+		fields = append(fields, checkSchedule(a)...)
+		if len(fields) > 0 {
+			return &ValidationError{Fields: fields}
+		}
+		a.Category = strings.TrimSpace(a.Category)
+		*cur = a
+		return nil
+	})
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while patching article with id:%v", id), err)
+		return models.Article{}, err
+	}
+	return res, nil
+}
+
+//modify applies fn to the current article with the given id and saves the result if the
+//article is still at the expected version. With storage.AnyVersion a write landing between the
+//read and the save makes it start over from the newer article instead of overwriting it.
+//Trashed articles are reported as not found
+func (s *Server) modify(ctx context.Context, id, version int, fn func(*models.Article) error) (models.Article, error) {
+	return s.modifyIn(ctx, id, version, false, fn)
+}
+
+//modifyIn is modify restricted to articles inside the trash when trashed is set and to those
+//outside it otherwise. The creation time and creator are kept and the article is marked as
+//updated now by the user making the request, or by no one when the context names none. The
+//slug is kept unless the title changes into one with another slug, or the article predates
+//slugs, in which case it moves to a new slug and the old one keeps leading to the article
+func (s *Server) modifyIn(ctx context.Context, id, version int, trashed bool, fn func(*models.Article) error) (models.Article, error) {
+	for {
+		cur, err := s.db.GetArticleByID(ctx, id)
+		if err != nil {
+			return models.Article{}, err
+		}
+		if (cur.DeletedAt != nil) != trashed {
+			return models.Article{}, storage.ErrResourceNotFound
+		}
+		if version != storage.AnyVersion && version != cur.Version {
+			return models.Article{}, storage.ErrVersionConflict
+		}
+
+		a := cur
+		if err := fn(&a); err != nil {
+			return models.Article{}, err
+		}
+		a.ArticleID, a.Version = id, cur.Version
+		a.CreatedAt, a.UpdatedAt = cur.CreatedAt, s.clock.Now().UTC()
+		a.CreatedBy, a.UpdatedBy = cur.CreatedBy, actor(ctx)
+
+		save := func(a models.Article) error { return s.db.UpdateArticle(ctx, id, a) }
+		if a.Slug = cur.Slug; a.Slug == "" || Slugify(a.Title) != Slugify(cur.Title) {
+			err = saveSlugged(&a, save)
+		} else {
+			err = save(a)
+		}
+		if err == storage.ErrVersionConflict && version == storage.AnyVersion {
+			continue
+		}
+		if err != nil {
+			return models.Article{}, err
+		}
+		a.Version++
+		s.index.put(a)
+		return a, nil
+	}
+}
+
+//decodePatchedArticle decodes a patched article document, reporting fields the patch added or
+//retyped as validation failures
+func decodePatchedArticle(doc []byte) (models.Article, error) {
+	var a models.Article
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&a)
+	if err == nil {
+		return a, nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return models.Article{}, &ValidationError{Fields: []validator.FieldError{{Field: typeErr.Field, Reason: "has the wrong type"}}}
+	}
+	if field, ok := validator.UnknownField(err); ok {
+		return models.Article{}, &ValidationError{Fields: []validator.FieldError{{Field: field, Reason: "is not allowed"}}}
+	}
+	return models.Article{}, err
 }
 
-//DeleteArticle deletes an article given the id
+//DeleteArticle moves an article given the id to the trash if it is still at the expected
+//version, storage.AnyVersion skips the check. It stays there until it is restored or purged
 //DELETE /articles/{articleId}
-func (s *Server) DeleteArticle(id int) error {
-	err := s.db.DeleteArticle(id)
+func (s *Server) DeleteArticle(ctx context.Context, id, version int) error {
+	_, err := s.modify(ctx, id, version, func(cur *models.Article) error {
+		now := s.clock.Now().UTC()
+		cur.DeletedAt = &now
+		return nil
+	})
 	if err != nil {
-		//TODO: Check for 404
 		log.ErrorLog(fmt.Sprintf("Error while deleting log with id:%v", id), err)
 		return err
 	}
 	return nil
 }
 
-//GetArticlesByUser returns a list of all articles written by the given user
-//GET /articles/user/{userId}
-func (s *Server) GetArticlesByUser(userID int) ([]models.Article, error) {
+//GetArticlesByUser returns a list of all articles written by the given user, users are not
+//stored by this service so an unknown user is answered with an empty list rather than a 404
+//GET /users/{userId}/articles
+func (s *Server) GetArticlesByUser(ctx context.Context, userID int) ([]models.Article, error) {
 	var arts []models.Article
-	arts, err := s.db.GetArticleByUserID(userID)
+	arts, err := s.db.GetArticleByUserID(ctx, userID)
 	if err != nil {
-		//TODO: Check for 404
 		log.ErrorLog(fmt.Sprintf("Error while fetching articles with user id:%v", userID), err)
 		return arts, err
 	}
-	return arts, nil
+	return untrashed(arts), nil
 }
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+const (
+	//maxSlugLen caps the number of characters of the slug derived from a title, before any suffix
+	//making it unique
+	maxSlugLen = 80
+	//numberedSlugs is the number of slugs tried, the bare one then -2, -3 and so on, before
+	//falling back to random suffixes
+	numberedSlugs = 10
+	//randomSlugs is the number of randomly suffixed slugs tried before giving up
+	randomSlugs = 5
+	//defaultSlug stands in for titles without a single letter or digit spelled in ASCII
+	defaultSlug = "article"
+)
+
+//slugFolds spells the accented latin letters common in titles without their accents
+var slugFolds = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+)
+
+//GetArticleBySlug returns the article outside the trash holding the given slug, now or before
+//its title changed. Callers compare the slug asked for with the article's to tell the two apart
+//GET /articles/by-slug/{slug}
+func (s *Server) GetArticleBySlug(ctx context.Context, slug string) (models.Article, error) {
+	article, err := s.db.GetArticleBySlug(ctx, slug)
+	if err == nil && article.DeletedAt != nil {
+		err = storage.ErrResourceNotFound
+	}
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while requesting article from db with slug:%q", slug), err)
+		return models.Article{}, err
+	}
+	return article, nil
+}
+
+//Slugify derives the URL-safe slug of a title, its lower case ASCII letters and digits with
+//every run of other characters turned into a single hyphen. Apostrophes are dropped so that
+//"don't" stays one word. Titles without a single letter or digit it can spell get defaultSlug
+func Slugify(title string) string {
+	if slug := slugify(title); slug != "" {
+		return slug
+	}
+	return defaultSlug
+}
+
+//slugify is Slugify returning "" for titles without a letter or digit it can spell. The slug is
+//cut at maxSlugLen characters and never ends in a hyphen
+func slugify(title string) string {
+	var (
+		b    strings.Builder
+		dash bool
+	)
+	for _, r := range slugFolds.Replace(strings.ToLower(title)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				//A hyphen is only worth writing with at least one character after it
+				if b.Len()+2 > maxSlugLen {
+					return b.String()
+				}
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		case r == '\'' || r == '’':
+		default:
+			dash = true
+		}
+		if b.Len() >= maxSlugLen {
+			break
+		}
+	}
+	return b.String()
+}
+
+//saveSlugged gives a the slug derived from its title and saves it, moving on to the next
+//candidate slug each time save reports the slug is held by another article. Storage enforces
+//the uniqueness, so concurrent writers racing for a slug each end up with their own. Titles
+//without a slug of their own, such as those in scripts without latin letters, would all crowd
+//onto the numbered defaultSlug so they go straight to the random suffixes
+func saveSlugged(a *models.Article, save func(models.Article) error) error {
+	base, first := slugify(a.Title), 0
+	if base == "" {
+		base, first = defaultSlug, numberedSlugs
+	}
+	for i := first; i < numberedSlugs+randomSlugs; i++ {
+		a.Slug = slugCandidate(base, i)
+		if err := save(*a); err != storage.ErrSlugTaken {
+			return err
+		}
+	}
+	return storage.ErrSlugTaken
+}
+
+//slugCandidate returns the i-th slug tried for base
+func slugCandidate(base string, i int) string {
+	switch {
+	case i == 0:
+		return base
+	case i < numberedSlugs:
+		return fmt.Sprintf("%v-%v", base, i+1)
+	}
+	return fmt.Sprintf("%v-%08x", base, rand.Uint32())
+}
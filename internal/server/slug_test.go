@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+func TestSlugify(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"Hello World", "hello-world"},
+		{"  Go 1.22: what's new?! ", "go-1-22-whats-new"},
+		{"Don’t Panic", "dont-panic"},
+		{"Crème brûlée à la française", "creme-brulee-a-la-francaise"},
+		{"Straße & Œuvre", "strasse-oeuvre"},
+		{"---", defaultSlug},
+		{"", defaultSlug},
+		{"Привет мир", defaultSlug},
+		{"日本語のタイトル", defaultSlug},
+		{"Go в продакшене", "go"},
+		//The slug is cut at maxSlugLen and never ends in a hyphen
+		{strings.Repeat("a", 100), strings.Repeat("a", maxSlugLen)},
+		{strings.Repeat("a", maxSlugLen-1) + " b", strings.Repeat("a", maxSlugLen-1)},
+		{strings.Repeat("a", maxSlugLen-2) + " bc", strings.Repeat("a", maxSlugLen-2) + "-b"},
+		{strings.Repeat("a", maxSlugLen) + " b", strings.Repeat("a", maxSlugLen)},
+		{strings.Repeat("é", maxSlugLen+5), strings.Repeat("e", maxSlugLen)},
+	} {
+		got := Slugify(tc.in)
+		if got != tc.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+		if len(got) > maxSlugLen || strings.HasSuffix(got, "-") {
+			t.Errorf("Slugify(%q) = %q, longer than %v or ending in a hyphen", tc.in, got, maxSlugLen)
+		}
+	}
+}
+
+func TestSaveSluggedTriesCandidates(t *testing.T) {
+	random := regexp.MustCompile(`^([a-z]+)-[0-9a-f]{8}$`)
+	for _, tc := range []struct {
+		title, base string
+		numbered    bool
+	}{
+		{"Hello", "hello", true},
+		//Titles without a slug of their own skip straight to random suffixes
+		{"Привет", defaultSlug, false},
+	} {
+		var tried []string
+		a := models.Article{Title: tc.title}
+		err := saveSlugged(&a, func(a models.Article) error {
+			tried = append(tried, a.Slug)
+			return storage.ErrSlugTaken
+		})
+		if err != storage.ErrSlugTaken {
+			t.Fatalf("saveSlugged(%q) with every slug taken returned %v, want ErrSlugTaken", tc.title, err)
+		}
+		want := randomSlugs
+		if tc.numbered {
+			want += numberedSlugs
+			if tried[0] != tc.base || tried[1] != tc.base+"-2" || tried[numberedSlugs-1] != tc.base+"-10" {
+				t.Fatalf("saveSlugged(%q) tried %v, want %v then numbered slugs", tc.title, tried, tc.base)
+			}
+		}
+		if len(tried) != want {
+			t.Fatalf("saveSlugged(%q) tried %v slugs, want %v", tc.title, len(tried), want)
+		}
+		for _, slug := range tried[want-randomSlugs:] {
+			if m := random.FindStringSubmatch(slug); m == nil || m[1] != tc.base {
+				t.Fatalf("saveSlugged(%q) tried %q, want %v with a random suffix", tc.title, slug, tc.base)
+			}
+		}
+	}
+}
+
+func TestCreateArticleSlugs(t *testing.T) {
+	s := NewServer(storage.NewMockDynamo())
+	ctx := context.Background()
+	for _, tc := range []struct {
+		title string
+		want  *regexp.Regexp
+	}{
+		{"Hello World", regexp.MustCompile(`^hello-world$`)},
+		{"Hello, world!", regexp.MustCompile(`^hello-world-2$`)},
+		{"Привет мир", regexp.MustCompile(`^article-[0-9a-f]{8}$`)},
+		{"Привет мир", regexp.MustCompile(`^article-[0-9a-f]{8}$`)},
+	} {
+		id, err := s.CreateArticle(ctx, models.NewArticle{UserID: 1, Title: tc.title, Body: "b"})
+		if err != nil {
+			t.Fatalf("CreateArticle(%q) returned %v", tc.title, err)
+		}
+		a, err := s.GetArticleByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetArticleByID returned %v", err)
+		}
+		if !tc.want.MatchString(a.Slug) {
+			t.Fatalf("article titled %q got slug %q, want %v", tc.title, a.Slug, tc.want)
+		}
+		if got, err := s.GetArticleBySlug(ctx, a.Slug); err != nil || got.ArticleID != id {
+			t.Fatalf("GetArticleBySlug(%q) returned article %v, %v, want %v", a.Slug, got.ArticleID, err, id)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+	"github.com/Perezonance/article-management-service/internal/util/validator"
+)
+
+const (
+	//maxTagLen caps the number of characters of a tag
+	maxTagLen = 50
+	//maxTags caps the number of tags an article carries
+	maxTags = 20
+)
+
+//AddTags tags an article outside the trash with the given tags and returns the article. Tags are
+//free-form but matched case-insensitively, so they are stored lower cased with their runs of
+//spaces collapsed. Tags the article already carries are left alone
+//POST /articles/{articleId}/tags
+func (s *Server) AddTags(ctx context.Context, id int, tags []string) (models.Article, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return models.Article{}, err
+	}
+	cur, err := s.GetArticleByID(ctx, id)
+	if err != nil {
+		return models.Article{}, err
+	}
+	if n := len(mergeTags(cur.Tags, tags)); n > maxTags {
+		return models.Article{}, &ValidationError{Fields: []validator.FieldError{{Field: "tags", Reason: fmt.Sprintf("would give the article %v tags, at most %v are allowed", n, maxTags)}}}
+	}
+
+	if err := s.db.AddTags(ctx, id, tags); err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while tagging article with id:%v", id), err)
+		return models.Article{}, err
+	}
+	return s.retagged(ctx, id)
+}
+
+//RemoveTags removes the given tags from an article outside the trash and returns the article,
+//tags it does not carry are ignored
+//DELETE /articles/{articleId}/tags
+func (s *Server) RemoveTags(ctx context.Context, id int, tags []string) (models.Article, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return models.Article{}, err
+	}
+	if _, err := s.GetArticleByID(ctx, id); err != nil {
+		return models.Article{}, err
+	}
+
+	if err := s.db.RemoveTags(ctx, id, tags); err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while untagging article with id:%v", id), err)
+		return models.Article{}, err
+	}
+	return s.retagged(ctx, id)
+}
+
+//retagged reads back an article whose tags changed and refreshes its copy in the search index,
+//tag changes leave the version alone so the index takes the newer copy
+func (s *Server) retagged(ctx context.Context, id int) (models.Article, error) {
+	a, err := s.GetArticleByID(ctx, id)
+	if err != nil {
+		return models.Article{}, err
+	}
+	s.index.put(a)
+	return a, nil
+}
+
+//GetTags returns every tag carried by an article outside the trash with the number of such
+//articles carrying it, restricted to a single status when status is non-empty
+//GET /tags
+func (s *Server) GetTags(ctx context.Context, status string) ([]models.TagCount, error) {
+	tags, err := s.db.GetTagCounts(ctx, status)
+	if err != nil {
+		log.ErrorLog("Error while counting tags", err)
+		return nil, err
+	}
+	return tags, nil
+}
+
+//NormalizeTag lower cases a tag and collapses its runs of spaces so that it matches the way tags
+//are stored
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+//normalizeTags normalizes and deduplicates tags, reporting a validation failure for an empty
+//list and for tags that are blank, too long or contain a slash, a comma or a control character
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, &ValidationError{Fields: []validator.FieldError{{Field: "tags", Reason: "is required"}}}
+	}
+	var (
+		res    []string
+		fields []validator.FieldError
+	)
+	for i, t := range tags {
+		t = NormalizeTag(t)
+		field := fmt.Sprintf("tags[%v]", i)
+		switch {
+		case t == "":
+			fields = append(fields, validator.FieldError{Field: field, Reason: "is required"})
+		case utf8.RuneCountInString(t) > maxTagLen:
+			fields = append(fields, validator.FieldError{Field: field, Reason: fmt.Sprintf("must be at most %v characters", maxTagLen)})
+		case strings.ContainsAny(t, "/,") || strings.IndexFunc(t, unicode.IsControl) >= 0:
+			fields = append(fields, validator.FieldError{Field: field, Reason: "must not contain slashes, commas or control characters"})
+		default:
+			res = append(res, t)
+		}
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+	return mergeTags(nil, res), nil
+}
+
+//mergeTags returns the sorted union of two lists of tags
+func mergeTags(a, b []string) []string {
+	seen := map[string]bool{}
+	var res []string
+	for _, t := range append(append([]string(nil), a...), b...) {
+		if !seen[t] {
+			seen[t] = true
+			res = append(res, t)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+//sameTags reports whether two sorted lists of tags are equal
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	"github.com/Perezonance/article-management-service/internal/util/validator"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"Go", "go"},
+		{"  Go   Lang ", "go lang"},
+		{"go\tlang\nnotes", "go lang notes"},
+		{"ÉCOLE", "école"},
+		{"   ", ""},
+	} {
+		if got := NormalizeTag(tc.in); got != tc.want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		in     []string
+		want   []string
+		fields []validator.FieldError
+	}{
+		{"sorted and deduplicated", []string{"Web", "go", " GO ", "web"}, []string{"go", "web"}, nil},
+		{"at the length limit", []string{strings.Repeat("é", maxTagLen)}, []string{strings.Repeat("é", maxTagLen)}, nil},
+		{"empty list", nil, nil, []validator.FieldError{{Field: "tags", Reason: "is required"}}},
+		{"every bad tag", []string{"ok", " ", strings.Repeat("a", maxTagLen+1), "a/b", "a,b", "a\x00b"}, nil, []validator.FieldError{
+			{Field: "tags[1]", Reason: "is required"},
+			{Field: "tags[2]", Reason: fmt.Sprintf("must be at most %v characters", maxTagLen)},
+			{Field: "tags[3]", Reason: "must not contain slashes, commas or control characters"},
+			{Field: "tags[4]", Reason: "must not contain slashes, commas or control characters"},
+			{Field: "tags[5]", Reason: "must not contain slashes, commas or control characters"},
+		}},
+	} {
+		got, err := normalizeTags(tc.in)
+		var verr *ValidationError
+		if tc.fields == nil {
+			if err != nil || !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("%v: normalizeTags(%q) = %q, %v, want %q", tc.name, tc.in, got, err, tc.want)
+			}
+		} else if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Fields, tc.fields) {
+			t.Errorf("%v: normalizeTags(%q) returned %v, want a validation error listing %v", tc.name, tc.in, err, tc.fields)
+		}
+	}
+}
+
+func TestAddTagsCapsTagsPerArticle(t *testing.T) {
+	s := NewServer(storage.NewMockDynamo())
+	ctx := context.Background()
+	id, err := s.CreateArticle(ctx, models.NewArticle{UserID: 1, Title: "t", Body: "b"})
+	if err != nil {
+		t.Fatalf("CreateArticle returned %v", err)
+	}
+	var tags []string
+	for i := 0; i < maxTags; i++ {
+		tags = append(tags, fmt.Sprint("tag", i))
+	}
+	if _, err := s.AddTags(ctx, id, tags[:maxTags-1]); err != nil {
+		t.Fatalf("AddTags returned %v", err)
+	}
+
+	//Tags already carried do not count twice against the cap
+	a, err := s.AddTags(ctx, id, []string{"TAG0", tags[maxTags-1]})
+	if err != nil {
+		t.Fatalf("AddTags up to the cap returned %v", err)
+	}
+	if len(a.Tags) != maxTags {
+		t.Fatalf("AddTags up to the cap left %v tags, want %v", len(a.Tags), maxTags)
+	}
+	var verr *ValidationError
+	if _, err := s.AddTags(ctx, id, []string{"one too many"}); !errors.As(err, &verr) {
+		t.Fatalf("AddTags past the cap returned %v, want a validation error", err)
+	}
+	if a, _ := s.GetArticleByID(ctx, id); len(a.Tags) != maxTags {
+		t.Fatalf("AddTags past the cap left %v tags, want %v", len(a.Tags), maxTags)
+	}
+
+	a, err = s.RemoveTags(ctx, id, []string{" Tag1 ", "unknown"})
+	if err != nil || len(a.Tags) != maxTags-1 {
+		t.Fatalf("RemoveTags returned %v tags, %v, want %v", len(a.Tags), err, maxTags-1)
+	}
+	if _, err := s.AddTags(ctx, id, []string{"one too many"}); err != nil {
+		t.Fatalf("AddTags after a removal returned %v", err)
+	}
+	if _, err := s.AddTags(ctx, 99, []string{"go"}); err != storage.ErrResourceNotFound {
+		t.Fatalf("AddTags on a missing article returned %v, want ErrResourceNotFound", err)
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+//purgeBatch is the number of trashed articles read at a time while purging
+const purgeBatch = 100
+
+//ListTrash returns one page of trashed articles ordered by id, resuming after the given opaque
+//cursor
+//GET /trash?limit=n&cursor=c
+func (s *Server) ListTrash(ctx context.Context, cur string, limit int) (models.ArticlePage, error) {
+	return s.listPage(ctx, storage.PageQuery{Limit: pageLimit(limit), Trashed: true}, cur)
+}
+
+//GetTrashedArticle returns the trashed article with the given id
+func (s *Server) GetTrashedArticle(ctx context.Context, id int) (models.Article, error) {
+	a, err := s.db.GetArticleByID(ctx, id)
+	if err == nil && a.DeletedAt == nil {
+		err = storage.ErrResourceNotFound
+	}
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while requesting trashed article with id:%v", id), err)
+		return models.Article{}, err
+	}
+	return a, nil
+}
+
+//RestoreArticle takes an article out of the trash if it is still at the expected version,
+//storage.AnyVersion skips the check. The article comes back with the status it was deleted in
+//POST /trash/{articleId}/restore
+func (s *Server) RestoreArticle(ctx context.Context, id, version int) (models.Article, error) {
+	a, err := s.modifyIn(ctx, id, version, true, func(cur *models.Article) error {
+		cur.DeletedAt = nil
+		return nil
+	})
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while restoring article with id:%v", id), err)
+		return models.Article{}, err
+	}
+	return a, nil
+}
+
+//RunPurger permanently deletes articles that have been in the trash longer than retention every
+//interval until ctx is done
+func (s *Server) RunPurger(ctx context.Context, interval, retention time.Duration) {
+	runEvery(ctx, interval, "trash purge", func(ctx context.Context) (int, error) {
+		return s.PurgeTrash(ctx, retention)
+	})
+}
+
+//PurgeTrash permanently deletes the articles, with their revisions, comments, reactions and
+//views, that were trashed longer than retention ago by the server clock and returns how many
+//were deleted. Each delete is tied to the version that was read so an article restored in the
+//meantime is left alone
+func (s *Server) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	cutoff := s.clock.Now().Add(-retention)
+	purged := 0
+	q := storage.PageQuery{Limit: purgeBatch, Trashed: true}
+	for {
+		page, err := s.db.ListArticles(ctx, q)
+		if err != nil {
+			return purged, err
+		}
+		for _, a := range page.Articles {
+			if a.DeletedAt.After(cutoff) {
+				continue
+			}
+			switch err := s.db.DeleteArticle(ctx, a.ArticleID, a.Version); err {
+			case nil:
+				purged++
+				s.index.remove(a.ArticleID)
+				s.deleteArticleComments(ctx, a.ArticleID)
+				s.deleteArticleReactions(ctx, a.ArticleID)
+				s.deleteArticleViews(ctx, a.ArticleID)
+				log.InfoLog(fmt.Sprintf("Purged trashed article with id:%v", a.ArticleID))
+			case storage.ErrResourceNotFound, storage.ErrVersionConflict:
+			default:
+				log.ErrorLog(fmt.Sprintf("Error while purging article with id:%v", a.ArticleID), err)
+			}
+		}
+		if !page.More {
+			return purged, nil
+		}
+		q = q.Next(page.Articles[len(page.Articles)-1])
+	}
+}
+
+//untrashed leaves out the trashed articles of a slice
+func untrashed(arts []models.Article) []models.Article {
+	res := arts[:0]
+	for _, a := range arts {
+		if a.DeletedAt == nil {
+			res = append(res, a)
+		}
+	}
+	return res
+}
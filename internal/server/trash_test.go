@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+//purgeTrash runs a purge with the given retention and checks how many articles it deleted
+func purgeTrash(t *testing.T, s *Server, retention time.Duration, want int) {
+	t.Helper()
+	n, err := s.PurgeTrash(context.Background(), retention)
+	if err != nil {
+		t.Fatalf("PurgeTrash returned %v", err)
+	}
+	if n != want {
+		t.Fatalf("PurgeTrash deleted %v articles, want %v", n, want)
+	}
+}
+
+func TestPurgeTrashHonoursRetention(t *testing.T) {
+	const retention = 24 * time.Hour
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	clk := &fakeClock{t: start}
+	db := storage.NewMockDynamo()
+	var (
+		cs = storage.NewMemoryCommentStorage()
+		rs = storage.NewMemoryReactionStorage()
+		vs = storage.NewMemoryViewStorage()
+	)
+	s := NewServerWithStores(db, cs, rs, vs, clk)
+	ctx := WithActor(context.Background(), 7)
+
+	old := scheduled(t, db, models.StatusPublished, nil, nil)
+	recent := scheduled(t, db, models.StatusPublished, nil, nil)
+	later := scheduled(t, db, models.StatusPublished, nil, nil)
+	c, err := s.CreateComment(ctx, old, 0, models.NewComment{Body: "first"})
+	if err != nil {
+		t.Fatalf("CreateComment returned %v", err)
+	}
+	if _, err := s.AddReaction(ctx, old, models.ReactionKinds[0]); err != nil {
+		t.Fatalf("AddReaction returned %v", err)
+	}
+	s.RecordView(old, "a")
+	if _, err := s.FlushViews(ctx); err != nil {
+		t.Fatalf("FlushViews returned %v", err)
+	}
+
+	if err := s.DeleteArticle(ctx, old, storage.AnyVersion); err != nil {
+		t.Fatalf("DeleteArticle returned %v", err)
+	}
+	clk.t = start.Add(time.Hour)
+	if err := s.DeleteArticle(ctx, recent, storage.AnyVersion); err != nil {
+		t.Fatalf("DeleteArticle returned %v", err)
+	}
+
+	//The first article is purged once it has been in the trash for the whole retention and not
+	//a second earlier
+	clk.t = start.Add(retention - time.Second)
+	purgeTrash(t, s, retention, 0)
+	clk.t = start.Add(retention)
+	purgeTrash(t, s, retention, 1)
+	if _, err := db.GetArticleByID(ctx, old); err != storage.ErrResourceNotFound {
+		t.Fatalf("purged article lookup returned %v, want ErrResourceNotFound", err)
+	}
+	if revs, err := db.GetRevisions(ctx, old); (err != nil && err != storage.ErrResourceNotFound) || len(revs) != 0 {
+		t.Fatalf("purged article kept revisions %+v, %v", revs, err)
+	}
+	if _, err := cs.GetComment(ctx, c.CommentID); err != storage.ErrResourceNotFound {
+		t.Fatalf("comment of the purged article lookup returned %v, want ErrResourceNotFound", err)
+	}
+	if r, err := rs.GetReactions(ctx, 0, []int{old}); err != nil || len(r[old].Counts) != 0 {
+		t.Fatalf("purged article kept reactions %+v, %v", r[old], err)
+	}
+	if counts, err := vs.GetViewCounts(ctx, start); err != nil || len(counts) != 0 {
+		t.Fatalf("purged article kept views %+v, %v", counts, err)
+	}
+	if a := article(t, db, recent); a.DeletedAt == nil {
+		t.Fatalf("article trashed an hour later left the trash")
+	}
+
+	//A restored article is no longer due, one still in the trash is purged when its turn comes
+	if _, err := s.RestoreArticle(ctx, recent, storage.AnyVersion); err != nil {
+		t.Fatalf("RestoreArticle returned %v", err)
+	}
+	if err := s.DeleteArticle(ctx, later, storage.AnyVersion); err != nil {
+		t.Fatalf("DeleteArticle returned %v", err)
+	}
+	clk.t = start.Add(retention + time.Hour)
+	purgeTrash(t, s, retention, 0)
+	if a := article(t, db, recent); a.DeletedAt != nil {
+		t.Fatalf("restored article is back in the trash")
+	}
+	clk.t = clk.t.Add(retention)
+	purgeTrash(t, s, retention, 1)
+	if _, err := db.GetArticleByID(ctx, later); err != storage.ErrResourceNotFound {
+		t.Fatalf("purged article lookup returned %v, want ErrResourceNotFound", err)
+	}
+}
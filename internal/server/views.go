@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+const (
+	//ViewDedupWindow is how long repeated views of an article by the same client count as one
+	ViewDedupWindow = 30 * time.Minute
+	//MaxPopularWindow is the longest window popular articles are ranked over, older views are
+	//dropped from storage
+	MaxPopularWindow = 30 * 24 * time.Hour
+)
+
+//viewKey identifies the views of an article by a single client
+type viewKey struct {
+	articleID int
+	client    string
+}
+
+//viewHour identifies the views of an article during the hour starting at
+type viewHour struct {
+	articleID int
+	at        time.Time
+}
+
+//viewRecorder holds the views counted since the last flush to storage along with the last time
+//each client's view of an article was counted and the hour old views were last dropped in
+type viewRecorder struct {
+	mu      sync.Mutex
+	seen    map[viewKey]time.Time
+	pending map[viewHour]int
+	pruned  time.Time
+}
+
+func newViewRecorder() *viewRecorder {
+	return &viewRecorder{seen: make(map[viewKey]time.Time), pending: make(map[viewHour]int)}
+}
+
+//RecordView counts a view of an article by a client, identified by any string stable across its
+//requests. Views by the same client within ViewDedupWindow of the last one counted are ignored.
+//The view is only held in memory until the next FlushViews so recording never waits on storage
+func (s *Server) RecordView(id int, client string) {
+	now := s.clock.Now().UTC()
+	v := s.viewLog
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	k := viewKey{articleID: id, client: client}
+	if last, ok := v.seen[k]; ok && now.Sub(last) < ViewDedupWindow {
+		return
+	}
+	v.seen[k] = now
+	v.pending[viewHour{articleID: id, at: now.Truncate(time.Hour)}]++
+}
+
+//RunViewFlusher writes the recorded views to storage every interval until ctx is done, then
+//flushes one last time so that no counted view is lost on shutdown
+func (s *Server) RunViewFlusher(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, "view flush", s.FlushViews)
+	if _, err := s.FlushViews(context.Background()); err != nil {
+		log.ErrorLog("Error while flushing views on shutdown", err)
+	}
+}
+
+//FlushViews writes the views recorded since the last flush to storage in a single batch and
+//returns how many there were. Views that fail to be written are kept for the next flush. It also
+//forgets clients outside the dedup window and, once an hour as views are stored by the hour,
+//drops views older than MaxPopularWindow
+func (s *Server) FlushViews(ctx context.Context) (int, error) {
+	now := s.clock.Now().UTC()
+	v := s.viewLog
+	v.mu.Lock()
+	pending := v.pending
+	v.pending = make(map[viewHour]int)
+	for k, last := range v.seen {
+		if now.Sub(last) >= ViewDedupWindow {
+			delete(v.seen, k)
+		}
+	}
+	prune := now.Truncate(time.Hour).After(v.pruned)
+	v.mu.Unlock()
+
+	counts := make([]storage.ViewCount, 0, len(pending))
+	total := 0
+	for h, n := range pending {
+		counts = append(counts, storage.ViewCount{ArticleID: h.articleID, At: h.at, Views: n})
+		total += n
+	}
+	if len(counts) > 0 {
+		if err := s.views.AddViews(ctx, counts); err != nil {
+			v.mu.Lock()
+			for h, n := range pending {
+				v.pending[h] += n
+			}
+			v.mu.Unlock()
+			return 0, err
+		}
+	}
+	if !prune {
+		return total, nil
+	}
+	if _, err := s.views.DeleteViewsBefore(ctx, now.Add(-MaxPopularWindow)); err != nil {
+		return total, err
+	}
+	v.mu.Lock()
+	v.pruned = now.Truncate(time.Hour)
+	v.mu.Unlock()
+	return total, nil
+}
+
+//PopularArticles returns at most limit of the published articles outside the trash viewed most
+//during the window ending now, most viewed first. Views are counted by the hour so the window
+//stretches back to the start of its first hour, and views not flushed yet are left out
+//GET /articles/popular?window=24h
+func (s *Server) PopularArticles(ctx context.Context, window time.Duration, limit int) ([]models.PopularArticle, error) {
+	counts, err := s.views.GetViewCounts(ctx, s.clock.Now().Add(-window))
+	if err != nil {
+		log.ErrorLog("Error while requesting view counts", err)
+		return nil, err
+	}
+
+	limit = pageLimit(limit)
+	res := []models.PopularArticle{}
+	for _, c := range counts {
+		if len(res) == limit {
+			break
+		}
+		a, err := s.db.GetArticleByID(ctx, c.ArticleID)
+		if err == storage.ErrResourceNotFound {
+			continue
+		}
+		if err != nil {
+			log.ErrorLog(fmt.Sprintf("Error while requesting popular article with id:%v", c.ArticleID), err)
+			return nil, err
+		}
+		if a.DeletedAt == nil && a.Status == models.StatusPublished {
+			res = append(res, models.PopularArticle{Article: a, Views: c.Views})
+		}
+	}
+	return res, nil
+}
+
+//deleteArticleViews removes the views of an article that has been deleted. A failure is only
+//logged, views of a missing article are never listed
+func (s *Server) deleteArticleViews(ctx context.Context, articleID int) {
+	if _, err := s.views.DeleteArticleViews(ctx, articleID); err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while deleting the views of article with id:%v", articleID), err)
+	}
+}
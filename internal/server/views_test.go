@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+//countingViews is a ViewStorage recording the batches written to it, failing the writes while
+//fail is set
+type countingViews struct {
+	storage.ViewStorage
+	batches [][]storage.ViewCount
+	prunes  int
+	fail    bool
+}
+
+func (v *countingViews) AddViews(ctx context.Context, counts []storage.ViewCount) error {
+	if v.fail {
+		return errors.New("write failed")
+	}
+	v.batches = append(v.batches, counts)
+	return v.ViewStorage.AddViews(ctx, counts)
+}
+
+func (v *countingViews) DeleteViewsBefore(ctx context.Context, t time.Time) (int, error) {
+	v.prunes++
+	return v.ViewStorage.DeleteViewsBefore(ctx, t)
+}
+
+//flushViews flushes the recorded views and checks how many there were
+func flushViews(t *testing.T, s *Server, want int) {
+	t.Helper()
+	n, err := s.FlushViews(context.Background())
+	if err != nil {
+		t.Fatalf("FlushViews returned %v", err)
+	}
+	if n != want {
+		t.Fatalf("FlushViews wrote %v views, want %v", n, want)
+	}
+}
+
+//popularViews returns the flushed views of an article over the last day
+func popularViews(t *testing.T, s *Server, id int) int {
+	t.Helper()
+	popular, err := s.PopularArticles(context.Background(), 24*time.Hour, 10)
+	if err != nil {
+		t.Fatalf("PopularArticles returned %v", err)
+	}
+	for _, p := range popular {
+		if p.Article.ArticleID == id {
+			return p.Views
+		}
+	}
+	return 0
+}
+
+func TestRecordViewDedupesWithinWindow(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+	db := storage.NewMockDynamo()
+	s := NewServerWithClock(db, clk)
+	id := scheduled(t, db, models.StatusPublished, nil, nil)
+
+	s.RecordView(id, "a")
+	s.RecordView(id, "a")
+	s.RecordView(id, "b")
+	clk.t = clk.t.Add(ViewDedupWindow - time.Second)
+	s.RecordView(id, "a")
+	flushViews(t, s, 2)
+
+	//The window runs from the last view counted, not the last one seen
+	clk.t = clk.t.Add(time.Second)
+	s.RecordView(id, "a")
+	s.RecordView(id, "b")
+	flushViews(t, s, 2)
+	if n := popularViews(t, s, id); n != 4 {
+		t.Fatalf("article has %v views, want 4", n)
+	}
+}
+
+func TestFlushViewsWritesOneBatch(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)}
+	db := storage.NewMockDynamo()
+	views := &countingViews{ViewStorage: storage.NewMemoryViewStorage()}
+	s := NewServerWithStores(db, storage.NewMemoryCommentStorage(), storage.NewMemoryReactionStorage(), views, clk)
+	first := scheduled(t, db, models.StatusPublished, nil, nil)
+	second := scheduled(t, db, models.StatusPublished, nil, nil)
+
+	for _, client := range []string{"a", "b", "c"} {
+		s.RecordView(first, client)
+	}
+	s.RecordView(second, "a")
+	clk.t = clk.t.Add(time.Hour)
+	s.RecordView(first, "d")
+	if len(views.batches) != 0 {
+		t.Fatalf("RecordView wrote %v batches before a flush, want none", len(views.batches))
+	}
+
+	flushViews(t, s, 5)
+	if len(views.batches) != 1 {
+		t.Fatalf("FlushViews wrote %v batches, want 1", len(views.batches))
+	}
+	got := map[int]map[time.Time]int{first: {}, second: {}}
+	for _, c := range views.batches[0] {
+		got[c.ArticleID][c.At] += c.Views
+	}
+	hour := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	if len(views.batches[0]) != 3 || got[first][hour] != 3 || got[first][hour.Add(time.Hour)] != 1 || got[second][hour] != 1 {
+		t.Fatalf("FlushViews wrote %+v, want one count per article and hour", views.batches[0])
+	}
+
+	flushViews(t, s, 0)
+	if len(views.batches) != 1 {
+		t.Fatalf("FlushViews with nothing recorded wrote %v batches, want 1", len(views.batches))
+	}
+	if views.prunes != 1 {
+		t.Fatalf("two flushes in one hour dropped old views %v times, want 1", views.prunes)
+	}
+	clk.t = clk.t.Add(time.Hour)
+	flushViews(t, s, 0)
+	if views.prunes != 2 {
+		t.Fatalf("flushes over two hours dropped old views %v times, want 2", views.prunes)
+	}
+}
+
+func TestFlushViewsKeepsFailedViews(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+	db := storage.NewMockDynamo()
+	views := &countingViews{ViewStorage: storage.NewMemoryViewStorage(), fail: true}
+	s := NewServerWithStores(db, storage.NewMemoryCommentStorage(), storage.NewMemoryReactionStorage(), views, clk)
+	id := scheduled(t, db, models.StatusPublished, nil, nil)
+
+	s.RecordView(id, "a")
+	if _, err := s.FlushViews(context.Background()); err == nil {
+		t.Fatalf("FlushViews returned no error when the write failed")
+	}
+	views.fail = false
+	s.RecordView(id, "b")
+	flushViews(t, s, 2)
+	if n := popularViews(t, s, id); n != 2 {
+		t.Fatalf("article has %v views, want 2", n)
+	}
+}
+
+func TestFlushedViewsSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "articles.bolt")
+	clk := &fakeClock{t: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+
+	db, err := storage.NewBoltStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltStorage returned %v", err)
+	}
+	id := scheduled(t, db, models.StatusPublished, nil, nil)
+	s := NewServerWithClock(db, clk)
+	s.RecordView(id, "a")
+	s.RecordView(id, "b")
+	flushViews(t, s, 2)
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close returned %v", err)
+	}
+
+	db, err = storage.NewBoltStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltStorage returned %v", err)
+	}
+	defer db.Close()
+	if n := popularViews(t, NewServerWithClock(db, clk), id); n != 2 {
+		t.Fatalf("article has %v views after a restart, want 2", n)
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	log "github.com/Perezonance/article-management-service/internal/util/logger"
+)
+
+//transitions lists the statuses each status may move to. Drafts go to review, review either
+//publishes or returns the article to draft, published articles are archived and archived ones
+//can be revived as drafts
+var transitions = map[string][]string{
+	models.StatusDraft:     {models.StatusInReview, models.StatusArchived},
+	models.StatusInReview:  {models.StatusDraft, models.StatusPublished},
+	models.StatusPublished: {models.StatusArchived},
+	models.StatusArchived:  {models.StatusDraft},
+}
+
+//canTransition reports whether an article may move from one status to another
+func canTransition(from, to string) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+//TransitionArticle moves an article to the requested status on behalf of the user making the
+//request if the move is allowed from its current status and the article is still at the expected
+//version, recording who made the transition and when. The user is also recorded as the last editor
+//POST /articles/{articleId}/transitions
+func (s *Server) TransitionArticle(ctx context.Context, id, version int, t models.TransitionRequest) (models.Article, error) {
+	userID := actor(ctx)
+	if userID == 0 {
+		return models.Article{}, ErrNoActor
+	}
+	a, err := s.modify(ctx, id, version, func(cur *models.Article) error {
+		if !canTransition(cur.Status, t.Status) {
+			return fmt.Errorf("%w: %v to %v", ErrIllegalTransition, cur.Status, t.Status)
+		}
+		transition(cur, t.Status, userID, s.clock.Now())
+		return nil
+	})
+	if err != nil {
+		log.ErrorLog(fmt.Sprintf("Error while moving article with id:%v to %v", id, t.Status), err)
+		return models.Article{}, err
+	}
+	return a, nil
+}
+
+//transition moves an article to a status and records the move
+func transition(a *models.Article, status string, by int, at time.Time) {
+	a.LastTransition = &models.Transition{From: a.Status, To: status, By: by, At: at.UTC()}
+	a.Status = status
+}
+
+//sameTransition reports whether two recorded transitions are equal, comparing times by instant
+func sameTransition(a, b *models.Transition) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.From == b.From && a.To == b.To && a.By == b.By && a.At.Equal(b.At)
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+func TestTransitionArticleActsForRequestUser(t *testing.T) {
+	ctx := context.Background()
+	clk := &fakeClock{t: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)}
+	db := storage.NewMockDynamo()
+	s := NewServerWithClock(db, clk)
+	id := scheduled(t, db, models.StatusDraft, nil, nil)
+
+	_, err := s.TransitionArticle(ctx, id, storage.AnyVersion, models.TransitionRequest{Status: models.StatusInReview})
+	if !errors.Is(err, ErrNoActor) {
+		t.Fatalf("TransitionArticle without an actor returned %v, want ErrNoActor", err)
+	}
+	if a := article(t, db, id); a.Status != models.StatusDraft {
+		t.Fatalf("article is %v after a transition without an actor, want draft", a.Status)
+	}
+
+	a, err := s.TransitionArticle(WithActor(ctx, 9), id, storage.AnyVersion, models.TransitionRequest{Status: models.StatusInReview})
+	if err != nil {
+		t.Fatalf("TransitionArticle returned %v", err)
+	}
+	if lt := a.LastTransition; lt == nil || lt.By != 9 || lt.To != models.StatusInReview || !lt.At.Equal(clk.t) {
+		t.Fatalf("transition recorded %+v, want user 9 moving to in_review at %v", a.LastTransition, clk.t)
+	}
+	if a.UpdatedBy != 9 {
+		t.Fatalf("transition credited user %v as the last editor, want 9", a.UpdatedBy)
+	}
+}
@@ -0,0 +1,642 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	articlesBucket     = []byte("articles")
+	userArticlesBucket = []byte("userArticles")
+	revisionsBucket    = []byte("revisions")
+	createdAtBucket    = []byte("articlesByCreatedAt")
+	updatedAtBucket    = []byte("articlesByUpdatedAt")
+	titleBucket        = []byte("articlesByTitle")
+	articleTagsBucket  = []byte("articleTags")
+	tagArticlesBucket  = []byte("tagArticles")
+	slugsBucket        = []byte("slugs")
+	articleSlugsBucket = []byte("articleSlugs")
+)
+
+//boltIndex is a bucket of empty values whose keys order articles, each key ending in the id of
+//its article
+type boltIndex struct {
+	bucket []byte
+	key    func(models.Article) []byte
+}
+
+//boltIndexes are kept up to date with every write, ListArticles walks the one matching its query
+var boltIndexes = []boltIndex{
+	{userArticlesBucket, func(a models.Article) []byte { return userArticleKey(a.UserID, a.ArticleID) }},
+	{createdAtBucket, func(a models.Article) []byte { return timeIndexKey(a.CreatedAt, a.ArticleID) }},
+	{updatedAtBucket, func(a models.Article) []byte { return timeIndexKey(a.UpdatedAt, a.ArticleID) }},
+	{titleBucket, func(a models.Article) []byte { return append([]byte(a.Title+"\x00"), itob(a.ArticleID)...) }},
+}
+
+//BoltStorage persists articles to a single bbolt data file. Articles are stored as JSON keyed
+//by id, secondary buckets index them by user, timestamps and title and a revisions bucket keeps
+//every version keyed by articleID|version. Tags are related to articles by a pair of buckets
+//keyed articleID|tag and tag|articleID, and slugs map to the article holding them with every
+//slug an article held listed under articleID|slug. Comments are stored as JSON keyed by id with
+//a thread bucket keyed articleID|parentID|commentID, reactions as keys articleID|userID|kind
+//and view counts keyed hour|articleID.
+//Every write updates the article, its index entries and its revisions in one transaction, and
+//bbolt's copy-on-write commits mean a crash leaves the file at the last committed transaction
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+//NewBoltStorage opens or creates the data file at path and ensures the buckets exist
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	//Fail fast instead of blocking forever when another process holds the file lock
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{articlesBucket, revisionsBucket, articleTagsBucket, tagArticlesBucket, slugsBucket, articleSlugsBucket, commentsBucket, commentThreadsBucket, reactionsBucket, viewsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		for _, ix := range boltIndexes {
+			if err := ensureIndex(tx, ix); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStorage{db: db}, nil
+}
+
+//Close releases the data file
+func (b *BoltStorage) Close() error {
+	return b.db.Close()
+}
+
+//GetArticleByID returns an article given an id
+func (b *BoltStorage) GetArticleByID(ctx context.Context, id int) (models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Article{}, err
+	}
+	var article models.Article
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		article, err = getArticle(tx, id)
+		return err
+	})
+	return article, err
+}
+
+//GetArticleBySlug returns the article holding a slug, now or before it moved on to another one
+func (b *BoltStorage) GetArticleBySlug(ctx context.Context, slug string) (models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Article{}, err
+	}
+	var article models.Article
+	err := b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(slugsBucket).Get([]byte(slug))
+		if v == nil {
+			return ErrResourceNotFound
+		}
+		var err error
+		article, err = getArticle(tx, btoi(v))
+		return err
+	})
+	return article, err
+}
+
+//GetAllArticles returns all articles in the data file ordered by id
+func (b *BoltStorage) GetAllArticles(ctx context.Context) ([]models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var articles []models.Article
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(articlesBucket).ForEach(func(_, v []byte) error {
+			a, err := decodeArticle(v)
+			if err != nil {
+				return err
+			}
+			articles = append(articles, withTags(tx, a))
+			return nil
+		})
+	})
+	return articles, err
+}
+
+//GetArticleByUserID returns all articles filtered by a particular userId using the user index
+func (b *BoltStorage) GetArticleByUserID(ctx context.Context, userID int) ([]models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var articles []models.Article
+	err := b.db.View(func(tx *bolt.Tx) error {
+		prefix := itob(userID)
+		c := tx.Bucket(userArticlesBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			a, err := getArticle(tx, btoi(k[8:]))
+			if err != nil {
+				return err
+			}
+			articles = append(articles, a)
+		}
+		return nil
+	})
+	return articles, err
+}
+
+//ListArticles returns a page of articles in the order of the query by walking the articles
+//bucket or the index holding that order. Sorting by id walks the tag bucket for one tag and the
+//user index for one author
+func (b *BoltStorage) ListArticles(ctx context.Context, q PageQuery) (Page, error) {
+	if err := ctx.Err(); err != nil {
+		return Page{}, err
+	}
+	ix, prefix := boltIndex{articlesBucket, func(a models.Article) []byte { return itob(a.ArticleID) }}, []byte(nil)
+	switch {
+	case q.Sort == SortByCreatedAt:
+		ix = boltIndexes[1]
+	case q.Sort == SortByUpdatedAt:
+		ix = boltIndexes[2]
+	case q.Sort == SortByTitle:
+		ix = boltIndexes[3]
+	case q.Tag != "":
+		ix = boltIndex{tagArticlesBucket, func(a models.Article) []byte { return tagArticleKey(q.Tag, a.ArticleID) }}
+		prefix = tagPrefix(q.Tag)
+	case q.UserID != 0:
+		ix, prefix = boltIndexes[0], itob(q.UserID)
+	}
+
+	var articles []models.Article
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(ix.bucket).Cursor()
+		var k []byte
+		switch {
+		case q.AfterID == 0 && !q.Desc:
+			k, _ = c.Seek(prefix)
+		case q.AfterID == 0:
+			k = seekLast(c, prefix)
+		default:
+			pos := q.position()
+			pos.UserID = q.UserID
+			after := ix.key(pos)
+			k, _ = c.Seek(after)
+			switch {
+			case q.Desc && k == nil:
+				k, _ = c.Last()
+			case q.Desc:
+				k, _ = c.Prev()
+			case bytes.Equal(k, after):
+				k, _ = c.Next()
+			}
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix) && len(articles) <= q.Limit; k = step(c, q.Desc) {
+			a, err := getArticle(tx, btoi(k[len(k)-8:]))
+			if err != nil {
+				return err
+			}
+			if q.Matches(a) {
+				articles = append(articles, a)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Page{}, err
+	}
+	return newPage(articles, q.Limit), nil
+}
+
+//seekLast moves the cursor onto the last key starting with prefix, or the last key of the
+//bucket when prefix is empty
+func seekLast(c *bolt.Cursor, prefix []byte) []byte {
+	past := successor(prefix)
+	if past == nil {
+		k, _ := c.Last()
+		return k
+	}
+	if k, _ := c.Seek(past); k == nil {
+		k, _ = c.Last()
+		return k
+	}
+	k, _ := c.Prev()
+	return k
+}
+
+//successor returns the first key past every key starting with prefix, nil when there is none
+func successor(prefix []byte) []byte {
+	past := append([]byte(nil), prefix...)
+	for i := len(past) - 1; i >= 0; i-- {
+		if past[i]++; past[i] != 0 {
+			return past[:i+1]
+		}
+	}
+	return nil
+}
+
+func step(c *bolt.Cursor, desc bool) []byte {
+	if desc {
+		k, _ := c.Prev()
+		return k
+	}
+	k, _ := c.Next()
+	return k
+}
+
+//CreateArticle stores a new article under the next id of the articles bucket sequence
+func (b *BoltStorage) CreateArticle(ctx context.Context, art models.Article) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	var id int
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		seq, err := tx.Bucket(articlesBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		id = int(seq)
+		art.ArticleID, art.Version, art.Status = id, 1, models.StatusDraft
+		if err := claimSlug(tx, id, art.Slug); err != nil {
+			return err
+		}
+		return putArticle(tx, art)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+//UpdateArticle replaces an existing article with a new one if its version still matches,
+//moving its index entries
+func (b *BoltStorage) UpdateArticle(ctx context.Context, id int, article models.Article) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		old, err := getArticleVersion(tx, id, article.Version)
+		if err != nil {
+			return err
+		}
+		if err := claimSlug(tx, id, article.Slug); err != nil {
+			return err
+		}
+		if err := deleteIndexes(tx, old); err != nil {
+			return err
+		}
+		article.ArticleID = id
+		article.Version = old.Version + 1
+		return putArticle(tx, article)
+	})
+}
+
+//DeleteArticle removes an article and its index entries from the data file if its version
+//still matches
+func (b *BoltStorage) DeleteArticle(ctx context.Context, id, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		old, err := getArticleVersion(tx, id, version)
+		if err != nil {
+			return err
+		}
+		if err := deleteIndexes(tx, old); err != nil {
+			return err
+		}
+		if err := untag(tx, id, old.Tags); err != nil {
+			return err
+		}
+		for _, k := range prefixedKeys(tx, articleSlugsBucket, itob(id)) {
+			if err := tx.Bucket(slugsBucket).Delete(k[8:]); err != nil {
+				return err
+			}
+			if err := tx.Bucket(articleSlugsBucket).Delete(k); err != nil {
+				return err
+			}
+		}
+		for _, k := range prefixedKeys(tx, revisionsBucket, itob(id)) {
+			if err := tx.Bucket(revisionsBucket).Delete(k); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(articlesBucket).Delete(itob(id))
+	})
+}
+
+//GetScheduledArticles returns the articles outside the trash with a change due by t ordered by
+//id, those in review with a publishAt at or before t and those published with an unpublishAt at
+//or before t
+func (b *BoltStorage) GetScheduledArticles(ctx context.Context, t time.Time) ([]models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var articles []models.Article
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(articlesBucket).ForEach(func(_, v []byte) error {
+			a, err := decodeArticle(v)
+			if err != nil {
+				return err
+			}
+			if scheduledBy(a, t) {
+				articles = append(articles, withTags(tx, a))
+			}
+			return nil
+		})
+	})
+	return articles, err
+}
+
+//AddTags relates an article to the given tags, tags it already carries are left alone
+func (b *BoltStorage) AddTags(ctx context.Context, id int, tags []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(articlesBucket).Get(itob(id)) == nil {
+			return ErrResourceNotFound
+		}
+		for _, t := range tags {
+			if err := tx.Bucket(articleTagsBucket).Put(articleTagKey(id, t), []byte{}); err != nil {
+				return err
+			}
+			if err := tx.Bucket(tagArticlesBucket).Put(tagArticleKey(t, id), []byte{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//RemoveTags drops the given tags from an article, tags it does not carry are left alone
+func (b *BoltStorage) RemoveTags(ctx context.Context, id int, tags []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(articlesBucket).Get(itob(id)) == nil {
+			return ErrResourceNotFound
+		}
+		return untag(tx, id, tags)
+	})
+}
+
+//GetTagCounts returns every tag carried by an article outside the trash with the number of
+//such articles carrying it, restricted to a single status when status is non-empty. It walks
+//the tag bucket so that untagged articles are never read
+func (b *BoltStorage) GetTagCounts(ctx context.Context, status string) ([]models.TagCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var counts []models.TagCount
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tagArticlesBucket).ForEach(func(k, _ []byte) error {
+			a, err := getArticle(tx, btoi(k[len(k)-8:]))
+			if err != nil {
+				return err
+			}
+			if a.DeletedAt != nil || (status != "" && a.Status != status) {
+				return nil
+			}
+			tag := string(k[:len(k)-9])
+			if n := len(counts); n > 0 && counts[n-1].Tag == tag {
+				counts[n-1].Count++
+			} else {
+				counts = append(counts, models.TagCount{Tag: tag, Count: 1})
+			}
+			return nil
+		})
+	})
+	if counts == nil {
+		counts = []models.TagCount{}
+	}
+	return counts, err
+}
+
+//GetRevisions returns every revision of an article ordered by version
+func (b *BoltStorage) GetRevisions(ctx context.Context, id int) ([]models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var revisions []models.Article
+	err := b.db.View(func(tx *bolt.Tx) error {
+		prefix := itob(id)
+		c := tx.Bucket(revisionsBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			a, err := decodeArticle(v)
+			if err != nil {
+				return err
+			}
+			revisions = append(revisions, a)
+		}
+		if len(revisions) == 0 {
+			return ErrResourceNotFound
+		}
+		return nil
+	})
+	return revisions, err
+}
+
+//GetRevision returns the revision of an article at the given version
+func (b *BoltStorage) GetRevision(ctx context.Context, id, version int) (models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Article{}, err
+	}
+	var a models.Article
+	err := b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(revisionsBucket).Get(revisionKey(id, version))
+		if v == nil {
+			return ErrResourceNotFound
+		}
+		var err error
+		a, err = decodeArticle(v)
+		return err
+	})
+	return a, err
+}
+
+func getArticle(tx *bolt.Tx, id int) (models.Article, error) {
+	v := tx.Bucket(articlesBucket).Get(itob(id))
+	if v == nil {
+		return models.Article{}, ErrResourceNotFound
+	}
+	a, err := decodeArticle(v)
+	if err != nil {
+		return models.Article{}, err
+	}
+	return withTags(tx, a), nil
+}
+
+//decodeArticle decodes a stored article, filling in fields it was stored without
+func decodeArticle(v []byte) (models.Article, error) {
+	var a models.Article
+	if err := json.Unmarshal(v, &a); err != nil {
+		return models.Article{}, err
+	}
+	return withLegacyDefaults(a), nil
+}
+
+//withTags fills in the tags of an article, read in order from its prefix of the tag relation
+func withTags(tx *bolt.Tx, a models.Article) models.Article {
+	a.Tags = nil
+	prefix := itob(a.ArticleID)
+	c := tx.Bucket(articleTagsBucket).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		a.Tags = append(a.Tags, string(k[8:]))
+	}
+	return a
+}
+
+//untag removes the relation between an article and each of the given tags
+func untag(tx *bolt.Tx, id int, tags []string) error {
+	for _, t := range tags {
+		if err := tx.Bucket(articleTagsBucket).Delete(articleTagKey(id, t)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(tagArticlesBucket).Delete(tagArticleKey(t, id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//claimSlug reserves a slug for an article unless it is empty or the article holds it already
+func claimSlug(tx *bolt.Tx, id int, slug string) error {
+	if slug == "" {
+		return nil
+	}
+	if v := tx.Bucket(slugsBucket).Get([]byte(slug)); v != nil {
+		if btoi(v) != id {
+			return ErrSlugTaken
+		}
+		return nil
+	}
+	if err := tx.Bucket(slugsBucket).Put([]byte(slug), itob(id)); err != nil {
+		return err
+	}
+	return tx.Bucket(articleSlugsBucket).Put(append(itob(id), slug...), []byte{})
+}
+
+//prefixedKeys returns copies of the keys of a bucket starting with prefix. Deleting while
+//iterating skips keys, so callers removing them collect them first
+func prefixedKeys(tx *bolt.Tx, bucket, prefix []byte) [][]byte {
+	var keys [][]byte
+	c := tx.Bucket(bucket).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	return keys
+}
+
+func getArticleVersion(tx *bolt.Tx, id, version int) (models.Article, error) {
+	a, err := getArticle(tx, id)
+	if err != nil {
+		return models.Article{}, err
+	}
+	if version != AnyVersion && version != a.Version {
+		return models.Article{}, ErrVersionConflict
+	}
+	return a, nil
+}
+
+//putArticle stores an article, with the tags it carries left to the tag relation
+func putArticle(tx *bolt.Tx, a models.Article) error {
+	a.Tags = nil
+	v, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(articlesBucket).Put(itob(a.ArticleID), v); err != nil {
+		return err
+	}
+	if err := tx.Bucket(revisionsBucket).Put(revisionKey(a.ArticleID, a.Version), v); err != nil {
+		return err
+	}
+	for _, ix := range boltIndexes {
+		if err := tx.Bucket(ix.bucket).Put(ix.key(a), []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//deleteIndexes removes the index entries of an article as it was stored
+func deleteIndexes(tx *bolt.Tx, a models.Article) error {
+	for _, ix := range boltIndexes {
+		if err := tx.Bucket(ix.bucket).Delete(ix.key(a)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//ensureIndex creates an index bucket missing from the data file and fills it from the articles
+//already stored, so files written before the index was added can be listed in its order
+func ensureIndex(tx *bolt.Tx, ix boltIndex) error {
+	if tx.Bucket(ix.bucket) != nil {
+		return nil
+	}
+	bkt, err := tx.CreateBucket(ix.bucket)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(articlesBucket).ForEach(func(_, v []byte) error {
+		a, err := decodeArticle(v)
+		if err != nil {
+			return err
+		}
+		return bkt.Put(ix.key(a), []byte{})
+	})
+}
+
+//timeIndexKey builds the index key time|articleID, the fixed width time sorting in order
+func timeIndexKey(t time.Time, articleID int) []byte {
+	return append([]byte(t.UTC().Format(timeFormat)), itob(articleID)...)
+}
+
+//userArticleKey builds the index key userID|articleID so a user's articles share a prefix
+func userArticleKey(userID, articleID int) []byte {
+	return append(itob(userID), itob(articleID)...)
+}
+
+//articleTagKey builds the relation key articleID|tag so an article's tags share a prefix
+func articleTagKey(articleID int, tag string) []byte {
+	return append(itob(articleID), tag...)
+}
+
+//tagArticleKey builds the relation key tag|0|articleID so a tag's articles share a prefix, the
+//zero byte keeping a tag's articles ahead of those of longer tags
+func tagArticleKey(tag string, articleID int) []byte {
+	return append(tagPrefix(tag), itob(articleID)...)
+}
+
+func tagPrefix(tag string) []byte {
+	return []byte(tag + "\x00")
+}
+
+//revisionKey builds the revision key articleID|version so an article's revisions share a prefix
+func revisionKey(articleID, version int) []byte {
+	return append(itob(articleID), itob(version)...)
+}
+
+//itob encodes an int as 8 big-endian bytes so keys sort numerically
+func itob(v int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	return b
+}
+
+func btoi(b []byte) int {
+	return int(binary.BigEndian.Uint64(b))
+}
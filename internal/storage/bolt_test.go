@@ -0,0 +1,123 @@
+package storage_test
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	"github.com/Perezonance/article-management-service/internal/storage/storagetest"
+	bolt "go.etcd.io/bbolt"
+)
+
+//newBoltStorage opens a data file of its own in the test's temporary directory
+func newBoltStorage(t *testing.T) *storage.BoltStorage {
+	t.Helper()
+	b, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "articles.bolt"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage returned %v", err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestBoltStorageConformance(t *testing.T) {
+	storagetest.RunConformanceTests(t, func(t *testing.T) storage.Storage {
+		return newBoltStorage(t)
+	})
+}
+
+func TestBoltCommentStorageConformance(t *testing.T) {
+	storagetest.RunCommentConformanceTests(t, func(t *testing.T) storage.CommentStorage {
+		return newBoltStorage(t)
+	})
+}
+
+func TestBoltReactionStorageConformance(t *testing.T) {
+	storagetest.RunReactionConformanceTests(t, func(t *testing.T) storage.ReactionStorage {
+		return newBoltStorage(t)
+	})
+}
+
+func TestBoltViewStorageConformance(t *testing.T) {
+	storagetest.RunViewConformanceTests(t, func(t *testing.T) storage.ViewStorage {
+		return newBoltStorage(t)
+	})
+}
+
+//putLegacyBoltArticle stores an article the way the first BoltStorage release wrote it, JSON
+//without a version or status indexed by user, taking the next id of the articles bucket
+func putLegacyBoltArticle(t *testing.T, path string, userID int, title string) int {
+	t.Helper()
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open returned %v", err)
+	}
+	defer db.Close()
+	var id int
+	err = db.Update(func(tx *bolt.Tx) error {
+		articles, err := tx.CreateBucketIfNotExists([]byte("articles"))
+		if err != nil {
+			return err
+		}
+		users, err := tx.CreateBucketIfNotExists([]byte("userArticles"))
+		if err != nil {
+			return err
+		}
+		seq, err := articles.NextSequence()
+		if err != nil {
+			return err
+		}
+		id = int(seq)
+		v := fmt.Sprintf(`{"userID":%v,"articleID":%v,"title":%q,"body":"body"}`, userID, id, title)
+		if err := articles.Put(key(id), []byte(v)); err != nil {
+			return err
+		}
+		return users.Put(append(key(userID), key(id)...), []byte{})
+	})
+	if err != nil {
+		t.Fatalf("storing legacy article returned %v", err)
+	}
+	return id
+}
+
+func key(n int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(n))
+	return b
+}
+
+func TestBoltStorageReadsLegacyArticles(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "articles.bolt")
+	id := putLegacyBoltArticle(t, path, 7, "legacy")
+
+	b, err := storage.NewBoltStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltStorage over a legacy file returned %v", err)
+	}
+	defer b.Close()
+
+	a, err := b.GetArticleByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetArticleByID on legacy article returned %v", err)
+	}
+	if a.Version != 1 || a.Status != models.StatusPublished {
+		t.Fatalf("legacy article read at version %v with status %q, want version 1 published", a.Version, a.Status)
+	}
+	page, err := b.ListArticles(ctx, storage.PageQuery{Status: models.StatusPublished, Limit: 10})
+	if err != nil || len(page.Articles) != 1 {
+		t.Fatalf("ListArticles of published legacy articles returned %+v, %v", page, err)
+	}
+	a.Title = "updated"
+	a.Status = models.StatusArchived
+	if err := b.UpdateArticle(ctx, id, a); err != nil {
+		t.Fatalf("UpdateArticle at version 1 of legacy article returned %v", err)
+	}
+	if a, err = b.GetArticleByID(ctx, id); err != nil || a.Version != 2 || a.Status != models.StatusArchived {
+		t.Fatalf("legacy article after update is %+v, %v", a, err)
+	}
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	commentsBucket       = []byte("comments")
+	commentThreadsBucket = []byte("commentThreads")
+)
+
+//GetComment returns a comment given an id
+func (b *BoltStorage) GetComment(ctx context.Context, id int) (models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Comment{}, err
+	}
+	var c models.Comment
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		c, err = getComment(tx, id)
+		return err
+	})
+	return c, err
+}
+
+//ListComments returns a page of the comments of an article replying to the parent of the query
+//by walking the thread bucket from just past AfterID
+func (b *BoltStorage) ListComments(ctx context.Context, q CommentQuery) (CommentPage, error) {
+	if err := ctx.Err(); err != nil {
+		return CommentPage{}, err
+	}
+	var comments []models.Comment
+	err := b.db.View(func(tx *bolt.Tx) error {
+		prefix := threadPrefix(q.ArticleID, q.ParentID)
+		start := threadKey(models.Comment{ArticleID: q.ArticleID, ParentID: q.ParentID, CommentID: q.AfterID + 1})
+		c := tx.Bucket(commentThreadsBucket).Cursor()
+		for k, _ := c.Seek(start); k != nil && bytes.HasPrefix(k, prefix) && len(comments) <= q.Limit; k, _ = c.Next() {
+			cm, err := getComment(tx, btoi(k[16:]))
+			if err != nil {
+				return err
+			}
+			comments = append(comments, cm)
+		}
+		return nil
+	})
+	if err != nil {
+		return CommentPage{}, err
+	}
+	if len(comments) > q.Limit {
+		return CommentPage{Comments: comments[:q.Limit], More: true}, nil
+	}
+	return CommentPage{Comments: comments}, nil
+}
+
+//CreateComment stores a new comment under the next id of the comments bucket sequence, counting
+//it as a reply of its parent in the same transaction
+func (b *BoltStorage) CreateComment(ctx context.Context, c models.Comment) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		if c.ParentID != 0 {
+			parent, err := getComment(tx, c.ParentID)
+			if err != nil {
+				return err
+			}
+			if parent.ArticleID != c.ArticleID {
+				return ErrResourceNotFound
+			}
+			parent.Replies++
+			if err := putComment(tx, parent); err != nil {
+				return err
+			}
+		}
+		seq, err := tx.Bucket(commentsBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		c.CommentID, c.Replies = int(seq), 0
+		if err := putComment(tx, c); err != nil {
+			return err
+		}
+		return tx.Bucket(commentThreadsBucket).Put(threadKey(c), []byte{})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return c.CommentID, nil
+}
+
+//UpdateComment replaces the body, deleted flag and update time of an existing comment, the
+//article and parent it belongs to cannot change
+func (b *BoltStorage) UpdateComment(ctx context.Context, c models.Comment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		old, err := getComment(tx, c.CommentID)
+		if err != nil {
+			return err
+		}
+		old.Body, old.Deleted, old.UpdatedAt = c.Body, c.Deleted, c.UpdatedAt
+		return putComment(tx, old)
+	})
+}
+
+//DeleteComment removes a comment nothing replies to and takes it off its parent's replies
+func (b *BoltStorage) DeleteComment(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		c, err := getComment(tx, id)
+		if err != nil {
+			return err
+		}
+		if c.Replies > 0 {
+			return ErrCommentHasReplies
+		}
+		if c.ParentID != 0 {
+			parent, err := getComment(tx, c.ParentID)
+			if err != nil {
+				return err
+			}
+			parent.Replies--
+			if err := putComment(tx, parent); err != nil {
+				return err
+			}
+		}
+		return deleteComment(tx, c)
+	})
+}
+
+//DeleteArticleComments removes every comment on an article, found under the article's prefix of
+//the thread bucket, and returns how many there were
+func (b *BoltStorage) DeleteArticleComments(ctx context.Context, articleID int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	n := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		for _, k := range prefixedKeys(tx, commentThreadsBucket, itob(articleID)) {
+			c, err := getComment(tx, btoi(k[16:]))
+			if err != nil {
+				return err
+			}
+			if err := deleteComment(tx, c); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+func getComment(tx *bolt.Tx, id int) (models.Comment, error) {
+	v := tx.Bucket(commentsBucket).Get(itob(id))
+	if v == nil {
+		return models.Comment{}, ErrResourceNotFound
+	}
+	var c models.Comment
+	err := json.Unmarshal(v, &c)
+	return c, err
+}
+
+//putComment stores a comment as JSON keyed by id, its number of replies included
+func putComment(tx *bolt.Tx, c models.Comment) error {
+	v, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(commentsBucket).Put(itob(c.CommentID), v)
+}
+
+//deleteComment removes a comment and its thread entry
+func deleteComment(tx *bolt.Tx, c models.Comment) error {
+	if err := tx.Bucket(commentThreadsBucket).Delete(threadKey(c)); err != nil {
+		return err
+	}
+	return tx.Bucket(commentsBucket).Delete(itob(c.CommentID))
+}
+
+//threadKey builds the thread key articleID|parentID|commentID so the replies to a comment, or
+//the top level comments of an article under parent 0, share a prefix in creation order
+func threadKey(c models.Comment) []byte {
+	return append(threadPrefix(c.ArticleID, c.ParentID), itob(c.CommentID)...)
+}
+
+func threadPrefix(articleID, parentID int) []byte {
+	return append(itob(articleID), itob(parentID)...)
+}
@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	//CommentThreadIndex is the name of the global secondary index of the comments table listing
+	//the replies to a comment, or the top level comments of an article, sorted by commentID
+	CommentThreadIndex = "thread-index"
+	//CommentArticleIndex is the name of the global secondary index of the comments table listing
+	//every comment on an article
+	CommentArticleIndex = "commentArticleID-index"
+
+	//commentsTableSuffix is appended to the articles table name to name the table holding
+	//comments, keyed by commentID
+	commentsTableSuffix = "Comments"
+)
+
+//createCommentsTable creates the comments table with its indexes. Its id sequence lives in the
+//item of the reserved commentID 0, which has no thread and stays out of both indexes
+func (d *DynamoStorage) createCommentsTable(ctx context.Context) error {
+	_, err := d.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(d.commentsTable),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("commentID"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("thread"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("articleID"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("commentID"), KeyType: types.KeyTypeHash},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String(CommentThreadIndex),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("thread"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("commentID"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+			{
+				IndexName: aws.String(CommentArticleIndex),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("commentID"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+			},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	return ignoreInUse(err)
+}
+
+//GetComment returns a comment given an id
+func (d *DynamoStorage) GetComment(ctx context.Context, id int) (models.Comment, error) {
+	if id == counterID {
+		return models.Comment{}, ErrResourceNotFound
+	}
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.commentsTable),
+		Key:            commentKey(id),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if len(out.Item) == 0 {
+		return models.Comment{}, ErrResourceNotFound
+	}
+	return itemToComment(out.Item)
+}
+
+//ListComments returns a page of the comments of an article replying to the parent of the query
+//by querying the thread index from just past AfterID
+func (d *DynamoStorage) ListComments(ctx context.Context, q CommentQuery) (CommentPage, error) {
+	var (
+		comments []models.Comment
+		startKey map[string]types.AttributeValue
+	)
+	for len(comments) <= q.Limit {
+		out, err := d.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(d.commentsTable),
+			IndexName:              aws.String(CommentThreadIndex),
+			KeyConditionExpression: aws.String("thread = :thread AND commentID > :after"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":thread": threadValue(q.ArticleID, q.ParentID),
+				":after":  numberValue(q.AfterID),
+			},
+			Limit:             aws.Int32(int32(q.Limit + 1 - len(comments))),
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return CommentPage{}, err
+		}
+		for _, item := range out.Items {
+			c, err := itemToComment(item)
+			if err != nil {
+				return CommentPage{}, err
+			}
+			comments = append(comments, c)
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = out.LastEvaluatedKey
+	}
+	if len(comments) > q.Limit {
+		return CommentPage{Comments: comments[:q.Limit], More: true}, nil
+	}
+	return CommentPage{Comments: comments}, nil
+}
+
+//CreateComment stores a new comment under the next id of the comments table sequence. A reply
+//is counted on its parent in the same transaction, whose condition checks the parent is on the
+//same article
+func (d *DynamoStorage) CreateComment(ctx context.Context, c models.Comment) (int, error) {
+	id, err := d.nextSeq(ctx, d.commentsTable, commentKey(counterID))
+	if err != nil {
+		return 0, err
+	}
+	c.CommentID, c.Replies = id, 0
+	items := []types.TransactWriteItem{{Put: &types.Put{
+		TableName:           aws.String(d.commentsTable),
+		Item:                commentToItem(c),
+		ConditionExpression: aws.String("attribute_not_exists(commentID)"),
+	}}}
+	if c.ParentID != 0 {
+		items = append(items, types.TransactWriteItem{Update: &types.Update{
+			TableName:                 aws.String(d.commentsTable),
+			Key:                       commentKey(c.ParentID),
+			UpdateExpression:          aws.String("ADD replies :one"),
+			ConditionExpression:       aws.String("articleID = :articleID"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":one": numberValue(1), ":articleID": numberValue(c.ArticleID)},
+		}})
+	}
+	_, err = d.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if failed, _ := canceledAt(err); len(failed) > 1 && failed[1] {
+		return 0, ErrResourceNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+//UpdateComment replaces the body, deleted flag and update time of an existing comment, the
+//article and parent it belongs to cannot change
+func (d *DynamoStorage) UpdateComment(ctx context.Context, c models.Comment) error {
+	_, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(d.commentsTable),
+		Key:                 commentKey(c.CommentID),
+		UpdateExpression:    aws.String("SET body = :body, deleted = :deleted, updatedAt = :updatedAt"),
+		ConditionExpression: aws.String("attribute_exists(thread)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":body":      &types.AttributeValueMemberS{Value: c.Body},
+			":deleted":   &types.AttributeValueMemberBOOL{Value: c.Deleted},
+			":updatedAt": timeValue(c.UpdatedAt),
+		},
+	})
+	return translateConditionErr(err)
+}
+
+//DeleteComment removes a comment nothing replies to and takes it off its parent's replies in one
+//transaction, whose condition rechecks the replies the comment was read with
+func (d *DynamoStorage) DeleteComment(ctx context.Context, id int) error {
+	c, err := d.GetComment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if c.Replies > 0 {
+		return ErrCommentHasReplies
+	}
+	items := []types.TransactWriteItem{{Delete: &types.Delete{
+		TableName:                           aws.String(d.commentsTable),
+		Key:                                 commentKey(id),
+		ConditionExpression:                 aws.String("attribute_exists(thread) AND (attribute_not_exists(replies) OR replies = :zero)"),
+		ExpressionAttributeValues:           map[string]types.AttributeValue{":zero": numberValue(0)},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}}}
+	if c.ParentID != 0 {
+		items = append(items, types.TransactWriteItem{Update: &types.Update{
+			TableName:                 aws.String(d.commentsTable),
+			Key:                       commentKey(c.ParentID),
+			UpdateExpression:          aws.String("ADD replies :minus"),
+			ConditionExpression:       aws.String("attribute_exists(thread)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":minus": numberValue(-1)},
+		}})
+	}
+	_, err = d.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	failed, reasons := canceledAt(err)
+	switch {
+	case len(failed) > 0 && failed[0] && len(reasons[0].Item) > 0:
+		return ErrCommentHasReplies
+	case len(failed) > 0:
+		//The comment, or the whole thread along with its article, is gone
+		return ErrResourceNotFound
+	}
+	return err
+}
+
+//DeleteArticleComments removes every comment on an article, found through the article index of
+//the comments table, and returns how many there were
+func (d *DynamoStorage) DeleteArticleComments(ctx context.Context, articleID int) (int, error) {
+	var (
+		n        int
+		startKey map[string]types.AttributeValue
+	)
+	for {
+		out, err := d.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(d.commentsTable),
+			IndexName:                 aws.String(CommentArticleIndex),
+			KeyConditionExpression:    aws.String("articleID = :id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":id": numberValue(articleID)},
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return n, err
+		}
+		for _, item := range out.Items {
+			id, err := numberAttr(item, "commentID")
+			if err != nil {
+				return n, err
+			}
+			if _, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(d.commentsTable),
+				Key:       commentKey(id),
+			}); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return n, nil
+		}
+		startKey = out.LastEvaluatedKey
+	}
+}
+
+//canceledAt reports which writes of a canceled transaction failed their condition along with
+//the cancellation reasons, both nil when err is not a cancellation
+func canceledAt(err error) ([]bool, []types.CancellationReason) {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return nil, nil
+	}
+	failed := make([]bool, len(canceled.CancellationReasons))
+	for i, r := range canceled.CancellationReasons {
+		failed[i] = aws.ToString(r.Code) == "ConditionalCheckFailed"
+	}
+	return failed, canceled.CancellationReasons
+}
+
+func commentKey(id int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"commentID": numberValue(id)}
+}
+
+//threadValue names the thread of the comments replying to parentID on an article, parentID 0
+//naming its top level comments
+func threadValue(articleID, parentID int) *types.AttributeValueMemberS {
+	return &types.AttributeValueMemberS{Value: fmt.Sprintf("%d#%d", articleID, parentID)}
+}
+
+func commentToItem(c models.Comment) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"commentID": numberValue(c.CommentID),
+		"articleID": numberValue(c.ArticleID),
+		"parentID":  numberValue(c.ParentID),
+		"thread":    threadValue(c.ArticleID, c.ParentID),
+		"userID":    numberValue(c.UserID),
+		"body":      &types.AttributeValueMemberS{Value: c.Body},
+		"deleted":   &types.AttributeValueMemberBOOL{Value: c.Deleted},
+		"replies":   numberValue(c.Replies),
+		"createdAt": timeValue(c.CreatedAt),
+		"updatedAt": timeValue(c.UpdatedAt),
+	}
+}
+
+func itemToComment(item map[string]types.AttributeValue) (models.Comment, error) {
+	var (
+		c   models.Comment
+		err error
+	)
+	for name, n := range map[string]*int{"commentID": &c.CommentID, "articleID": &c.ArticleID, "parentID": &c.ParentID, "userID": &c.UserID, "replies": &c.Replies} {
+		if *n, err = numberAttr(item, name); err != nil {
+			return models.Comment{}, err
+		}
+	}
+	for name, t := range map[string]*time.Time{"createdAt": &c.CreatedAt, "updatedAt": &c.UpdatedAt} {
+		if *t, err = timeAttr(item, name); err != nil {
+			return models.Comment{}, err
+		}
+	}
+	c.Body = stringAttr(item, "body")
+	if v, ok := item["deleted"].(*types.AttributeValueMemberBOOL); ok {
+		c.Deleted = v.Value
+	}
+	return c, nil
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+)
+
+//MemoryCommentStorage keeps comments in memory, along with the number of replies to each of
+//them. It is safe for concurrent use and each instance issues its own id sequence
+type MemoryCommentStorage struct {
+	mu        sync.RWMutex
+	comments  map[int]models.Comment
+	replies   map[int]int
+	idCounter int
+}
+
+//NewMemoryCommentStorage creates a MemoryCommentStorage without any comments
+func NewMemoryCommentStorage() *MemoryCommentStorage {
+	return &MemoryCommentStorage{
+		comments:  make(map[int]models.Comment),
+		replies:   make(map[int]int),
+		idCounter: 1,
+	}
+}
+
+//GetComment returns a comment given an id
+func (m *MemoryCommentStorage) GetComment(ctx context.Context, id int) (models.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Comment{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.get(id)
+}
+
+//ListComments returns a page of the comments of an article replying to the parent of the query
+//ordered by id
+func (m *MemoryCommentStorage) ListComments(ctx context.Context, q CommentQuery) (CommentPage, error) {
+	if err := ctx.Err(); err != nil {
+		return CommentPage{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var comments []models.Comment
+	for _, c := range m.comments {
+		if c.ArticleID == q.ArticleID && c.ParentID == q.ParentID && c.CommentID > q.AfterID {
+			c.Replies = m.replies[c.CommentID]
+			comments = append(comments, c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].CommentID < comments[j].CommentID })
+	if len(comments) > q.Limit {
+		return CommentPage{Comments: comments[:q.Limit], More: true}, nil
+	}
+	return CommentPage{Comments: comments}, nil
+}
+
+//CreateComment stores a new comment under the next id, checking the parent it replies to is on
+//the same article
+func (m *MemoryCommentStorage) CreateComment(ctx context.Context, c models.Comment) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c.ParentID != 0 {
+		parent, ok := m.comments[c.ParentID]
+		if !ok || parent.ArticleID != c.ArticleID {
+			return 0, ErrResourceNotFound
+		}
+		m.replies[c.ParentID]++
+	}
+	c.CommentID, c.Replies = m.idCounter, 0
+	m.idCounter++
+	m.comments[c.CommentID] = c
+	return c.CommentID, nil
+}
+
+//UpdateComment replaces the body, deleted flag and update time of an existing comment, the
+//article and parent it belongs to cannot change
+func (m *MemoryCommentStorage) UpdateComment(ctx context.Context, c models.Comment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.comments[c.CommentID]
+	if !ok {
+		return ErrResourceNotFound
+	}
+	old.Body, old.Deleted, old.UpdatedAt = c.Body, c.Deleted, c.UpdatedAt
+	m.comments[c.CommentID] = old
+	return nil
+}
+
+//DeleteComment removes a comment nothing replies to
+func (m *MemoryCommentStorage) DeleteComment(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.comments[id]
+	if !ok {
+		return ErrResourceNotFound
+	}
+	if m.replies[id] > 0 {
+		return ErrCommentHasReplies
+	}
+	m.remove(c)
+	return nil
+}
+
+//DeleteArticleComments removes every comment on an article and returns how many there were
+func (m *MemoryCommentStorage) DeleteArticleComments(ctx context.Context, articleID int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, c := range m.comments {
+		if c.ArticleID == articleID {
+			m.remove(c)
+			n++
+		}
+	}
+	return n, nil
+}
+
+//get looks up a comment by id, callers must hold mu
+func (m *MemoryCommentStorage) get(id int) (models.Comment, error) {
+	c, ok := m.comments[id]
+	if !ok {
+		return models.Comment{}, ErrResourceNotFound
+	}
+	c.Replies = m.replies[id]
+	return c, nil
+}
+
+//remove drops a comment and its reply count and takes it off its parent's, callers must hold mu
+func (m *MemoryCommentStorage) remove(c models.Comment) {
+	delete(m.comments, c.CommentID)
+	delete(m.replies, c.CommentID)
+	if c.ParentID == 0 {
+		return
+	}
+	if m.replies[c.ParentID]--; m.replies[c.ParentID] <= 0 {
+		delete(m.replies, c.ParentID)
+	}
+}
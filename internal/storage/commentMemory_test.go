@@ -0,0 +1,14 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/storage"
+	"github.com/Perezonance/article-management-service/internal/storage/storagetest"
+)
+
+func TestMemoryCommentStorageConformance(t *testing.T) {
+	storagetest.RunCommentConformanceTests(t, func(t *testing.T) storage.CommentStorage {
+		return storage.NewMemoryCommentStorage()
+	})
+}
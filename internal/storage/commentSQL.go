@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+)
+
+//commentColumns lists the columns of a comment row in the order scanComment reads them
+const commentColumns = `comment_id, article_id, parent_id, user_id, body, deleted, replies, created_at, updated_at`
+
+//GetComment returns a comment given an id
+func (s *SQLStorage) GetComment(ctx context.Context, id int) (models.Comment, error) {
+	row := s.db.QueryRowContext(ctx, s.rebind(`SELECT `+commentColumns+` FROM article_comments WHERE comment_id = ?`), id)
+
+	c, err := scanComment(row)
+	if err == sql.ErrNoRows {
+		return models.Comment{}, ErrResourceNotFound
+	}
+	return c, err
+}
+
+//ListComments returns a page of the comments of an article replying to the parent of the query
+//ordered by id, fetching one extra row to learn whether another page follows
+func (s *SQLStorage) ListComments(ctx context.Context, q CommentQuery) (CommentPage, error) {
+	rows, err := s.db.QueryContext(ctx,
+		s.rebind(`SELECT `+commentColumns+` FROM article_comments WHERE article_id = ? AND parent_id = ? AND comment_id > ? ORDER BY comment_id LIMIT ?`),
+		q.ArticleID, q.ParentID, q.AfterID, q.Limit+1,
+	)
+	if err != nil {
+		return CommentPage{}, err
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return CommentPage{}, err
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return CommentPage{}, err
+	}
+	if len(comments) > q.Limit {
+		return CommentPage{Comments: comments[:q.Limit], More: true}, nil
+	}
+	return CommentPage{Comments: comments}, nil
+}
+
+//CreateComment inserts a new comment and returns the id issued by the database. A reply is
+//counted on its parent first, which also checks the parent is on the same article and holds
+//the parent's row until the reply is in
+func (s *SQLStorage) CreateComment(ctx context.Context, c models.Comment) (int, error) {
+	var id int
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if c.ParentID != 0 {
+			res, err := tx.ExecContext(ctx, s.rebind(`UPDATE article_comments SET replies = replies + 1 WHERE comment_id = ? AND article_id = ?`), c.ParentID, c.ArticleID)
+			if err != nil {
+				return err
+			}
+			if n, err := res.RowsAffected(); err != nil || n == 0 {
+				return orNotFound(err)
+			}
+		}
+		return tx.QueryRowContext(ctx,
+			s.rebind(`INSERT INTO article_comments (article_id, parent_id, user_id, body, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING comment_id`),
+			c.ArticleID, c.ParentID, c.UserID, c.Body, c.CreatedAt.UTC(), c.UpdatedAt.UTC(),
+		).Scan(&id)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+//UpdateComment replaces the body, deleted flag and update time of an existing comment, the
+//article and parent it belongs to cannot change
+func (s *SQLStorage) UpdateComment(ctx context.Context, c models.Comment) error {
+	res, err := s.db.ExecContext(ctx,
+		s.rebind(`UPDATE article_comments SET body = ?, deleted = ?, updated_at = ? WHERE comment_id = ?`),
+		c.Body, c.Deleted, c.UpdatedAt.UTC(), c.CommentID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return orNotFound(err)
+	}
+	return nil
+}
+
+//DeleteComment removes a comment nothing replies to and takes it off its parent's replies
+func (s *SQLStorage) DeleteComment(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		var parentID int
+		err := tx.QueryRowContext(ctx, s.rebind(`DELETE FROM article_comments WHERE comment_id = ? AND replies = 0 RETURNING parent_id`), id).Scan(&parentID)
+		if err == sql.ErrNoRows {
+			var exists int
+			err = tx.QueryRowContext(ctx, s.rebind(`SELECT 1 FROM article_comments WHERE comment_id = ?`), id).Scan(&exists)
+			if err == nil {
+				return ErrCommentHasReplies
+			}
+			return orNotFound(err)
+		}
+		if err != nil || parentID == 0 {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, s.rebind(`UPDATE article_comments SET replies = replies - 1 WHERE comment_id = ?`), parentID)
+		return err
+	})
+}
+
+//DeleteArticleComments removes every comment on an article and returns how many there were
+func (s *SQLStorage) DeleteArticleComments(ctx context.Context, articleID int) (int, error) {
+	res, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM article_comments WHERE article_id = ?`), articleID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+//scanComment reads a row selected with commentColumns
+func scanComment(row interface{ Scan(...interface{}) error }) (models.Comment, error) {
+	var c models.Comment
+	err := row.Scan(&c.CommentID, &c.ArticleID, &c.ParentID, &c.UserID, &c.Body, &c.Deleted, &c.Replies, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	c.CreatedAt, c.UpdatedAt = c.CreatedAt.UTC(), c.UpdatedAt.UTC()
+	return c, nil
+}
+
+//orNotFound reports ErrResourceNotFound in place of a missing error or a missing row
+func orNotFound(err error) error {
+	if err == nil || err == sql.ErrNoRows {
+		return ErrResourceNotFound
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+)
+
+//CommentStorage defines the behavior for a store of comments, kept alongside Storage. Comments
+//are numbered by the store and listed in the order they were created. CreateComment fails with
+//ErrResourceNotFound when the parent it replies to is missing or on another article, and
+//DeleteComment fails with ErrCommentHasReplies while other comments reply to the comment so that
+//no reply is left without its parent. Reads fill in each comment's number of direct Replies.
+//The store does not know about articles, callers check the article exists and remove its
+//comments with DeleteArticleComments once it is deleted
+type CommentStorage interface {
+	GetComment(context.Context, int) (models.Comment, error)
+	ListComments(context.Context, CommentQuery) (CommentPage, error)
+	CreateComment(context.Context, models.Comment) (int, error)
+	UpdateComment(context.Context, models.Comment) error
+	DeleteComment(context.Context, int) error
+	DeleteArticleComments(context.Context, int) (int, error)
+}
+
+//CommentQuery selects one page of the comments of an article replying to the same parent, 0
+//selecting the top level comments
+type CommentQuery struct {
+	ArticleID int
+	ParentID  int
+	//AfterID resumes the listing after the comment with that id, 0 starts from the beginning
+	AfterID int
+	//Limit is the maximum number of comments in the page
+	Limit int
+}
+
+//CommentPage is one page of a comment listing
+type CommentPage struct {
+	Comments []models.Comment
+	//More reports whether further comments follow the last one in Comments
+	More bool
+}
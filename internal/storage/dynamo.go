@@ -0,0 +1,1575 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	//DefaultArticlesTable is the table name used when none is configured
+	DefaultArticlesTable = "Articles"
+	//UserIDIndex is the name of the global secondary index on userID sorted by articleID
+	UserIDIndex = "userID-index"
+	//ArticleIDIndex is the name of the global secondary index that lists every article sorted
+	//by articleID under the single itemType partition
+	ArticleIDIndex = "articleID-index"
+	//CreatedAtIndex, UpdatedAtIndex and TitleIndex list every article under the itemType
+	//partition sorted by a key made of the sort value followed by the articleID
+	CreatedAtIndex = "createdAt-index"
+	UpdatedAtIndex = "updatedAt-index"
+	TitleIndex     = "title-index"
+	//TagIndex is the name of the global secondary index of the tags table listing the articles
+	//carrying a tag sorted by articleID
+	TagIndex = "tag-index"
+	//SlugArticleIndex is the name of the global secondary index of the slugs table listing the
+	//slugs held by an article
+	SlugArticleIndex = "slugArticleID-index"
+
+	//articleItemType is the itemType of article items, the id sequence item has none so it
+	//stays out of ArticleIDIndex
+	articleItemType = "article"
+
+	//counterID is the reserved articleID of the item holding the id sequence and of the tag
+	//counter items
+	counterID = 0
+	//tagListTag is the reserved tag of the item listing every tag of an article in the tags
+	//table, tags cannot contain a slash so it never names a real one
+	tagListTag = "/"
+
+	//indexPollInterval is how often the status of an index being built is checked
+	indexPollInterval = 5 * time.Second
+
+	//timeFormat is a fixed width UTC layout so that time attributes compare in order as strings
+	timeFormat = "2006-01-02T15:04:05.000000000Z"
+
+	//revisionsTableSuffix is appended to the articles table name to name the table holding
+	//revisions, keyed by articleID and version
+	revisionsTableSuffix = "Revisions"
+	//tagsTableSuffix is appended to the articles table name to name the table relating articles
+	//to their tags
+	tagsTableSuffix = "Tags"
+	//slugsTableSuffix is appended to the articles table name to name the table mapping every
+	//slug to the article holding it
+	slugsTableSuffix = "Slugs"
+)
+
+//dynamoSortIndex names the index holding a sort order and the attribute it is sorted by
+type dynamoSortIndex struct {
+	index, key string
+}
+
+var dynamoSortIndexes = map[string]dynamoSortIndex{
+	SortByCreatedAt: {CreatedAtIndex, "createdAtKey"},
+	SortByUpdatedAt: {UpdatedAtIndex, "updatedAtKey"},
+	SortByTitle:     {TitleIndex, "titleKey"},
+}
+
+//DynamoAPI defines the subset of the DynamoDB client used by DynamoStorage so that a
+//fake client can be substituted in tests
+type DynamoAPI interface {
+	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	BatchGetItem(context.Context, *dynamodb.BatchGetItemInput, ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Query(context.Context, *dynamodb.QueryInput, ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	TransactWriteItems(context.Context, *dynamodb.TransactWriteItemsInput, ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	Scan(context.Context, *dynamodb.ScanInput, ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	CreateTable(context.Context, *dynamodb.CreateTableInput, ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	DescribeTable(context.Context, *dynamodb.DescribeTableInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	UpdateTable(context.Context, *dynamodb.UpdateTableInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error)
+}
+
+//DynamoStorage stores articles in a DynamoDB table keyed by articleID with a global
+//secondary index on userID, their revisions in a companion table named by appending
+//Revisions to it, their tags in one named by appending Tags, keyed by articleID and tag, with a
+//list of the tags of each article and the counts of each tag under the reserved articleID, their
+//slugs in one named by appending
+//Slugs, keyed by slug, comments in one named by appending Comments, keyed by commentID,
+//reactions in one named by appending Reactions, keyed by articleID and reaction, and view
+//counts in one named by appending Views, keyed by articleID and hour
+type DynamoStorage struct {
+	client         DynamoAPI
+	table          string
+	revisionsTable string
+	tagsTable      string
+	slugsTable     string
+	commentsTable  string
+	reactionsTable string
+	viewsTable     string
+}
+
+//NewDynamoStorage creates a DynamoStorage for the given client and table name
+func NewDynamoStorage(client DynamoAPI, table string) *DynamoStorage {
+	if table == "" {
+		table = DefaultArticlesTable
+	}
+	return &DynamoStorage{
+		client:         client,
+		table:          table,
+		revisionsTable: table + revisionsTableSuffix,
+		tagsTable:      table + tagsTableSuffix,
+		slugsTable:     table + slugsTableSuffix,
+		commentsTable:  table + commentsTableSuffix,
+		reactionsTable: table + reactionsTableSuffix,
+		viewsTable:     table + viewsTableSuffix,
+	}
+}
+
+//CreateTable creates the articles table with its indexes and the revisions, tags, slugs,
+//comments, reactions and views tables if they do not exist yet. An articles table created by an
+//older release gets the indexes it lacks added, though articles stored back then only appear in
+//them once Backfill has run
+func (d *DynamoStorage) CreateTable(ctx context.Context) error {
+	if err := d.createArticlesTable(ctx); err != nil {
+		return err
+	}
+	_, err := d.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(d.revisionsTable),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("articleID"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("version"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("version"), KeyType: types.KeyTypeRange},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	if err := ignoreInUse(err); err != nil {
+		return err
+	}
+	_, err = d.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(d.tagsTable),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("articleID"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("tag"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("tag"), KeyType: types.KeyTypeRange},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String(TagIndex),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("tag"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+			},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	if err := ignoreInUse(err); err != nil {
+		return err
+	}
+	_, err = d.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(d.slugsTable),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("slug"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("articleID"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("slug"), KeyType: types.KeyTypeHash},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String(SlugArticleIndex),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("slug"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+			},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	if err := ignoreInUse(err); err != nil {
+		return err
+	}
+	if err := d.createCommentsTable(ctx); err != nil {
+		return err
+	}
+	if err := d.createReactionsTable(ctx); err != nil {
+		return err
+	}
+	return d.createViewsTable(ctx)
+}
+
+func (d *DynamoStorage) createArticlesTable(ctx context.Context) error {
+	in := &dynamodb.CreateTableInput{
+		TableName: aws.String(d.table),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("articleID"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("userID"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("itemType"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("createdAtKey"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("updatedAtKey"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("titleKey"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeHash},
+		},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String(UserIDIndex),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("userID"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+			{
+				IndexName: aws.String(ArticleIDIndex),
+				KeySchema: []types.KeySchemaElement{
+					{AttributeName: aws.String("itemType"), KeyType: types.KeyTypeHash},
+					{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeRange},
+				},
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+			},
+			sortIndex(dynamoSortIndexes[SortByCreatedAt]),
+			sortIndex(dynamoSortIndexes[SortByUpdatedAt]),
+			sortIndex(dynamoSortIndexes[SortByTitle]),
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	}
+	_, err := d.client.CreateTable(ctx, in)
+	var inUse *types.ResourceInUseException
+	if errors.As(err, &inUse) {
+		return d.addMissingIndexes(ctx, in)
+	}
+	return err
+}
+
+//addMissingIndexes adds to the existing articles table the global secondary indexes of in that
+//it lacks. DynamoDB builds one index at a time, so each is waited on before the next is added
+func (d *DynamoStorage) addMissingIndexes(ctx context.Context, in *dynamodb.CreateTableInput) error {
+	out, err := d.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: in.TableName})
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, gsi := range out.Table.GlobalSecondaryIndexes {
+		existing[aws.ToString(gsi.IndexName)] = true
+	}
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		if existing[aws.ToString(gsi.IndexName)] {
+			continue
+		}
+		//Only the key attributes of the new index may be defined alongside it
+		var defs []types.AttributeDefinition
+		for _, def := range in.AttributeDefinitions {
+			for _, k := range gsi.KeySchema {
+				if aws.ToString(k.AttributeName) == aws.ToString(def.AttributeName) {
+					defs = append(defs, def)
+				}
+			}
+		}
+		_, err := d.client.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+			TableName:            in.TableName,
+			AttributeDefinitions: defs,
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{{Create: &types.CreateGlobalSecondaryIndexAction{
+				IndexName:  gsi.IndexName,
+				KeySchema:  gsi.KeySchema,
+				Projection: gsi.Projection,
+			}}},
+		})
+		if err != nil {
+			return err
+		}
+		if err := d.waitForIndex(ctx, aws.ToString(gsi.IndexName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//waitForIndex blocks until the named index of the articles table is active
+func (d *DynamoStorage) waitForIndex(ctx context.Context, name string) error {
+	for {
+		out, err := d.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(d.table)})
+		if err != nil {
+			return err
+		}
+		for _, gsi := range out.Table.GlobalSecondaryIndexes {
+			if aws.ToString(gsi.IndexName) == name && gsi.IndexStatus == types.IndexStatusActive {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(indexPollInterval):
+		}
+	}
+}
+
+//Backfill rewrites the article items stored by older releases that lack attributes added since,
+//so that they carry a version, a status and the keys of the listing indexes, and stores their
+//current revision. Items written again meanwhile are left to that write. It returns the number
+//of items rewritten
+func (d *DynamoStorage) Backfill(ctx context.Context) (int, error) {
+	var (
+		n        int
+		startKey map[string]types.AttributeValue
+	)
+	for {
+		out, err := d.client.Scan(ctx, &dynamodb.ScanInput{
+			TableName: aws.String(d.table),
+			FilterExpression: aws.String("articleID <> :counter AND (attribute_not_exists(version) OR attribute_not_exists(#status)" +
+				" OR attribute_not_exists(itemType) OR attribute_not_exists(createdAtKey))"),
+			ExpressionAttributeNames:  map[string]string{"#status": "status"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":counter": numberValue(counterID)},
+			ConsistentRead:            aws.Bool(true),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return n, err
+		}
+		for _, item := range out.Items {
+			a, err := itemToArticle(item)
+			if err != nil {
+				return n, err
+			}
+			backfilled := articleToItem(a)
+			if tagWrites, ok := item["tagWrites"]; ok {
+				backfilled["tagWrites"] = tagWrites
+			}
+			values := map[string]types.AttributeValue{}
+			_, err = d.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{Put: &types.Put{
+						TableName:                 aws.String(d.table),
+						Item:                      backfilled,
+						ConditionExpression:       versionCondition(a.Version, values),
+						ExpressionAttributeValues: values,
+					}},
+					{Put: &types.Put{
+						TableName: aws.String(d.revisionsTable),
+						Item:      articleToItem(a),
+					}},
+				},
+			})
+			var canceled *types.TransactionCanceledException
+			if errors.As(err, &canceled) {
+				continue
+			}
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return n, nil
+		}
+		startKey = out.LastEvaluatedKey
+	}
+}
+
+func sortIndex(s dynamoSortIndex) types.GlobalSecondaryIndex {
+	return types.GlobalSecondaryIndex{
+		IndexName: aws.String(s.index),
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("itemType"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String(s.key), KeyType: types.KeyTypeRange},
+		},
+		Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+	}
+}
+
+//ignoreInUse treats creating a table that already exists as success
+func ignoreInUse(err error) error {
+	var inUse *types.ResourceInUseException
+	if errors.As(err, &inUse) {
+		return nil
+	}
+	return err
+}
+
+//GetArticleByID returns an article given an id
+func (d *DynamoStorage) GetArticleByID(ctx context.Context, id int) (models.Article, error) {
+	if id == counterID {
+		return models.Article{}, ErrResourceNotFound
+	}
+	item, err := d.articleItem(ctx, id)
+	if err != nil {
+		return models.Article{}, err
+	}
+	a, err := itemToArticle(item)
+	if err != nil {
+		return models.Article{}, err
+	}
+	a.Tags, err = d.articleTags(ctx, id)
+	return a, err
+}
+
+//GetArticleBySlug returns the article holding a slug, now or before it moved on to another one
+func (d *DynamoStorage) GetArticleBySlug(ctx context.Context, slug string) (models.Article, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.slugsTable),
+		Key:            slugItemKey(slug),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return models.Article{}, err
+	}
+	if len(out.Item) == 0 {
+		return models.Article{}, ErrResourceNotFound
+	}
+	id, err := numberAttr(out.Item, "articleID")
+	if err != nil {
+		return models.Article{}, err
+	}
+	return d.GetArticleByID(ctx, id)
+}
+
+//GetAllArticles returns all articles in the table
+func (d *DynamoStorage) GetAllArticles(ctx context.Context) ([]models.Article, error) {
+	var (
+		articles []models.Article
+		startKey map[string]types.AttributeValue
+	)
+	for {
+		out, err := d.client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:                 aws.String(d.table),
+			FilterExpression:          aws.String("articleID <> :counter"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":counter": numberValue(counterID)},
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range out.Items {
+			a, err := itemToArticle(item)
+			if err != nil {
+				return nil, err
+			}
+			articles = append(articles, a)
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return articles, d.fillTags(ctx, articles)
+		}
+		startKey = out.LastEvaluatedKey
+	}
+}
+
+//GetArticleByUserID returns all articles filtered by a particular userId using the userID index
+func (d *DynamoStorage) GetArticleByUserID(ctx context.Context, userID int) ([]models.Article, error) {
+	var (
+		articles []models.Article
+		startKey map[string]types.AttributeValue
+	)
+	for {
+		out, err := d.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(d.table),
+			IndexName:                 aws.String(UserIDIndex),
+			KeyConditionExpression:    aws.String("userID = :userID"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":userID": numberValue(userID)},
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range out.Items {
+			a, err := itemToArticle(item)
+			if err != nil {
+				return nil, err
+			}
+			articles = append(articles, a)
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return articles, d.fillTags(ctx, articles)
+		}
+		startKey = out.LastEvaluatedKey
+	}
+}
+
+//ListArticles returns a page of articles by querying the index holding the sort order of the
+//query. Sorting by id uses the userID index for one author and the articleID index otherwise,
+//the other orders narrow their index's key range to the cursor and to a date range on the field
+//they sort by. Remaining filters are applied by DynamoDB, apart from the tag which is checked
+//against each article's tags, and one extra item is fetched to learn whether another page
+//follows. Sorting by id for one tag walks the tag index instead
+func (d *DynamoStorage) ListArticles(ctx context.Context, q PageQuery) (Page, error) {
+	if q.Tag != "" && q.Sort == "" {
+		return d.listTagged(ctx, q)
+	}
+	values := map[string]types.AttributeValue{}
+	input := &dynamodb.QueryInput{
+		TableName:        aws.String(d.table),
+		ScanIndexForward: aws.Bool(!q.Desc),
+	}
+	var (
+		key     string
+		filters []string
+	)
+	createdAfter, createdBefore, updatedAfter, updatedBefore := q.CreatedAfter, q.CreatedBefore, q.UpdatedAfter, q.UpdatedBefore
+	if six, ok := dynamoSortIndexes[q.Sort]; ok {
+		input.IndexName = aws.String(six.index)
+		key = "itemType = :itemType"
+		values[":itemType"] = &types.AttributeValueMemberS{Value: articleItemType}
+
+		//The date range of the sorted field becomes part of the key range
+		var lo, hi string
+		switch q.Sort {
+		case SortByCreatedAt:
+			lo, hi = timeBounds(createdAfter, createdBefore)
+			createdAfter, createdBefore = time.Time{}, time.Time{}
+		case SortByUpdatedAt:
+			lo, hi = timeBounds(updatedAfter, updatedBefore)
+			updatedAfter, updatedBefore = time.Time{}, time.Time{}
+		}
+		if q.AfterID != 0 {
+			pos := q.position()
+			if q.Desc {
+				pos.ArticleID--
+				if k := sortKey(q.Sort, pos); hi == "" || k < hi {
+					hi = k
+				}
+			} else {
+				pos.ArticleID++
+				if k := sortKey(q.Sort, pos); k > lo {
+					lo = k
+				}
+			}
+		}
+		switch {
+		case lo != "" && hi != "" && lo > hi:
+			return Page{}, nil
+		case lo != "" && hi != "":
+			key += " AND " + six.key + " BETWEEN :lo AND :hi"
+		case lo != "":
+			key += " AND " + six.key + " >= :lo"
+		case hi != "":
+			key += " AND " + six.key + " <= :hi"
+		}
+		if lo != "" {
+			values[":lo"] = &types.AttributeValueMemberS{Value: lo}
+		}
+		if hi != "" {
+			values[":hi"] = &types.AttributeValueMemberS{Value: hi}
+		}
+		if q.UserID != 0 {
+			filters = append(filters, "userID = :userID")
+			values[":userID"] = numberValue(q.UserID)
+		}
+	} else {
+		if q.UserID == 0 {
+			input.IndexName = aws.String(ArticleIDIndex)
+			key = "itemType = :itemType"
+			values[":itemType"] = &types.AttributeValueMemberS{Value: articleItemType}
+		} else {
+			input.IndexName = aws.String(UserIDIndex)
+			key = "userID = :userID"
+			values[":userID"] = numberValue(q.UserID)
+		}
+		if q.AfterID != 0 {
+			op := " > "
+			if q.Desc {
+				op = " < "
+			}
+			key += " AND articleID" + op + ":after"
+			values[":after"] = numberValue(q.AfterID)
+		}
+	}
+	input.KeyConditionExpression = aws.String(key)
+
+	if q.Trashed {
+		filters = append(filters, "attribute_exists(deletedAt)")
+	} else {
+		filters = append(filters, "attribute_not_exists(deletedAt)")
+	}
+	if q.Status != "" {
+		//status is a reserved word so it is referenced through a name placeholder. Items stored
+		//before the publishing workflow have none and read as published
+		if q.Status == models.StatusPublished {
+			filters = append(filters, "(#status = :status OR attribute_not_exists(#status))")
+		} else {
+			filters = append(filters, "#status = :status")
+		}
+		input.ExpressionAttributeNames = map[string]string{"#status": "status"}
+		values[":status"] = &types.AttributeValueMemberS{Value: q.Status}
+	}
+	if q.Category != "" {
+		filters = append(filters, "category = :category")
+		values[":category"] = &types.AttributeValueMemberS{Value: q.Category}
+	}
+	for _, f := range []struct {
+		cond string
+		t    time.Time
+	}{
+		{"createdAt > :createdAfter", createdAfter},
+		{"createdAt < :createdBefore", createdBefore},
+		{"updatedAt > :updatedAfter", updatedAfter},
+		{"updatedAt < :updatedBefore", updatedBefore},
+	} {
+		if !f.t.IsZero() {
+			filters = append(filters, f.cond)
+			values[f.cond[strings.Index(f.cond, ":"):]] = timeValue(f.t)
+		}
+	}
+	input.FilterExpression = aws.String(strings.Join(filters, " AND "))
+	input.ExpressionAttributeValues = values
+
+	var articles []models.Article
+	for {
+		input.Limit = aws.Int32(int32(q.Limit + 1 - len(articles)))
+		out, err := d.client.Query(ctx, input)
+		if err != nil {
+			return Page{}, err
+		}
+		batch := make([]models.Article, len(out.Items))
+		for i, item := range out.Items {
+			if batch[i], err = itemToArticle(item); err != nil {
+				return Page{}, err
+			}
+		}
+		if err := d.fillTags(ctx, batch); err != nil {
+			return Page{}, err
+		}
+		for _, a := range batch {
+			if q.Tag == "" || hasTag(a, q.Tag) {
+				articles = append(articles, a)
+			}
+		}
+		if len(articles) > q.Limit || len(out.LastEvaluatedKey) == 0 {
+			return newPage(articles, q.Limit), nil
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+//listTagged returns a page of the articles carrying the tag of the query ordered by id, walking
+//the tag index and reading the articles it names to apply the remaining filters
+func (d *DynamoStorage) listTagged(ctx context.Context, q PageQuery) (Page, error) {
+	key := "tag = :tag"
+	values := map[string]types.AttributeValue{":tag": &types.AttributeValueMemberS{Value: q.Tag}}
+	if q.AfterID != 0 {
+		op := " > "
+		if q.Desc {
+			op = " < "
+		}
+		key += " AND articleID" + op + ":after"
+		values[":after"] = numberValue(q.AfterID)
+	}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(d.tagsTable),
+		IndexName:                 aws.String(TagIndex),
+		KeyConditionExpression:    aws.String(key),
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(!q.Desc),
+	}
+
+	var articles []models.Article
+	for {
+		input.Limit = aws.Int32(int32(q.Limit + 1 - len(articles)))
+		out, err := d.client.Query(ctx, input)
+		if err != nil {
+			return Page{}, err
+		}
+		batch, err := d.taggedArticles(ctx, out.Items)
+		if err != nil {
+			return Page{}, err
+		}
+		for _, a := range batch {
+			if q.Matches(a) {
+				articles = append(articles, a)
+			}
+		}
+		if len(articles) > q.Limit || len(out.LastEvaluatedKey) == 0 {
+			return newPage(articles, q.Limit), nil
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+//taggedArticles reads in batches the articles named by items of the tag index, along with their
+//tags, in the order of the items. The index is eventually consistent so it may still name a
+//deleted article, which is left out
+func (d *DynamoStorage) taggedArticles(ctx context.Context, items []map[string]types.AttributeValue) ([]models.Article, error) {
+	var (
+		ids  []int
+		keys []map[string]types.AttributeValue
+	)
+	for _, item := range items {
+		id, err := numberAttr(item, "articleID")
+		if err != nil {
+			return nil, err
+		}
+		if id != counterID {
+			ids = append(ids, id)
+			keys = append(keys, articleKey(id))
+		}
+	}
+	found, err := d.batchGet(ctx, d.table, keys)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.Article, len(found))
+	for _, item := range found {
+		a, err := itemToArticle(item)
+		if err != nil {
+			return nil, err
+		}
+		byID[a.ArticleID] = a
+	}
+	articles := make([]models.Article, 0, len(byID))
+	for _, id := range ids {
+		if a, ok := byID[id]; ok {
+			articles = append(articles, a)
+		}
+	}
+	return articles, d.fillTags(ctx, articles)
+}
+
+//CreateArticle issues a new id from the table's sequence item and stores the article under it
+//together with its first revision and its slug claim
+func (d *DynamoStorage) CreateArticle(ctx context.Context, art models.Article) (int, error) {
+	id, err := d.nextID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	insertArt := art
+	insertArt.ArticleID, insertArt.Version, insertArt.Status = id, 1, models.StatusDraft
+	_, err = d.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: d.withSlugClaim([]types.TransactWriteItem{
+			{Put: &types.Put{
+				TableName:           aws.String(d.table),
+				Item:                articleToItem(insertArt),
+				ConditionExpression: aws.String("attribute_not_exists(articleID)"),
+			}},
+			{Put: &types.Put{
+				TableName: aws.String(d.revisionsTable),
+				Item:      articleToItem(insertArt),
+			}},
+		}, insertArt),
+	})
+	if slugClaimFailed(err) {
+		return 0, ErrSlugTaken
+	}
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+//UpdateArticle replaces an existing article with a new one if its version still matches,
+//writing the article and its new revision in one transaction
+func (d *DynamoStorage) UpdateArticle(ctx context.Context, id int, article models.Article) error {
+	if id == counterID {
+		return ErrResourceNotFound
+	}
+	for {
+		//The revision is keyed by the new version and the tag counts by the old status, so the
+		//current article is read first and the write retried if another one lands in between
+		cur, err := d.articleItem(ctx, id)
+		if err != nil {
+			return err
+		}
+		old, err := itemToArticle(cur)
+		if err != nil {
+			return err
+		}
+		if article.Version != AnyVersion && article.Version != old.Version {
+			return ErrVersionConflict
+		}
+		err = d.putRevision(ctx, cur, old, article)
+		if err == ErrVersionConflict {
+			continue
+		}
+		return err
+	}
+}
+
+//articleItem reads the item of an article
+func (d *DynamoStorage) articleItem(ctx context.Context, id int) (map[string]types.AttributeValue, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.table),
+		Key:            articleKey(id),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Item) == 0 {
+		return nil, ErrResourceNotFound
+	}
+	return out.Item, nil
+}
+
+//putRevision stores article as the next version of old, read from the item cur, provided the
+//item is unchanged, claiming its slug in the same transaction. When the article enters or leaves
+//the trash or changes status the counts of its tags move with it, and the transaction then also
+//requires that no tag was written since cur was read
+func (d *DynamoStorage) putRevision(ctx context.Context, cur map[string]types.AttributeValue, old, article models.Article) error {
+	article.ArticleID = old.ArticleID
+	article.Version = old.Version + 1
+	item := articleToItem(article)
+	values := map[string]types.AttributeValue{}
+	cond := aws.ToString(versionCondition(old.Version, values))
+	if tagWrites, ok := cur["tagWrites"]; ok {
+		item["tagWrites"] = tagWrites
+	}
+
+	from, to := countedStatus(old), countedStatus(article)
+	var counts []types.TransactWriteItem
+	if from != to {
+		tags, err := d.articleTags(ctx, old.ArticleID)
+		if err != nil {
+			return err
+		}
+		for _, t := range tags {
+			counts = append(counts, d.countTag(t, from, to))
+		}
+		cond = tagWritesCondition(cond, cur, values)
+	}
+
+	items := d.withSlugClaim([]types.TransactWriteItem{
+		{Put: &types.Put{
+			TableName:                           aws.String(d.table),
+			Item:                                item,
+			ConditionExpression:                 aws.String(cond),
+			ExpressionAttributeValues:           values,
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		}},
+		{Put: &types.Put{
+			TableName: aws.String(d.revisionsTable),
+			Item:      articleToItem(article),
+		}},
+	}, article)
+	_, err := d.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: append(items, counts...),
+	})
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) && len(canceled.CancellationReasons) > 0 {
+		reason := canceled.CancellationReasons[0]
+		if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+			if len(reason.Item) > 0 {
+				return ErrVersionConflict
+			}
+			return ErrResourceNotFound
+		}
+	}
+	if slugClaimFailed(err) {
+		return ErrSlugTaken
+	}
+	return err
+}
+
+//withSlugClaim appends to the writes of an article a claim on its slug, which fails when
+//another article holds the slug. Articles without a slug claim nothing
+func (d *DynamoStorage) withSlugClaim(items []types.TransactWriteItem, a models.Article) []types.TransactWriteItem {
+	if a.Slug == "" {
+		return items
+	}
+	return append(items, types.TransactWriteItem{Put: &types.Put{
+		TableName:                 aws.String(d.slugsTable),
+		Item:                      map[string]types.AttributeValue{"slug": &types.AttributeValueMemberS{Value: a.Slug}, "articleID": numberValue(a.ArticleID)},
+		ConditionExpression:       aws.String("attribute_not_exists(slug) OR articleID = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":id": numberValue(a.ArticleID)},
+	}})
+}
+
+//slugClaimFailed reports whether a transaction built by withSlugClaim was canceled by the
+//condition of its slug claim, the item after the article and its revision
+func slugClaimFailed(err error) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || len(canceled.CancellationReasons) < 3 {
+		return false
+	}
+	return aws.ToString(canceled.CancellationReasons[2].Code) == "ConditionalCheckFailed"
+}
+
+//DeleteArticle removes an article from the table if its version still matches, then removes
+//its tags, slugs and revisions. A failure part way through can leave them behind, and as the
+//slugs are found through an eventually consistent index a slug claimed just before the delete
+//can stay reserved
+func (d *DynamoStorage) DeleteArticle(ctx context.Context, id, version int) error {
+	if id == counterID {
+		return ErrResourceNotFound
+	}
+	values := map[string]types.AttributeValue{}
+	input := &dynamodb.DeleteItemInput{
+		TableName:                           aws.String(d.table),
+		Key:                                 articleKey(id),
+		ConditionExpression:                 versionCondition(version, values),
+		ReturnValues:                        types.ReturnValueAllOld,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	}
+	if len(values) > 0 {
+		input.ExpressionAttributeValues = values
+	}
+	out, err := d.client.DeleteItem(ctx, input)
+	if err != nil {
+		return translateConditionErr(err)
+	}
+	old, err := itemToArticle(out.Attributes)
+	if err != nil {
+		return err
+	}
+
+	//Once the article is gone no tag write can succeed, so its tags are removed one by one, each
+	//with its count
+	tags, err := d.articleTags(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, t := range tags {
+		items := []types.TransactWriteItem{{Delete: &types.Delete{
+			TableName: aws.String(d.tagsTable),
+			Key:       tagItemKey(id, t),
+		}}}
+		if status := countedStatus(old); status != "" {
+			items = append(items, d.countTag(t, status, ""))
+		}
+		if err := d.transact(ctx, items); err != nil {
+			return err
+		}
+	}
+	_, err = d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.tagsTable),
+		Key:       tagItemKey(id, tagListTag),
+	})
+	if err != nil {
+		return err
+	}
+	if err := d.releaseSlugs(ctx, id); err != nil {
+		return err
+	}
+
+	revisions, err := d.GetRevisions(ctx, id)
+	if err == ErrResourceNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, r := range revisions {
+		_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(d.revisionsTable),
+			Key:       revisionItemKey(id, r.Version),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//releaseSlugs removes every slug held by an article
+func (d *DynamoStorage) releaseSlugs(ctx context.Context, id int) error {
+	var startKey map[string]types.AttributeValue
+	for {
+		out, err := d.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(d.slugsTable),
+			IndexName:                 aws.String(SlugArticleIndex),
+			KeyConditionExpression:    aws.String("articleID = :id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":id": numberValue(id)},
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return err
+		}
+		for _, item := range out.Items {
+			_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName:                 aws.String(d.slugsTable),
+				Key:                       slugItemKey(stringAttr(item, "slug")),
+				ConditionExpression:       aws.String("articleID = :id"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{":id": numberValue(id)},
+			})
+			var ccf *types.ConditionalCheckFailedException
+			if err != nil && !errors.As(err, &ccf) {
+				return err
+			}
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		startKey = out.LastEvaluatedKey
+	}
+}
+
+//GetScheduledArticles returns the articles outside the trash with a change due by t ordered by
+//id, those in review with a publishAt at or before t and those published with an unpublishAt at
+//or before t. Few articles carry a schedule so a filtered scan is used rather than an index
+func (d *DynamoStorage) GetScheduledArticles(ctx context.Context, t time.Time) ([]models.Article, error) {
+	var (
+		articles []models.Article
+		startKey map[string]types.AttributeValue
+	)
+	for {
+		out, err := d.client.Scan(ctx, &dynamodb.ScanInput{
+			TableName: aws.String(d.table),
+			FilterExpression: aws.String("((publishAt <= :t AND #status = :review) OR (unpublishAt <= :t AND (#status = :published" +
+				" OR attribute_not_exists(#status)))) AND attribute_not_exists(deletedAt)"),
+			ExpressionAttributeNames: map[string]string{"#status": "status"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":t":         timeValue(t),
+				":review":    &types.AttributeValueMemberS{Value: models.StatusInReview},
+				":published": &types.AttributeValueMemberS{Value: models.StatusPublished},
+			},
+			ConsistentRead:    aws.Bool(true),
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range out.Items {
+			a, err := itemToArticle(item)
+			if err != nil {
+				return nil, err
+			}
+			articles = append(articles, a)
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = out.LastEvaluatedKey
+	}
+	sort.Slice(articles, func(i, j int) bool { return articles[i].ArticleID < articles[j].ArticleID })
+	return articles, d.fillTags(ctx, articles)
+}
+
+//AddTags relates an article to the given tags, tags it already carries are left alone
+func (d *DynamoStorage) AddTags(ctx context.Context, id int, tags []string) error {
+	return d.writeTags(ctx, id, tags, nil)
+}
+
+//RemoveTags drops the given tags from an article, tags it does not carry are left alone
+func (d *DynamoStorage) RemoveTags(ctx context.Context, id int, tags []string) error {
+	return d.writeTags(ctx, id, nil, tags)
+}
+
+//writeTags adds and removes tags of an article in one transaction that moves the counts of the
+//tags actually added or removed, rewrites the article's tag list and bumps its tagWrites, so
+//that a concurrent change of its tags, status or trash state notices. The transaction only
+//applies while the article and the tags read beforehand are unchanged and is retried otherwise
+func (d *DynamoStorage) writeTags(ctx context.Context, id int, add, remove []string) error {
+	if id == counterID {
+		return ErrResourceNotFound
+	}
+	for {
+		item, err := d.articleItem(ctx, id)
+		if err != nil {
+			return err
+		}
+		a, err := itemToArticle(item)
+		if err != nil {
+			return err
+		}
+		current, err := d.articleTags(ctx, id)
+		if err != nil {
+			return err
+		}
+		carried := make(map[string]bool, len(current))
+		for _, t := range current {
+			carried[t] = true
+		}
+
+		values := map[string]types.AttributeValue{":one": numberValue(1)}
+		cond := tagWritesCondition(aws.ToString(versionCondition(a.Version, values)), item, values)
+		items := []types.TransactWriteItem{{Update: &types.Update{
+			TableName:                 aws.String(d.table),
+			Key:                       articleKey(id),
+			UpdateExpression:          aws.String("ADD tagWrites :one"),
+			ConditionExpression:       aws.String(cond),
+			ExpressionAttributeValues: values,
+		}}}
+		status := countedStatus(a)
+		for _, t := range add {
+			if carried[t] {
+				continue
+			}
+			carried[t] = true
+			items = append(items, types.TransactWriteItem{Put: &types.Put{
+				TableName:           aws.String(d.tagsTable),
+				Item:                tagItemKey(id, t),
+				ConditionExpression: aws.String("attribute_not_exists(articleID)"),
+			}})
+			if status != "" {
+				items = append(items, d.countTag(t, "", status))
+			}
+		}
+		for _, t := range remove {
+			if !carried[t] {
+				continue
+			}
+			delete(carried, t)
+			items = append(items, types.TransactWriteItem{Delete: &types.Delete{
+				TableName:           aws.String(d.tagsTable),
+				Key:                 tagItemKey(id, t),
+				ConditionExpression: aws.String("attribute_exists(articleID)"),
+			}})
+			if status != "" {
+				items = append(items, d.countTag(t, status, ""))
+			}
+		}
+		if len(items) == 1 {
+			return nil
+		}
+		tags := make([]string, 0, len(carried))
+		for t := range carried {
+			tags = append(tags, t)
+		}
+		items = append(items, types.TransactWriteItem{Put: &types.Put{
+			TableName: aws.String(d.tagsTable),
+			Item:      tagListItem(id, tags),
+		}})
+
+		err = d.transact(ctx, items)
+		if failed, _ := canceledAt(err); slices.Contains(failed, true) {
+			continue
+		}
+		return err
+	}
+}
+
+//tagWritesCondition extends cond to require that the tagWrites of an article are still those of
+//its item cur
+func tagWritesCondition(cond string, cur, values map[string]types.AttributeValue) string {
+	tagWrites, ok := cur["tagWrites"]
+	if !ok {
+		return cond + " AND attribute_not_exists(tagWrites)"
+	}
+	values[":tagWrites"] = tagWrites
+	return cond + " AND tagWrites = :tagWrites"
+}
+
+//countedStatus is the status an article is counted under in the tag counts, empty for an
+//article in the trash
+func countedStatus(a models.Article) string {
+	if a.DeletedAt != nil {
+		return ""
+	}
+	return a.Status
+}
+
+//countTag moves one article carrying tag from the count of status from to that of status to in
+//the tag's counter item, an empty status standing for none. Counter items sit in the tags table
+//under the reserved articleID with one count per status
+func (d *DynamoStorage) countTag(tag, from, to string) types.TransactWriteItem {
+	var (
+		adds   []string
+		names  = map[string]string{}
+		values = map[string]types.AttributeValue{}
+	)
+	if from != "" {
+		adds = append(adds, "#from :down")
+		names["#from"], values[":down"] = from, numberValue(-1)
+	}
+	if to != "" {
+		adds = append(adds, "#to :up")
+		names["#to"], values[":up"] = to, numberValue(1)
+	}
+	return types.TransactWriteItem{Update: &types.Update{
+		TableName:                 aws.String(d.tagsTable),
+		Key:                       tagItemKey(counterID, tag),
+		UpdateExpression:          aws.String("ADD " + strings.Join(adds, ", ")),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	}}
+}
+
+//GetTagCounts returns every tag carried by an article outside the trash with the number of
+//such articles carrying it, restricted to a single status when status is non-empty. The counts
+//are read from the counter items of the tags
+func (d *DynamoStorage) GetTagCounts(ctx context.Context, status string) ([]models.TagCount, error) {
+	var (
+		counts   []models.TagCount
+		startKey map[string]types.AttributeValue
+	)
+	for {
+		out, err := d.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(d.tagsTable),
+			KeyConditionExpression:    aws.String("articleID = :counter"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":counter": numberValue(counterID)},
+			ConsistentRead:            aws.Bool(true),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range out.Items {
+			n := 0
+			for name := range item {
+				if name == "articleID" || name == "tag" || status != "" && name != status {
+					continue
+				}
+				c, err := numberAttr(item, name)
+				if err != nil {
+					return nil, err
+				}
+				n += c
+			}
+			if n > 0 {
+				counts = append(counts, models.TagCount{Tag: stringAttr(item, "tag"), Count: n})
+			}
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return counts, nil
+		}
+		startKey = out.LastEvaluatedKey
+	}
+}
+
+//articleTags reads the tags of an article from its tag list
+func (d *DynamoStorage) articleTags(ctx context.Context, id int) ([]string, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.tagsTable),
+		Key:            tagItemKey(id, tagListTag),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return itemTags(out.Item), nil
+}
+
+//fillTags reads the tag lists of the given articles in batches
+func (d *DynamoStorage) fillTags(ctx context.Context, articles []models.Article) error {
+	var (
+		keys []map[string]types.AttributeValue
+		seen = map[int]bool{}
+	)
+	for _, a := range articles {
+		if !seen[a.ArticleID] {
+			seen[a.ArticleID] = true
+			keys = append(keys, tagItemKey(a.ArticleID, tagListTag))
+		}
+	}
+	items, err := d.batchGet(ctx, d.tagsTable, keys)
+	if err != nil {
+		return err
+	}
+	tags := make(map[int][]string, len(items))
+	for _, item := range items {
+		id, err := numberAttr(item, "articleID")
+		if err != nil {
+			return err
+		}
+		tags[id] = itemTags(item)
+	}
+	for i := range articles {
+		articles[i].Tags = tags[articles[i].ArticleID]
+	}
+	return nil
+}
+
+//GetRevisions returns every revision of an article ordered by version
+func (d *DynamoStorage) GetRevisions(ctx context.Context, id int) ([]models.Article, error) {
+	var (
+		revisions []models.Article
+		startKey  map[string]types.AttributeValue
+	)
+	for {
+		out, err := d.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(d.revisionsTable),
+			KeyConditionExpression:    aws.String("articleID = :articleID"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":articleID": numberValue(id)},
+			ConsistentRead:            aws.Bool(true),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range out.Items {
+			a, err := itemToArticle(item)
+			if err != nil {
+				return nil, err
+			}
+			revisions = append(revisions, a)
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = out.LastEvaluatedKey
+	}
+	if len(revisions) == 0 {
+		return nil, ErrResourceNotFound
+	}
+	return revisions, nil
+}
+
+//GetRevision returns the revision of an article at the given version
+func (d *DynamoStorage) GetRevision(ctx context.Context, id, version int) (models.Article, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.revisionsTable),
+		Key:            revisionItemKey(id, version),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return models.Article{}, err
+	}
+	if len(out.Item) == 0 {
+		return models.Article{}, ErrResourceNotFound
+	}
+	return itemToArticle(out.Item)
+}
+
+//nextID atomically increments the sequence item and returns the new value
+func (d *DynamoStorage) nextID(ctx context.Context) (int, error) {
+	return d.nextSeq(ctx, d.table, articleKey(counterID))
+}
+
+//nextSeq atomically increments the sequence held by the item of a table with the given key and
+//returns the new value
+func (d *DynamoStorage) nextSeq(ctx context.Context, table string, key map[string]types.AttributeValue) (int, error) {
+	out, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(table),
+		Key:                       key,
+		UpdateExpression:          aws.String("ADD seq :one"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":one": numberValue(1)},
+		ReturnValues:              types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return numberAttr(out.Attributes, "seq")
+}
+
+//versionCondition builds the condition of a write to an existing article, adding the expected
+//version to values unless it is AnyVersion. Items written before versioning have no version
+//attribute and count as version 1
+func versionCondition(version int, values map[string]types.AttributeValue) *string {
+	switch version {
+	case AnyVersion:
+		return aws.String("attribute_exists(articleID)")
+	case 1:
+		values[":version"] = numberValue(version)
+		return aws.String("attribute_exists(articleID) AND (version = :version OR attribute_not_exists(version))")
+	}
+	values[":version"] = numberValue(version)
+	return aws.String("attribute_exists(articleID) AND version = :version")
+}
+
+//translateConditionErr maps a failed write condition onto ErrResourceNotFound, or onto
+//ErrVersionConflict when the returned old item shows the article exists
+func translateConditionErr(err error) error {
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		if len(ccf.Item) > 0 {
+			return ErrVersionConflict
+		}
+		return ErrResourceNotFound
+	}
+	return err
+}
+
+func articleKey(id int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"articleID": numberValue(id)}
+}
+
+func tagItemKey(id int, tag string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"articleID": numberValue(id), "tag": &types.AttributeValueMemberS{Value: tag}}
+}
+
+//tagListItem builds the item listing the tags of an article in sorted order
+func tagListItem(id int, tags []string) map[string]types.AttributeValue {
+	sort.Strings(tags)
+	list := make([]types.AttributeValue, len(tags))
+	for i, t := range tags {
+		list[i] = &types.AttributeValueMemberS{Value: t}
+	}
+	item := tagItemKey(id, tagListTag)
+	item["tags"] = &types.AttributeValueMemberL{Value: list}
+	return item
+}
+
+//itemTags reads the tags of a tag list item, none when there is no item
+func itemTags(item map[string]types.AttributeValue) []string {
+	list, _ := item["tags"].(*types.AttributeValueMemberL)
+	if list == nil || len(list.Value) == 0 {
+		return nil
+	}
+	tags := make([]string, len(list.Value))
+	for i, v := range list.Value {
+		if s, ok := v.(*types.AttributeValueMemberS); ok {
+			tags[i] = s.Value
+		}
+	}
+	return tags
+}
+
+func slugItemKey(slug string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"slug": &types.AttributeValueMemberS{Value: slug}}
+}
+
+func revisionItemKey(id, version int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"articleID": numberValue(id), "version": numberValue(version)}
+}
+
+func articleToItem(a models.Article) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"articleID": numberValue(a.ArticleID),
+		"itemType":  &types.AttributeValueMemberS{Value: articleItemType},
+		"userID":    numberValue(a.UserID),
+		"title":     &types.AttributeValueMemberS{Value: a.Title},
+		"slug":      &types.AttributeValueMemberS{Value: a.Slug},
+		"body":      &types.AttributeValueMemberS{Value: a.Body},
+		"category":  &types.AttributeValueMemberS{Value: a.Category},
+		"version":   numberValue(a.Version),
+		"status":    &types.AttributeValueMemberS{Value: a.Status},
+		"createdAt": timeValue(a.CreatedAt),
+		"updatedAt": timeValue(a.UpdatedAt),
+		"createdBy": numberValue(a.CreatedBy),
+		"updatedBy": numberValue(a.UpdatedBy),
+	}
+	for sort, six := range dynamoSortIndexes {
+		item[six.key] = &types.AttributeValueMemberS{Value: sortKey(sort, a)}
+	}
+	if t := a.LastTransition; t != nil {
+		item["transitionFrom"] = &types.AttributeValueMemberS{Value: t.From}
+		item["transitionTo"] = &types.AttributeValueMemberS{Value: t.To}
+		item["transitionBy"] = numberValue(t.By)
+		item["transitionAt"] = timeValue(t.At)
+	}
+	if a.PublishAt != nil {
+		item["publishAt"] = timeValue(*a.PublishAt)
+	}
+	if a.UnpublishAt != nil {
+		item["unpublishAt"] = timeValue(*a.UnpublishAt)
+	}
+	if a.DeletedAt != nil {
+		item["deletedAt"] = timeValue(*a.DeletedAt)
+	}
+	return item
+}
+
+func itemToArticle(item map[string]types.AttributeValue) (models.Article, error) {
+	var (
+		a   models.Article
+		err error
+	)
+	if a.ArticleID, err = numberAttr(item, "articleID"); err != nil {
+		return models.Article{}, err
+	}
+	if a.UserID, err = numberAttr(item, "userID"); err != nil {
+		return models.Article{}, err
+	}
+	if _, ok := item["version"]; ok {
+		if a.Version, err = numberAttr(item, "version"); err != nil {
+			return models.Article{}, err
+		}
+	}
+	a.Title = stringAttr(item, "title")
+	a.Slug = stringAttr(item, "slug")
+	a.Body = stringAttr(item, "body")
+	a.Category = stringAttr(item, "category")
+	a.Status = stringAttr(item, "status")
+	for name, n := range map[string]*int{"createdBy": &a.CreatedBy, "updatedBy": &a.UpdatedBy} {
+		if _, ok := item[name]; ok {
+			if *n, err = numberAttr(item, name); err != nil {
+				return models.Article{}, err
+			}
+		}
+	}
+	for name, t := range map[string]*time.Time{"createdAt": &a.CreatedAt, "updatedAt": &a.UpdatedAt} {
+		if _, ok := item[name]; ok {
+			if *t, err = timeAttr(item, name); err != nil {
+				return models.Article{}, err
+			}
+		}
+	}
+	if _, ok := item["transitionTo"]; ok {
+		t := models.Transition{From: stringAttr(item, "transitionFrom"), To: stringAttr(item, "transitionTo")}
+		if t.By, err = numberAttr(item, "transitionBy"); err != nil {
+			return models.Article{}, err
+		}
+		if t.At, err = timeAttr(item, "transitionAt"); err != nil {
+			return models.Article{}, err
+		}
+		a.LastTransition = &t
+	}
+	if _, ok := item["publishAt"]; ok {
+		t, err := timeAttr(item, "publishAt")
+		if err != nil {
+			return models.Article{}, err
+		}
+		a.PublishAt = &t
+	}
+	if _, ok := item["unpublishAt"]; ok {
+		t, err := timeAttr(item, "unpublishAt")
+		if err != nil {
+			return models.Article{}, err
+		}
+		a.UnpublishAt = &t
+	}
+	if _, ok := item["deletedAt"]; ok {
+		t, err := timeAttr(item, "deletedAt")
+		if err != nil {
+			return models.Article{}, err
+		}
+		a.DeletedAt = &t
+	}
+	return withLegacyDefaults(a), nil
+}
+
+//sortKey builds the value an article is sorted by in the index of a sort order, the sort value
+//followed by the zero padded articleID so that keys are unique and ties are broken by id. A
+//title is closed with \x01 so it sorts before longer titles, and the bytes \x00 and \x01 within
+//it are escaped as \x01 followed by a byte above any digit so that they sort after the close
+func sortKey(sort string, a models.Article) string {
+	id := fmt.Sprintf("%020d", a.ArticleID)
+	switch sort {
+	case SortByCreatedAt:
+		return timeValue(a.CreatedAt).Value + "#" + id
+	case SortByUpdatedAt:
+		return timeValue(a.UpdatedAt).Value + "#" + id
+	}
+	return titleEscaper.Replace(a.Title) + "\x01" + id
+}
+
+//titleEscaper escapes the bytes of a title that would otherwise sort at or below the close of
+//its sort key
+var titleEscaper = strings.NewReplacer("\x00", "\x01:", "\x01", "\x01;")
+
+//timeBounds turns an exclusive time range into inclusive bounds on time sort keys, "" for an
+//open end. Keys at the after time all fall below its bound since "$" follows "#"
+func timeBounds(after, before time.Time) (string, string) {
+	var lo, hi string
+	if !after.IsZero() {
+		lo = timeValue(after).Value + "$"
+	}
+	if !before.IsZero() {
+		hi = timeValue(before).Value
+	}
+	return lo, hi
+}
+
+func timeValue(t time.Time) *types.AttributeValueMemberS {
+	return &types.AttributeValueMemberS{Value: t.UTC().Format(timeFormat)}
+}
+
+func timeAttr(item map[string]types.AttributeValue, name string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, stringAttr(item, name))
+}
+
+func numberValue(n int) *types.AttributeValueMemberN {
+	return &types.AttributeValueMemberN{Value: strconv.Itoa(n)}
+}
+
+func numberAttr(item map[string]types.AttributeValue, name string) (int, error) {
+	v, ok := item[name].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("attribute %v missing or not a number", name)
+	}
+	return strconv.Atoi(v.Value)
+}
+
+func stringAttr(item map[string]types.AttributeValue, name string) string {
+	if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return v.Value
+	}
+	return ""
+}
@@ -1,81 +1,308 @@
 package storage
 
-import "github.com/Perezonance/article-management-service/internal/models"
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
 
-var (
-	idCounter = 1
+	"github.com/Perezonance/article-management-service/internal/models"
 )
 
-//MockDynamo emulates a key value store db with an Articles table
+//MockDynamo emulates a key value store db with an Articles table, a table of their revisions,
+//a table relating articles to their tags and a table of the slugs held by each article. It is
+//safe for concurrent use and each instance issues its own id sequence
 type MockDynamo struct {
-	ArticlesTable map[int]models.Article
+	mu        sync.RWMutex
+	articles  map[int]models.Article
+	revisions map[int][]models.Article
+	tags      map[int]map[string]struct{}
+	slugs     map[string]int
+	idCounter int
 }
 
-//NewMockDynamo creates a new MockDynamo DB with a blank Articles table
+//NewMockDynamo creates a new MockDynamo DB with blank Articles, revisions, tags and slugs tables
 func NewMockDynamo() *MockDynamo {
-	return &MockDynamo{ArticlesTable: make(map[int]models.Article)}
+	return &MockDynamo{
+		articles:  make(map[int]models.Article),
+		revisions: make(map[int][]models.Article),
+		tags:      make(map[int]map[string]struct{}),
+		slugs:     make(map[string]int),
+		idCounter: 1,
+	}
 }
 
 //GetArticleByID returns an article given an id
-func (mdb *MockDynamo) GetArticleByID(id int) (models.Article, error) {
-	article := mdb.ArticlesTable[id]
-	//Check to see if key contains non-zero value
-	if article == (models.Article{}) {
-		return models.Article{}, ErrResourceNotFound
+func (mdb *MockDynamo) GetArticleByID(ctx context.Context, id int) (models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Article{}, err
 	}
-	return article, nil
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
+	return mdb.get(id)
 }
 
 //GetAllArticles returns all articles in the in-memory db
-func (mdb *MockDynamo) GetAllArticles() ([]models.Article, error) {
+func (mdb *MockDynamo) GetAllArticles(ctx context.Context) ([]models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
 	var articles []models.Article
-	for _, v := range mdb.ArticlesTable {
-		articles = append(articles, v)
+	for _, v := range mdb.articles {
+		articles = append(articles, mdb.withTags(v))
 	}
 	return articles, nil
 }
 
 //GetArticleByUserID returns all articles filtered by a particular userId
-func (mdb *MockDynamo) GetArticleByUserID(userID int) ([]models.Article, error) {
+func (mdb *MockDynamo) GetArticleByUserID(ctx context.Context, userID int) ([]models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
 	var articles []models.Article
-	for _, v := range mdb.ArticlesTable {
+	for _, v := range mdb.articles {
 		if v.UserID == userID {
-			articles = append(articles, v)
+			articles = append(articles, mdb.withTags(v))
 		}
 	}
 	return articles, nil
 }
 
+//GetArticleBySlug returns the article holding a slug, now or before it moved on to another one
+func (mdb *MockDynamo) GetArticleBySlug(ctx context.Context, slug string) (models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Article{}, err
+	}
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
+	id, ok := mdb.slugs[slug]
+	if !ok {
+		return models.Article{}, ErrResourceNotFound
+	}
+	return mdb.get(id)
+}
+
+//ListArticles returns a page of articles in the order of the query
+func (mdb *MockDynamo) ListArticles(ctx context.Context, q PageQuery) (Page, error) {
+	if err := ctx.Err(); err != nil {
+		return Page{}, err
+	}
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
+	var articles []models.Article
+	for _, v := range mdb.articles {
+		if v = mdb.withTags(v); q.selects(v) {
+			articles = append(articles, v)
+		}
+	}
+	return sortPage(articles, q), nil
+}
+
 //CreateArticle adds a new article into the in-mem mock db
-func (mdb *MockDynamo) CreateArticle(art models.NewArticle) (int, error) {
-	//TODO: Needs cleaner solution - work around memory counter and json decoding
-	var insertArt = models.Article{
-		ArticleID: idCounter,
-		UserID:    art.UserID,
-		Title:     art.Title,
-		Body:      art.Body,
-	}
-	idCounter++
-	mdb.ArticlesTable[insertArt.ArticleID] = insertArt
+func (mdb *MockDynamo) CreateArticle(ctx context.Context, art models.Article) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	mdb.mu.Lock()
+	defer mdb.mu.Unlock()
+	insertArt := art
+	insertArt.ArticleID, insertArt.Version, insertArt.Status, insertArt.Tags = mdb.idCounter, 1, models.StatusDraft, nil
+	if err := mdb.claimSlug(insertArt.ArticleID, insertArt.Slug); err != nil {
+		return 0, err
+	}
+	mdb.idCounter++
+	mdb.articles[insertArt.ArticleID] = insertArt
+	mdb.revisions[insertArt.ArticleID] = []models.Article{insertArt}
 	return insertArt.ArticleID, nil
 }
 
-//UpdateArticle replaces an existing article with a new one
-func (mdb *MockDynamo) UpdateArticle(id int, article models.Article) error {
-	_, err := mdb.GetArticleByID(id)
+//UpdateArticle replaces an existing article with a new one if its version still matches
+func (mdb *MockDynamo) UpdateArticle(ctx context.Context, id int, article models.Article) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	mdb.mu.Lock()
+	defer mdb.mu.Unlock()
+	old, err := mdb.getVersion(id, article.Version)
 	if err != nil {
 		return err
 	}
-	mdb.ArticlesTable[id] = article
+	if err := mdb.claimSlug(id, article.Slug); err != nil {
+		return err
+	}
+	article.ArticleID = id
+	article.Version = old.Version + 1
+	article.Tags = nil
+	mdb.articles[id] = article
+	mdb.revisions[id] = append(mdb.revisions[id], article)
 	return nil
 }
 
-//DeleteArticle removes an article from the in-memory mock db
-func (mdb *MockDynamo) DeleteArticle(id int) error {
-	_, err := mdb.GetArticleByID(id)
+//DeleteArticle removes an article from the in-memory mock db if its version still matches
+func (mdb *MockDynamo) DeleteArticle(ctx context.Context, id, version int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	mdb.mu.Lock()
+	defer mdb.mu.Unlock()
+	_, err := mdb.getVersion(id, version)
 	if err != nil {
 		return err
 	}
-	delete(mdb.ArticlesTable, id)
+	delete(mdb.articles, id)
+	delete(mdb.revisions, id)
+	delete(mdb.tags, id)
+	for slug, holder := range mdb.slugs {
+		if holder == id {
+			delete(mdb.slugs, slug)
+		}
+	}
 	return nil
 }
+
+//GetScheduledArticles returns the articles outside the trash with a change due by t ordered by
+//id, those in review with a publishAt at or before t and those published with an unpublishAt at
+//or before t
+func (mdb *MockDynamo) GetScheduledArticles(ctx context.Context, t time.Time) ([]models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
+	var articles []models.Article
+	for _, v := range mdb.articles {
+		if scheduledBy(v, t) {
+			articles = append(articles, mdb.withTags(v))
+		}
+	}
+	sort.Slice(articles, func(i, j int) bool { return articles[i].ArticleID < articles[j].ArticleID })
+	return articles, nil
+}
+
+//AddTags relates an article to the given tags, tags it already carries are left alone
+func (mdb *MockDynamo) AddTags(ctx context.Context, id int, tags []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	mdb.mu.Lock()
+	defer mdb.mu.Unlock()
+	if _, err := mdb.get(id); err != nil {
+		return err
+	}
+	if mdb.tags[id] == nil {
+		mdb.tags[id] = make(map[string]struct{})
+	}
+	for _, t := range tags {
+		mdb.tags[id][t] = struct{}{}
+	}
+	return nil
+}
+
+//RemoveTags drops the given tags from an article, tags it does not carry are left alone
+func (mdb *MockDynamo) RemoveTags(ctx context.Context, id int, tags []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	mdb.mu.Lock()
+	defer mdb.mu.Unlock()
+	if _, err := mdb.get(id); err != nil {
+		return err
+	}
+	for _, t := range tags {
+		delete(mdb.tags[id], t)
+	}
+	return nil
+}
+
+//GetTagCounts returns every tag carried by an article outside the trash with the number of
+//such articles carrying it, restricted to a single status when status is non-empty
+func (mdb *MockDynamo) GetTagCounts(ctx context.Context, status string) ([]models.TagCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
+	articles := make([]models.Article, 0, len(mdb.tags))
+	for id := range mdb.tags {
+		articles = append(articles, mdb.withTags(mdb.articles[id]))
+	}
+	return countTags(articles, status), nil
+}
+
+//GetRevisions returns every revision of an article ordered by version
+func (mdb *MockDynamo) GetRevisions(ctx context.Context, id int) ([]models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
+	revisions, ok := mdb.revisions[id]
+	if !ok {
+		return nil, ErrResourceNotFound
+	}
+	return append([]models.Article(nil), revisions...), nil
+}
+
+//GetRevision returns the revision of an article at the given version
+func (mdb *MockDynamo) GetRevision(ctx context.Context, id, version int) (models.Article, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Article{}, err
+	}
+	mdb.mu.RLock()
+	defer mdb.mu.RUnlock()
+	//Versions start at 1 and are never skipped so a revision sits at index version-1
+	revisions := mdb.revisions[id]
+	if version < 1 || version > len(revisions) {
+		return models.Article{}, ErrResourceNotFound
+	}
+	return revisions[version-1], nil
+}
+
+//get looks up an article by id, callers must hold mu
+func (mdb *MockDynamo) get(id int) (models.Article, error) {
+	article, ok := mdb.articles[id]
+	if !ok {
+		return models.Article{}, ErrResourceNotFound
+	}
+	return mdb.withTags(article), nil
+}
+
+//withTags fills in the tags of an article in sorted order, callers must hold mu
+func (mdb *MockDynamo) withTags(a models.Article) models.Article {
+	a.Tags = nil
+	for t := range mdb.tags[a.ArticleID] {
+		a.Tags = append(a.Tags, t)
+	}
+	sort.Strings(a.Tags)
+	return a
+}
+
+//claimSlug reserves a slug for an article unless it is empty or the article holds it already,
+//callers must hold mu
+func (mdb *MockDynamo) claimSlug(id int, slug string) error {
+	if slug == "" {
+		return nil
+	}
+	if holder, ok := mdb.slugs[slug]; ok && holder != id {
+		return ErrSlugTaken
+	}
+	mdb.slugs[slug] = id
+	return nil
+}
+
+//getVersion looks up an article by id and checks it is at the expected version, callers must
+//hold mu
+func (mdb *MockDynamo) getVersion(id, version int) (models.Article, error) {
+	article, err := mdb.get(id)
+	if err != nil {
+		return models.Article{}, err
+	}
+	if version != AnyVersion && version != article.Version {
+		return models.Article{}, ErrVersionConflict
+	}
+	return article, nil
+}
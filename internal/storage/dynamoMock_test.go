@@ -0,0 +1,46 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	"github.com/Perezonance/article-management-service/internal/storage/storagetest"
+)
+
+func TestMockDynamoConformance(t *testing.T) {
+	storagetest.RunConformanceTests(t, func(t *testing.T) storage.Storage {
+		return storage.NewMockDynamo()
+	})
+}
+
+func TestMockDynamoIDSequencePerInstance(t *testing.T) {
+	ctx := context.Background()
+	const instances = 8
+	var wg sync.WaitGroup
+	ids := make([][]int, instances)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			db := storage.NewMockDynamo()
+			for n := 0; n < 3; n++ {
+				id, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: fmt.Sprint(n)})
+				if err != nil {
+					t.Errorf("CreateArticle returned %v", err)
+					return
+				}
+				ids[i] = append(ids[i], id)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i, got := range ids {
+		if fmt.Sprint(got) != "[1 2 3]" {
+			t.Fatalf("instance %v issued ids %v, want [1 2 3]", i, got)
+		}
+	}
+}
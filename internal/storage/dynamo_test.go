@@ -0,0 +1,319 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+	"github.com/Perezonance/article-management-service/internal/storage/storagetest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+//newFakeDynamoStorage creates a DynamoStorage with its tables over a FakeDynamo of its own
+func newFakeDynamoStorage(t *testing.T) *storage.DynamoStorage {
+	t.Helper()
+	d := storage.NewDynamoStorage(storagetest.NewFakeDynamo(), "")
+	if err := d.CreateTable(context.Background()); err != nil {
+		t.Fatalf("CreateTable returned %v", err)
+	}
+	return d
+}
+
+func TestDynamoStorageConformance(t *testing.T) {
+	storagetest.RunConformanceTests(t, func(t *testing.T) storage.Storage {
+		return newFakeDynamoStorage(t)
+	})
+}
+
+func TestDynamoCommentStorageConformance(t *testing.T) {
+	storagetest.RunCommentConformanceTests(t, func(t *testing.T) storage.CommentStorage {
+		return newFakeDynamoStorage(t)
+	})
+}
+
+func TestDynamoReactionStorageConformance(t *testing.T) {
+	storagetest.RunReactionConformanceTests(t, func(t *testing.T) storage.ReactionStorage {
+		return newFakeDynamoStorage(t)
+	})
+}
+
+func TestDynamoViewStorageConformance(t *testing.T) {
+	storagetest.RunViewConformanceTests(t, func(t *testing.T) storage.ViewStorage {
+		return newFakeDynamoStorage(t)
+	})
+}
+
+//TestDynamoLocalConformance runs the suite against a DynamoDB compatible endpoint such as
+//DynamoDB Local named by AMS_DYNAMODB_ENDPOINT, credentials and region come from the usual AWS
+//environment variables. Every subtest gets tables of its own
+func TestDynamoLocalConformance(t *testing.T) {
+	endpoint := os.Getenv("AMS_DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("AMS_DYNAMODB_ENDPOINT is not set")
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		t.Fatalf("LoadDefaultConfig returned %v", err)
+	}
+	client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+	})
+	prefix := fmt.Sprintf("ams-test-%v", time.Now().UnixNano())
+	var n int32
+	storagetest.RunConformanceTests(t, func(t *testing.T) storage.Storage {
+		d := storage.NewDynamoStorage(client, fmt.Sprintf("%v-%v", prefix, atomic.AddInt32(&n, 1)))
+		if err := d.CreateTable(context.Background()); err != nil {
+			t.Fatalf("CreateTable returned %v", err)
+		}
+		return d
+	})
+}
+
+//putLegacyDynamoItem stores an article the way the first DynamoStorage release wrote it,
+//without a version, status or itemType, and advances the id sequence past it
+func putLegacyDynamoItem(t *testing.T, client *storagetest.FakeDynamo, id, userID int, title string) {
+	t.Helper()
+	ctx := context.Background()
+	item := map[string]types.AttributeValue{
+		"articleID": &types.AttributeValueMemberN{Value: strconv.Itoa(id)},
+		"userID":    &types.AttributeValueMemberN{Value: strconv.Itoa(userID)},
+		"title":     &types.AttributeValueMemberS{Value: title},
+		"body":      &types.AttributeValueMemberS{Value: "body"},
+	}
+	_, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{{Put: &types.Put{TableName: aws.String(storage.DefaultArticlesTable), Item: item}}},
+	})
+	if err != nil {
+		t.Fatalf("storing legacy item returned %v", err)
+	}
+	_, err = client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(storage.DefaultArticlesTable),
+		Key:                       map[string]types.AttributeValue{"articleID": &types.AttributeValueMemberN{Value: "0"}},
+		UpdateExpression:          aws.String("ADD seq :one"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":one": &types.AttributeValueMemberN{Value: "1"}},
+	})
+	if err != nil {
+		t.Fatalf("advancing id sequence returned %v", err)
+	}
+}
+
+func TestDynamoStorageReadsLegacyItems(t *testing.T) {
+	ctx := context.Background()
+	client := storagetest.NewFakeDynamo()
+	d := storage.NewDynamoStorage(client, "")
+	if err := d.CreateTable(ctx); err != nil {
+		t.Fatalf("CreateTable returned %v", err)
+	}
+	putLegacyDynamoItem(t, client, 1, 7, "legacy")
+
+	a, err := d.GetArticleByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetArticleByID on legacy item returned %v", err)
+	}
+	if a.Version != 1 || a.Status != models.StatusPublished {
+		t.Fatalf("legacy item read at version %v with status %q, want version 1 published", a.Version, a.Status)
+	}
+	page, err := d.ListArticles(ctx, storage.PageQuery{UserID: 7, Status: models.StatusPublished, Limit: 10})
+	if err != nil || len(page.Articles) != 1 {
+		t.Fatalf("ListArticles of published legacy items by user returned %+v, %v", page, err)
+	}
+	if all, err := d.GetAllArticles(ctx); err != nil || len(all) != 1 {
+		t.Fatalf("GetAllArticles with a legacy item returned %v articles and %v", len(all), err)
+	}
+
+	a.Title = "updated"
+	if err := d.UpdateArticle(ctx, 1, a); err != nil {
+		t.Fatalf("UpdateArticle at version 1 of legacy item returned %v", err)
+	}
+	if a, err = d.GetArticleByID(ctx, 1); err != nil || a.Version != 2 || a.Title != "updated" {
+		t.Fatalf("legacy item after update is %+v, %v", a, err)
+	}
+	a.Version = 1
+	if err := d.UpdateArticle(ctx, 1, a); err != storage.ErrVersionConflict {
+		t.Fatalf("UpdateArticle at stale version 1 returned %v, want ErrVersionConflict", err)
+	}
+
+	putLegacyDynamoItem(t, client, 2, 7, "legacy delete")
+	if err := d.DeleteArticle(ctx, 2, 1); err != nil {
+		t.Fatalf("DeleteArticle at version 1 of legacy item returned %v", err)
+	}
+	if id, err := d.CreateArticle(ctx, models.Article{UserID: 7, Title: "new"}); err != nil || id != 3 {
+		t.Fatalf("CreateArticle after legacy items returned %v, %v", id, err)
+	}
+}
+
+func TestDynamoStorageUpgradesLegacyTable(t *testing.T) {
+	ctx := context.Background()
+	client := storagetest.NewFakeDynamo()
+	//The first release created the articles table with the userID index alone
+	_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(storage.DefaultArticlesTable),
+		KeySchema: []types.KeySchemaElement{{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeHash}},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{{
+			IndexName: aws.String(storage.UserIDIndex),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("userID"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeRange},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("creating legacy table returned %v", err)
+	}
+	putLegacyDynamoItem(t, client, 1, 7, "b legacy")
+	putLegacyDynamoItem(t, client, 2, 8, "a legacy")
+
+	d := storage.NewDynamoStorage(client, "")
+	if err := d.CreateTable(ctx); err != nil {
+		t.Fatalf("CreateTable over a legacy table returned %v", err)
+	}
+	out, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(storage.DefaultArticlesTable)})
+	if err != nil || len(out.Table.GlobalSecondaryIndexes) != 5 {
+		t.Fatalf("legacy table after CreateTable is %+v, %v", out, err)
+	}
+
+	n, err := d.Backfill(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("Backfill returned %v, %v, want 2 items rewritten", n, err)
+	}
+	for _, q := range []storage.PageQuery{
+		{Status: models.StatusPublished, Limit: 10},
+		{Sort: storage.SortByCreatedAt, Limit: 10},
+		{Sort: storage.SortByUpdatedAt, Status: models.StatusPublished, Limit: 10},
+		{Sort: storage.SortByTitle, Limit: 10},
+	} {
+		page, err := d.ListArticles(ctx, q)
+		if err != nil || len(page.Articles) != 2 {
+			t.Fatalf("ListArticles(%+v) after Backfill returned %+v, %v", q, page, err)
+		}
+	}
+	if revs, err := d.GetRevisions(ctx, 1); err != nil || len(revs) != 1 || revs[0].Status != models.StatusPublished {
+		t.Fatalf("GetRevisions of a backfilled item returned %+v, %v", revs, err)
+	}
+
+	a, err := d.GetArticleByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetArticleByID on a backfilled item returned %v", err)
+	}
+	a.Status = models.StatusArchived
+	if err := d.UpdateArticle(ctx, 1, a); err != nil {
+		t.Fatalf("archiving a backfilled item returned %v", err)
+	}
+	if n, err := d.Backfill(ctx); err != nil || n != 0 {
+		t.Fatalf("second Backfill returned %v, %v, want nothing rewritten", n, err)
+	}
+	if a, err = d.GetArticleByID(ctx, 1); err != nil || a.Version != 2 || a.Status != models.StatusArchived {
+		t.Fatalf("archived item after second Backfill is %+v, %v", a, err)
+	}
+}
+
+func TestDynamoStorageSortsTitlesWithLowBytes(t *testing.T) {
+	ctx := context.Background()
+	d := newFakeDynamoStorage(t)
+	var articles []models.Article
+	for _, title := range []string{"a\x01", "a", "a\x00b", "a\x00", "ab", "a\x02", "\x00", "a\x01\x00", "a"} {
+		a := models.Article{UserID: 1, Title: title}
+		id, err := d.CreateArticle(ctx, a)
+		if err != nil {
+			t.Fatalf("CreateArticle(%q) returned %v", title, err)
+		}
+		a.ArticleID = id
+		articles = append(articles, a)
+	}
+
+	for _, desc := range []bool{false, true} {
+		q := storage.PageQuery{Sort: storage.SortByTitle, Desc: desc, Limit: 2}
+		want := append([]models.Article(nil), articles...)
+		sort.Slice(want, func(i, j int) bool { return q.Less(want[i], want[j]) })
+		var got []int
+		for {
+			page, err := d.ListArticles(ctx, q)
+			if err != nil {
+				t.Fatalf("ListArticles(%+v) returned %v", q, err)
+			}
+			for _, a := range page.Articles {
+				got = append(got, a.ArticleID)
+			}
+			if !page.More {
+				break
+			}
+			q = q.Next(page.Articles[len(page.Articles)-1])
+		}
+		var wantIDs []int
+		for _, a := range want {
+			wantIDs = append(wantIDs, a.ArticleID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(wantIDs) {
+			t.Fatalf("paging titles with Desc %v returned ids %v, want %v", desc, got, wantIDs)
+		}
+	}
+}
+
+//countingDynamo counts the queries of the tags table and the batch reads made through it
+type countingDynamo struct {
+	*storagetest.FakeDynamo
+	tagQueries, batchGets int
+}
+
+func (c *countingDynamo) Query(ctx context.Context, in *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if aws.ToString(in.TableName) == storage.DefaultArticlesTable+"Tags" {
+		c.tagQueries++
+	}
+	return c.FakeDynamo.Query(ctx, in, opts...)
+}
+
+func (c *countingDynamo) BatchGetItem(ctx context.Context, in *dynamodb.BatchGetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	c.batchGets++
+	return c.FakeDynamo.BatchGetItem(ctx, in, opts...)
+}
+
+func TestDynamoStorageReadsTagsInBatches(t *testing.T) {
+	ctx := context.Background()
+	client := &countingDynamo{FakeDynamo: storagetest.NewFakeDynamo()}
+	d := storage.NewDynamoStorage(client, "")
+	if err := d.CreateTable(ctx); err != nil {
+		t.Fatalf("CreateTable returned %v", err)
+	}
+	for i := 0; i < 30; i++ {
+		id, err := d.CreateArticle(ctx, models.Article{UserID: 1, Title: fmt.Sprint(i)})
+		if err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+		if err := d.AddTags(ctx, id, []string{"go", fmt.Sprint("t", i%3)}); err != nil {
+			t.Fatalf("AddTags returned %v", err)
+		}
+	}
+
+	for _, q := range []storage.PageQuery{
+		{Limit: 25},
+		{Sort: storage.SortByTitle, Limit: 25},
+		{Tag: "go", Limit: 25},
+		{Tag: "go", Sort: storage.SortByCreatedAt, Limit: 25},
+	} {
+		client.tagQueries, client.batchGets = 0, 0
+		page, err := d.ListArticles(ctx, q)
+		if err != nil || len(page.Articles) != q.Limit {
+			t.Fatalf("ListArticles(%+v) returned %v articles, %v", q, len(page.Articles), err)
+		}
+		for _, a := range page.Articles {
+			if len(a.Tags) != 2 {
+				t.Fatalf("ListArticles(%+v) returned article %v tagged %v", q, a.ArticleID, a.Tags)
+			}
+		}
+		//Tag lists, and articles found through the tag index, are read a batch per page of the
+		//underlying query rather than once per article, and a page takes at most two queries
+		if client.tagQueries > 2 || client.batchGets > 4 {
+			t.Fatalf("ListArticles(%+v) made %v queries of the tags table and %v batch reads", q, client.tagQueries, client.batchGets)
+		}
+	}
+}
@@ -5,4 +5,10 @@ import "errors"
 var (
 	//ErrResourceNotFound is thrown when the db cannot return the resource requested
 	ErrResourceNotFound = errors.New("resource requested was not found")
+	//ErrVersionConflict is thrown when a write expects a version other than the stored one
+	ErrVersionConflict = errors.New("resource version does not match")
+	//ErrSlugTaken is thrown when a write claims a slug held by another article
+	ErrSlugTaken = errors.New("slug is held by another article")
+	//ErrCommentHasReplies is thrown when deleting a comment that other comments reply to
+	ErrCommentHasReplies = errors.New("comment has replies")
 )
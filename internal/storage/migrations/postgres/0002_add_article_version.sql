@@ -0,0 +1 @@
+ALTER TABLE articles ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
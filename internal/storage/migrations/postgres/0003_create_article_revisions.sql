@@ -0,0 +1,11 @@
+CREATE TABLE article_revisions (
+    article_id BIGINT  NOT NULL,
+    version    INTEGER NOT NULL,
+    user_id    BIGINT  NOT NULL,
+    title      TEXT    NOT NULL DEFAULT '',
+    body       TEXT    NOT NULL DEFAULT '',
+    PRIMARY KEY (article_id, version)
+);
+
+INSERT INTO article_revisions (article_id, version, user_id, title, body)
+SELECT article_id, version, user_id, title, body FROM articles;
@@ -0,0 +1,17 @@
+ALTER TABLE articles ADD COLUMN status TEXT NOT NULL DEFAULT 'draft';
+ALTER TABLE articles ADD COLUMN transition_from TEXT;
+ALTER TABLE articles ADD COLUMN transition_to TEXT;
+ALTER TABLE articles ADD COLUMN transition_by BIGINT;
+ALTER TABLE articles ADD COLUMN transition_at TIMESTAMPTZ;
+
+ALTER TABLE article_revisions ADD COLUMN status TEXT NOT NULL DEFAULT 'draft';
+ALTER TABLE article_revisions ADD COLUMN transition_from TEXT;
+ALTER TABLE article_revisions ADD COLUMN transition_to TEXT;
+ALTER TABLE article_revisions ADD COLUMN transition_by BIGINT;
+ALTER TABLE article_revisions ADD COLUMN transition_at TIMESTAMPTZ;
+
+-- Articles written before the editorial workflow were already public
+UPDATE articles SET status = 'published';
+UPDATE article_revisions SET status = 'published';
+
+CREATE INDEX idx_articles_status ON articles (status);
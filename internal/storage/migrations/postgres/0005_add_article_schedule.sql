@@ -0,0 +1,8 @@
+ALTER TABLE articles ADD COLUMN publish_at TIMESTAMPTZ;
+ALTER TABLE articles ADD COLUMN unpublish_at TIMESTAMPTZ;
+
+ALTER TABLE article_revisions ADD COLUMN publish_at TIMESTAMPTZ;
+ALTER TABLE article_revisions ADD COLUMN unpublish_at TIMESTAMPTZ;
+
+CREATE INDEX idx_articles_publish_at ON articles (publish_at);
+CREATE INDEX idx_articles_unpublish_at ON articles (unpublish_at);
@@ -0,0 +1,13 @@
+ALTER TABLE articles ADD COLUMN created_at TIMESTAMPTZ;
+ALTER TABLE articles ADD COLUMN updated_at TIMESTAMPTZ;
+
+ALTER TABLE article_revisions ADD COLUMN created_at TIMESTAMPTZ;
+ALTER TABLE article_revisions ADD COLUMN updated_at TIMESTAMPTZ;
+
+-- Articles written before timestamps existed are stamped with the migration time
+UPDATE articles SET created_at = now(), updated_at = now();
+UPDATE article_revisions SET created_at = now(), updated_at = now();
+
+CREATE INDEX idx_articles_created_at ON articles (created_at, article_id);
+CREATE INDEX idx_articles_updated_at ON articles (updated_at, article_id);
+CREATE INDEX idx_articles_title ON articles (title, article_id);
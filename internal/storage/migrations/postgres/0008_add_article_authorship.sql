@@ -0,0 +1,9 @@
+ALTER TABLE articles ADD COLUMN created_by INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE articles ADD COLUMN updated_by INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE article_revisions ADD COLUMN created_by INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE article_revisions ADD COLUMN updated_by INTEGER NOT NULL DEFAULT 0;
+
+-- Articles written before authorship was recorded are credited to their author
+UPDATE articles SET created_by = user_id, updated_by = user_id;
+UPDATE article_revisions SET created_by = user_id, updated_by = user_id;
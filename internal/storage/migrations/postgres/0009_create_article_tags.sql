@@ -0,0 +1,13 @@
+ALTER TABLE articles ADD COLUMN category TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE article_revisions ADD COLUMN category TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_articles_category ON articles (category, article_id);
+
+CREATE TABLE article_tags (
+    article_id BIGINT  NOT NULL,
+    tag        TEXT    NOT NULL,
+    PRIMARY KEY (article_id, tag)
+);
+
+CREATE INDEX idx_article_tags_tag ON article_tags (tag, article_id);
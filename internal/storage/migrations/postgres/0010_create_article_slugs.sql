@@ -0,0 +1,11 @@
+ALTER TABLE articles ADD COLUMN slug TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE article_revisions ADD COLUMN slug TEXT NOT NULL DEFAULT '';
+
+-- Every slug an article has held, articles written before slugs existed get theirs on their next write
+CREATE TABLE article_slugs (
+    slug       TEXT    PRIMARY KEY,
+    article_id BIGINT  NOT NULL
+);
+
+CREATE INDEX idx_article_slugs_article ON article_slugs (article_id);
@@ -0,0 +1,6 @@
+CREATE TABLE article_reactions (
+    article_id BIGINT NOT NULL,
+    user_id    BIGINT NOT NULL,
+    kind       TEXT   NOT NULL,
+    PRIMARY KEY (article_id, user_id, kind)
+);
@@ -0,0 +1,9 @@
+-- Views are counted per article and hour, the hour being the Unix time it starts at
+CREATE TABLE article_views (
+    article_id BIGINT NOT NULL,
+    hour       BIGINT NOT NULL,
+    views      BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (article_id, hour)
+);
+
+CREATE INDEX idx_article_views_hour ON article_views (hour);
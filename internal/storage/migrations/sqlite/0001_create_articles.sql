@@ -0,0 +1,8 @@
+CREATE TABLE articles (
+    article_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL,
+    title      TEXT    NOT NULL DEFAULT '',
+    body       TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_articles_user_id ON articles (user_id);
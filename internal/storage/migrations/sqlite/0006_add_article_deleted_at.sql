@@ -0,0 +1,5 @@
+ALTER TABLE articles ADD COLUMN deleted_at TIMESTAMP;
+
+ALTER TABLE article_revisions ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_articles_deleted_at ON articles (deleted_at);
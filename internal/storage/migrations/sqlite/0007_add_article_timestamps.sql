@@ -0,0 +1,14 @@
+ALTER TABLE articles ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE articles ADD COLUMN updated_at TIMESTAMP;
+
+ALTER TABLE article_revisions ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE article_revisions ADD COLUMN updated_at TIMESTAMP;
+
+-- Articles written before timestamps existed are stamped with the migration time, in the
+-- layout the driver writes times in so that they compare in order as text
+UPDATE articles SET created_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now'), updated_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now');
+UPDATE article_revisions SET created_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now'), updated_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now');
+
+CREATE INDEX idx_articles_created_at ON articles (created_at, article_id);
+CREATE INDEX idx_articles_updated_at ON articles (updated_at, article_id);
+CREATE INDEX idx_articles_title ON articles (title, article_id);
@@ -0,0 +1,15 @@
+-- Comments keep the number of their direct replies so that a comment being replied to cannot be
+-- deleted, top level comments have parent 0
+CREATE TABLE article_comments (
+    comment_id INTEGER   PRIMARY KEY AUTOINCREMENT,
+    article_id INTEGER   NOT NULL,
+    parent_id  INTEGER   NOT NULL DEFAULT 0,
+    user_id    INTEGER   NOT NULL,
+    body       TEXT      NOT NULL DEFAULT '',
+    deleted    BOOLEAN   NOT NULL DEFAULT FALSE,
+    replies    INTEGER   NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_article_comments_thread ON article_comments (article_id, parent_id, comment_id);
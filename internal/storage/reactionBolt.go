@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var reactionsBucket = []byte("reactions")
+
+//AddReaction records that a user reacted to an article with a kind, reporting false when they
+//already had
+func (b *BoltStorage) AddReaction(ctx context.Context, articleID, userID int, kind string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	added := false
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		k := reactionKey(articleID, userID, kind)
+		if tx.Bucket(reactionsBucket).Get(k) != nil {
+			return nil
+		}
+		added = true
+		return tx.Bucket(reactionsBucket).Put(k, []byte{})
+	})
+	return added, err
+}
+
+//RemoveReaction withdraws the reaction of a user to an article with a kind, reporting false when
+//there was none
+func (b *BoltStorage) RemoveReaction(ctx context.Context, articleID, userID int, kind string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	removed := false
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		k := reactionKey(articleID, userID, kind)
+		if tx.Bucket(reactionsBucket).Get(k) == nil {
+			return nil
+		}
+		removed = true
+		return tx.Bucket(reactionsBucket).Delete(k)
+	})
+	return removed, err
+}
+
+//GetReactions returns the reaction counts of each article, counted from its prefix of the
+//reactions bucket, along with the kinds userID reacted with
+func (b *BoltStorage) GetReactions(ctx context.Context, userID int, articleIDs []int) (map[int]models.Reactions, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	res := make(map[int]models.Reactions, len(articleIDs))
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(reactionsBucket).Cursor()
+		for _, id := range articleIDs {
+			r := models.Reactions{Counts: make(map[string]int)}
+			prefix := itob(id)
+			for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				kind := string(k[16:])
+				r.Counts[kind]++
+				if userID != 0 && btoi(k[8:16]) == userID {
+					r.Mine = append(r.Mine, kind)
+				}
+			}
+			sort.Strings(r.Mine)
+			res[id] = r
+		}
+		return nil
+	})
+	return res, err
+}
+
+//DeleteArticleReactions removes every reaction to an article and returns how many there were
+func (b *BoltStorage) DeleteArticleReactions(ctx context.Context, articleID int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	n := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		for _, k := range prefixedKeys(tx, reactionsBucket, itob(articleID)) {
+			if err := tx.Bucket(reactionsBucket).Delete(k); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+//reactionKey builds the reaction key articleID|userID|kind so an article's reactions share a
+//prefix and each user's reactions follow one another
+func reactionKey(articleID, userID int, kind string) []byte {
+	return append(append(itob(articleID), itob(userID)...), kind...)
+}
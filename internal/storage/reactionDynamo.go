@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	//reactionsTableSuffix is appended to the articles table name to name the table holding
+	//reactions, keyed by articleID and reaction
+	reactionsTableSuffix = "Reactions"
+
+	//reactionCountsKey is the reaction range key of the item counting the reactions to an
+	//article, one number attribute per kind. Every other item of the article is a single reaction
+	//keyed kind#userID
+	reactionCountsKey = "#counts"
+
+	//batchGetLimit is the most keys DynamoDB reads in one BatchGetItem
+	batchGetLimit = 100
+
+	//transactAttempts is how many times a transaction canceled by a conflicting one is tried
+	transactAttempts = 5
+)
+
+func (d *DynamoStorage) createReactionsTable(ctx context.Context) error {
+	_, err := d.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(d.reactionsTable),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("articleID"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("reaction"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("reaction"), KeyType: types.KeyTypeRange},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	return ignoreInUse(err)
+}
+
+//AddReaction records that a user reacted to an article with a kind, reporting false when they
+//already had. The reaction item is put and the article's count of the kind incremented in one
+//transaction
+func (d *DynamoStorage) AddReaction(ctx context.Context, articleID, userID int, kind string) (bool, error) {
+	return d.writeReaction(ctx, articleID, kind, 1, types.TransactWriteItem{Put: &types.Put{
+		TableName:           aws.String(d.reactionsTable),
+		Item:                reactionItemKey(articleID, reactionKeyValue(kind, userID)),
+		ConditionExpression: aws.String("attribute_not_exists(reaction)"),
+	}})
+}
+
+//RemoveReaction withdraws the reaction of a user to an article with a kind, reporting false when
+//there was none. The reaction item is deleted and the article's count of the kind decremented
+//in one transaction
+func (d *DynamoStorage) RemoveReaction(ctx context.Context, articleID, userID int, kind string) (bool, error) {
+	return d.writeReaction(ctx, articleID, kind, -1, types.TransactWriteItem{Delete: &types.Delete{
+		TableName:           aws.String(d.reactionsTable),
+		Key:                 reactionItemKey(articleID, reactionKeyValue(kind, userID)),
+		ConditionExpression: aws.String("attribute_exists(reaction)"),
+	}})
+}
+
+//writeReaction applies a conditional write to a reaction item along with adding delta to the
+//article's count of the kind, reporting false when the condition failed and nothing changed
+func (d *DynamoStorage) writeReaction(ctx context.Context, articleID int, kind string, delta int, write types.TransactWriteItem) (bool, error) {
+	count := types.TransactWriteItem{Update: &types.Update{
+		TableName:                 aws.String(d.reactionsTable),
+		Key:                       reactionItemKey(articleID, reactionCountsKey),
+		UpdateExpression:          aws.String("ADD #kind :delta"),
+		ExpressionAttributeNames:  map[string]string{"#kind": kind},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":delta": numberValue(delta)},
+	}}
+	err := d.transact(ctx, []types.TransactWriteItem{write, count})
+	if failed, _ := canceledAt(err); len(failed) > 0 && failed[0] {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+//transact writes items in one transaction, trying again a few times when a concurrent
+//transaction on the same items canceled it
+func (d *DynamoStorage) transact(ctx context.Context, items []types.TransactWriteItem) error {
+	for attempt := 1; ; attempt++ {
+		_, err := d.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+		if _, reasons := canceledAt(err); attempt == transactAttempts || !conflicted(reasons) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+}
+
+//conflicted reports whether a transaction was canceled by a concurrent one
+func conflicted(reasons []types.CancellationReason) bool {
+	for _, r := range reasons {
+		if aws.ToString(r.Code) == "TransactionConflict" {
+			return true
+		}
+	}
+	return false
+}
+
+//GetReactions returns the reaction counts of each article along with the kinds userID reacted
+//with, reading the count item of every article and the item of every known kind by the user in
+//batches
+func (d *DynamoStorage) GetReactions(ctx context.Context, userID int, articleIDs []int) (map[int]models.Reactions, error) {
+	res := make(map[int]models.Reactions, len(articleIDs))
+	var keys []map[string]types.AttributeValue
+	for _, id := range articleIDs {
+		if _, ok := res[id]; ok {
+			continue
+		}
+		res[id] = models.Reactions{Counts: make(map[string]int)}
+		keys = append(keys, reactionItemKey(id, reactionCountsKey))
+		if userID != 0 {
+			for _, kind := range models.ReactionKinds {
+				keys = append(keys, reactionItemKey(id, reactionKeyValue(kind, userID)))
+			}
+		}
+	}
+
+	items, err := d.batchGet(ctx, d.reactionsTable, keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		id, err := numberAttr(item, "articleID")
+		if err != nil {
+			return nil, err
+		}
+		r := res[id]
+		if key := stringAttr(item, "reaction"); key != reactionCountsKey {
+			r.Mine = append(r.Mine, strings.SplitN(key, "#", 2)[0])
+			res[id] = r
+			continue
+		}
+		for name := range item {
+			if name == "articleID" || name == "reaction" {
+				continue
+			}
+			if n, err := numberAttr(item, name); err != nil {
+				return nil, err
+			} else if n > 0 {
+				r.Counts[name] = n
+			}
+		}
+	}
+	for id, r := range res {
+		sort.Strings(r.Mine)
+		res[id] = r
+	}
+	return res, nil
+}
+
+//batchGet reads the items of a table with the given keys, batchGetLimit keys at a time, asking
+//again for the keys DynamoDB left unprocessed
+func (d *DynamoStorage) batchGet(ctx context.Context, table string, keys []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	for start := 0; start < len(keys); start += batchGetLimit {
+		batch := keys[start:]
+		if len(batch) > batchGetLimit {
+			batch = batch[:batchGetLimit]
+		}
+		req := map[string]types.KeysAndAttributes{table: {Keys: batch, ConsistentRead: aws.Bool(true)}}
+		for len(req) > 0 {
+			out, err := d.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: req})
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, out.Responses[table]...)
+			req = out.UnprocessedKeys
+		}
+	}
+	return items, nil
+}
+
+//DeleteArticleReactions removes every reaction to an article along with its count item and
+//returns how many reactions there were
+func (d *DynamoStorage) DeleteArticleReactions(ctx context.Context, articleID int) (int, error) {
+	var (
+		n        int
+		startKey map[string]types.AttributeValue
+	)
+	for {
+		out, err := d.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(d.reactionsTable),
+			KeyConditionExpression:    aws.String("articleID = :id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":id": numberValue(articleID)},
+			ConsistentRead:            aws.Bool(true),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return n, err
+		}
+		for _, item := range out.Items {
+			key := stringAttr(item, "reaction")
+			if _, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(d.reactionsTable),
+				Key:       reactionItemKey(articleID, key),
+			}); err != nil {
+				return n, err
+			}
+			if key != reactionCountsKey {
+				n++
+			}
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return n, nil
+		}
+		startKey = out.LastEvaluatedKey
+	}
+}
+
+func reactionItemKey(articleID int, reaction string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"articleID": numberValue(articleID), "reaction": &types.AttributeValueMemberS{Value: reaction}}
+}
+
+//reactionKeyValue builds the range key kind#userID of a user's reaction
+func reactionKeyValue(kind string, userID int) string {
+	return kind + "#" + strconv.Itoa(userID)
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+)
+
+//MemoryReactionStorage keeps in memory the users reacting to each article with each kind. It is
+//safe for concurrent use
+type MemoryReactionStorage struct {
+	mu        sync.RWMutex
+	reactions map[int]map[string]map[int]struct{}
+}
+
+//NewMemoryReactionStorage creates a MemoryReactionStorage without any reactions
+func NewMemoryReactionStorage() *MemoryReactionStorage {
+	return &MemoryReactionStorage{reactions: make(map[int]map[string]map[int]struct{})}
+}
+
+//AddReaction records that a user reacted to an article with a kind, reporting false when they
+//already had
+func (m *MemoryReactionStorage) AddReaction(ctx context.Context, articleID, userID int, kind string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kinds := m.reactions[articleID]
+	if kinds == nil {
+		kinds = make(map[string]map[int]struct{})
+		m.reactions[articleID] = kinds
+	}
+	users := kinds[kind]
+	if users == nil {
+		users = make(map[int]struct{})
+		kinds[kind] = users
+	}
+	if _, ok := users[userID]; ok {
+		return false, nil
+	}
+	users[userID] = struct{}{}
+	return true, nil
+}
+
+//RemoveReaction withdraws the reaction of a user to an article with a kind, reporting false when
+//there was none
+func (m *MemoryReactionStorage) RemoveReaction(ctx context.Context, articleID, userID int, kind string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	users := m.reactions[articleID][kind]
+	if _, ok := users[userID]; !ok {
+		return false, nil
+	}
+	delete(users, userID)
+	if len(users) == 0 {
+		delete(m.reactions[articleID], kind)
+	}
+	if len(m.reactions[articleID]) == 0 {
+		delete(m.reactions, articleID)
+	}
+	return true, nil
+}
+
+//GetReactions returns the reaction counts of each article along with the kinds userID reacted
+//with
+func (m *MemoryReactionStorage) GetReactions(ctx context.Context, userID int, articleIDs []int) (map[int]models.Reactions, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	res := make(map[int]models.Reactions, len(articleIDs))
+	for _, id := range articleIDs {
+		r := models.Reactions{Counts: make(map[string]int)}
+		for kind, users := range m.reactions[id] {
+			r.Counts[kind] = len(users)
+			if _, ok := users[userID]; ok && userID != 0 {
+				r.Mine = append(r.Mine, kind)
+			}
+		}
+		sort.Strings(r.Mine)
+		res[id] = r
+	}
+	return res, nil
+}
+
+//DeleteArticleReactions removes every reaction to an article and returns how many there were
+func (m *MemoryReactionStorage) DeleteArticleReactions(ctx context.Context, articleID int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, users := range m.reactions[articleID] {
+		n += len(users)
+	}
+	delete(m.reactions, articleID)
+	return n, nil
+}
@@ -0,0 +1,14 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/storage"
+	"github.com/Perezonance/article-management-service/internal/storage/storagetest"
+)
+
+func TestMemoryReactionStorageConformance(t *testing.T) {
+	storagetest.RunReactionConformanceTests(t, func(t *testing.T) storage.ReactionStorage {
+		return storage.NewMemoryReactionStorage()
+	})
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+)
+
+//AddReaction records that a user reacted to an article with a kind, reporting false when they
+//already had
+func (s *SQLStorage) AddReaction(ctx context.Context, articleID, userID int, kind string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, s.rebind(`INSERT INTO article_reactions (article_id, user_id, kind) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`), articleID, userID, kind)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+//RemoveReaction withdraws the reaction of a user to an article with a kind, reporting false when
+//there was none
+func (s *SQLStorage) RemoveReaction(ctx context.Context, articleID, userID int, kind string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM article_reactions WHERE article_id = ? AND user_id = ? AND kind = ?`), articleID, userID, kind)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+//GetReactions returns the reaction counts of each article along with the kinds userID reacted
+//with, counted in SQL for batches of idBatch articles
+func (s *SQLStorage) GetReactions(ctx context.Context, userID int, articleIDs []int) (map[int]models.Reactions, error) {
+	res := make(map[int]models.Reactions, len(articleIDs))
+	for _, id := range articleIDs {
+		res[id] = models.Reactions{Counts: make(map[string]int)}
+	}
+	for start := 0; start < len(articleIDs); start += idBatch {
+		batch := articleIDs[start:]
+		if len(batch) > idBatch {
+			batch = batch[:idBatch]
+		}
+		args := []interface{}{userID}
+		for _, id := range batch {
+			args = append(args, id)
+		}
+		marks := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+		rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT article_id, kind, COUNT(*), SUM(CASE WHEN user_id = ? THEN 1 ELSE 0 END) FROM article_reactions WHERE article_id IN (`+marks+`) GROUP BY article_id, kind`), args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var (
+				id, count, mine int
+				kind            string
+			)
+			if err := rows.Scan(&id, &kind, &count, &mine); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			r := res[id]
+			r.Counts[kind] = count
+			if mine > 0 && userID != 0 {
+				r.Mine = append(r.Mine, kind)
+			}
+			res[id] = r
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	for id, r := range res {
+		sort.Strings(r.Mine)
+		res[id] = r
+	}
+	return res, nil
+}
+
+//DeleteArticleReactions removes every reaction to an article and returns how many there were
+func (s *SQLStorage) DeleteArticleReactions(ctx context.Context, articleID int) (int, error) {
+	res, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM article_reactions WHERE article_id = ?`), articleID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+)
+
+//ReactionStorage defines the behavior for a store of the reactions of users to articles, kept
+//alongside Storage. AddReaction and RemoveReaction take the article, the user and the kind of
+//reaction. A user reacts to an article at most once with each kind so both are idempotent and
+//report whether they changed anything. GetReactions returns the reactions to each of the given
+//articles, with the counts of the kinds at least one user reacted with and, unless the user is
+//0, the kinds that user reacted with in sorted order. Counts are derived from the reactions
+//themselves and stay exact under concurrent writers. The store does not know about articles,
+//callers check the article exists and remove its reactions with DeleteArticleReactions once it
+//is deleted
+type ReactionStorage interface {
+	AddReaction(context.Context, int, int, string) (bool, error)
+	RemoveReaction(context.Context, int, int, string) (bool, error)
+	GetReactions(context.Context, int, []int) (map[int]models.Reactions, error)
+	DeleteArticleReactions(context.Context, int) (int, error)
+}
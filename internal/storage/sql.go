@@ -0,0 +1,548 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+)
+
+const (
+	//DialectSQLite selects SQLite flavoured SQL and migrations
+	DialectSQLite = "sqlite"
+	//DialectPostgres selects PostgreSQL flavoured SQL and migrations
+	DialectPostgres = "postgres"
+)
+
+const (
+	//articleColumns lists the columns of an article row in the order scanArticle reads them
+	articleColumns = `article_id, user_id, title, body, version, status, transition_from, transition_to, transition_by, transition_at, publish_at, unpublish_at, deleted_at, created_at, updated_at, created_by, updated_by, category, slug`
+
+	//recordRevision copies the current state of an article into its revisions
+	recordRevision = `INSERT INTO article_revisions (` + articleColumns + `) SELECT ` + articleColumns + ` FROM articles WHERE article_id = ?`
+
+	//idBatch caps the number of articles whose tags or reactions are read in one query, keeping
+	//the number of bind parameters within every driver's limit
+	idBatch = 500
+)
+
+//sortColumns maps each sort order onto the column holding it
+var sortColumns = map[string]string{
+	"":              "article_id",
+	SortByCreatedAt: "created_at",
+	SortByUpdatedAt: "updated_at",
+	SortByTitle:     "title",
+}
+
+//SQLDrivers maps each supported dialect to the database/sql driver name it is registered under
+var SQLDrivers = map[string]string{
+	DialectSQLite:   "sqlite3",
+	DialectPostgres: "postgres",
+}
+
+//SQLStorage stores articles in a relational database through database/sql
+type SQLStorage struct {
+	db      *sql.DB
+	dialect string
+}
+
+//NewSQLStorage creates a SQLStorage over an open database of the given dialect. The schema
+//is expected to have been brought up to date with Migrate. SQLite databases are limited to a
+//single connection, SQLite serializes writers anyway and a transaction upgrading its read lock
+//to a write lock fails outright when another connection holds one
+func NewSQLStorage(db *sql.DB, dialect string) *SQLStorage {
+	if dialect == DialectSQLite {
+		db.SetMaxOpenConns(1)
+	}
+	return &SQLStorage{db: db, dialect: dialect}
+}
+
+//GetArticleByID returns an article given an id
+func (s *SQLStorage) GetArticleByID(ctx context.Context, id int) (models.Article, error) {
+	return s.queryArticle(ctx, `SELECT `+articleColumns+` FROM articles WHERE article_id = ?`, id)
+}
+
+//GetArticleBySlug returns the article holding a slug, now or before it moved on to another one
+func (s *SQLStorage) GetArticleBySlug(ctx context.Context, slug string) (models.Article, error) {
+	return s.queryArticle(ctx, `SELECT `+articleColumns+` FROM articles WHERE article_id = (SELECT article_id FROM article_slugs WHERE slug = ?)`, slug)
+}
+
+//queryArticle runs a query selecting a single article and fills in its tags
+func (s *SQLStorage) queryArticle(ctx context.Context, query string, args ...interface{}) (models.Article, error) {
+	row := s.db.QueryRowContext(ctx, s.rebind(query), args...)
+
+	a, err := scanArticle(row)
+	if err == sql.ErrNoRows {
+		return models.Article{}, ErrResourceNotFound
+	}
+	if err != nil {
+		return models.Article{}, err
+	}
+	arts := []models.Article{a}
+	if err := s.fillTags(ctx, arts); err != nil {
+		return models.Article{}, err
+	}
+	return arts[0], nil
+}
+
+//GetAllArticles returns all articles in the articles table
+func (s *SQLStorage) GetAllArticles(ctx context.Context) ([]models.Article, error) {
+	return s.queryTagged(ctx, `SELECT `+articleColumns+` FROM articles ORDER BY article_id`)
+}
+
+//GetArticleByUserID returns all articles filtered by a particular userId
+func (s *SQLStorage) GetArticleByUserID(ctx context.Context, userID int) ([]models.Article, error) {
+	return s.queryTagged(ctx, `SELECT `+articleColumns+` FROM articles WHERE user_id = ? ORDER BY article_id`, userID)
+}
+
+//ListArticles returns a page of articles with every filter and the sort order of the query
+//applied in SQL so the indexes on the sort columns are used. Pages continue from the last row's
+//sort value and id, fetching one extra row to learn whether another page follows
+func (s *SQLStorage) ListArticles(ctx context.Context, q PageQuery) (Page, error) {
+	query := `SELECT ` + articleColumns + ` FROM articles WHERE deleted_at IS NULL`
+	if q.Trashed {
+		query = `SELECT ` + articleColumns + ` FROM articles WHERE deleted_at IS NOT NULL`
+	}
+	var args []interface{}
+	where := func(cond string, values ...interface{}) {
+		query += ` AND ` + cond
+		args = append(args, values...)
+	}
+	if q.UserID != 0 {
+		where(`user_id = ?`, q.UserID)
+	}
+	if q.Status != "" {
+		where(`status = ?`, q.Status)
+	}
+	if q.Tag != "" {
+		where(`article_id IN (SELECT article_id FROM article_tags WHERE tag = ?)`, q.Tag)
+	}
+	if q.Category != "" {
+		where(`category = ?`, q.Category)
+	}
+	if !q.CreatedAfter.IsZero() {
+		where(`created_at > ?`, q.CreatedAfter.UTC())
+	}
+	if !q.CreatedBefore.IsZero() {
+		where(`created_at < ?`, q.CreatedBefore.UTC())
+	}
+	if !q.UpdatedAfter.IsZero() {
+		where(`updated_at > ?`, q.UpdatedAfter.UTC())
+	}
+	if !q.UpdatedBefore.IsZero() {
+		where(`updated_at < ?`, q.UpdatedBefore.UTC())
+	}
+
+	col, op, dir := sortColumns[q.Sort], ">", "ASC"
+	if q.Desc {
+		op, dir = "<", "DESC"
+	}
+	order := col + ` ` + dir
+	if col != "article_id" {
+		order += `, article_id ` + dir
+	}
+	if q.AfterID != 0 {
+		var after interface{}
+		switch q.Sort {
+		case SortByCreatedAt, SortByUpdatedAt:
+			after = q.AfterTime.UTC()
+		case SortByTitle:
+			after = q.AfterTitle
+		}
+		if after == nil {
+			where(`article_id `+op+` ?`, q.AfterID)
+		} else {
+			where(`(`+col+` `+op+` ? OR (`+col+` = ? AND article_id `+op+` ?))`, after, after, q.AfterID)
+		}
+	}
+	query += ` ORDER BY ` + order + ` LIMIT ?`
+	args = append(args, q.Limit+1)
+
+	articles, err := s.queryTagged(ctx, query, args...)
+	if err != nil {
+		return Page{}, err
+	}
+	return newPage(articles, q.Limit), nil
+}
+
+//CreateArticle inserts the content, timestamps and authorship of a new article along with its first
+//revision and returns the id issued by the database
+func (s *SQLStorage) CreateArticle(ctx context.Context, art models.Article) (int, error) {
+	var id int
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			s.rebind(`INSERT INTO articles (user_id, title, slug, body, category, status, created_at, updated_at, created_by, updated_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING article_id`),
+			art.UserID, art.Title, art.Slug, art.Body, art.Category, models.StatusDraft, art.CreatedAt.UTC(), art.UpdatedAt.UTC(), art.CreatedBy, art.UpdatedBy,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if err := s.claimSlug(ctx, tx, id, art.Slug); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, s.rebind(recordRevision), id)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+//UpdateArticle replaces an existing article with a new one if its version still matches and
+//records the result as a new revision
+func (s *SQLStorage) UpdateArticle(ctx context.Context, id int, article models.Article) error {
+	args := append([]interface{}{article.UserID, article.Title, article.Slug, article.Body, article.Category, article.Status}, transitionArgs(article.LastTransition)...)
+	args = append(args, nullTime(article.PublishAt), nullTime(article.UnpublishAt), nullTime(article.DeletedAt))
+	args = append(args, article.CreatedAt.UTC(), article.UpdatedAt.UTC(), article.CreatedBy, article.UpdatedBy, id)
+	query, args := versioned(
+		`UPDATE articles SET user_id = ?, title = ?, slug = ?, body = ?, category = ?, status = ?,
+			transition_from = ?, transition_to = ?, transition_by = ?, transition_at = ?,
+			publish_at = ?, unpublish_at = ?, deleted_at = ?, created_at = ?, updated_at = ?,
+			created_by = ?, updated_by = ?, version = version + 1 WHERE article_id = ?`,
+		article.Version, args...,
+	)
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, s.rebind(query), args...)
+		if err != nil {
+			return err
+		}
+		if err := s.requireAffected(ctx, tx, res, id); err != nil {
+			return err
+		}
+		if err := s.claimSlug(ctx, tx, id, article.Slug); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, s.rebind(recordRevision), id)
+		return err
+	})
+}
+
+//DeleteArticle removes an article, its revisions, its tags and its slugs if its version still
+//matches
+func (s *SQLStorage) DeleteArticle(ctx context.Context, id, version int) error {
+	query, args := versioned(`DELETE FROM articles WHERE article_id = ?`, version, id)
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, s.rebind(query), args...)
+		if err != nil {
+			return err
+		}
+		if err := s.requireAffected(ctx, tx, res, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM article_tags WHERE article_id = ?`), id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM article_slugs WHERE article_id = ?`), id); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, s.rebind(`DELETE FROM article_revisions WHERE article_id = ?`), id)
+		return err
+	})
+}
+
+//GetScheduledArticles returns the articles outside the trash with a change due by t ordered by
+//id, those in review with a publishAt at or before t and those published with an unpublishAt at
+//or before t
+func (s *SQLStorage) GetScheduledArticles(ctx context.Context, t time.Time) ([]models.Article, error) {
+	t = t.UTC()
+	return s.queryTagged(ctx, `SELECT `+articleColumns+` FROM articles WHERE ((publish_at <= ? AND status = ?) OR (unpublish_at <= ? AND status = ?))`+
+		` AND deleted_at IS NULL ORDER BY article_id`, t, models.StatusInReview, t, models.StatusPublished)
+}
+
+//AddTags relates an article to the given tags, tags it already carries are left alone
+func (s *SQLStorage) AddTags(ctx context.Context, id int, tags []string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.requireArticle(ctx, tx, id); err != nil {
+			return err
+		}
+		for _, t := range tags {
+			_, err := tx.ExecContext(ctx, s.rebind(`INSERT INTO article_tags (article_id, tag) VALUES (?, ?) ON CONFLICT DO NOTHING`), id, t)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//RemoveTags drops the given tags from an article, tags it does not carry are left alone
+func (s *SQLStorage) RemoveTags(ctx context.Context, id int, tags []string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.requireArticle(ctx, tx, id); err != nil {
+			return err
+		}
+		for _, t := range tags {
+			if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM article_tags WHERE article_id = ? AND tag = ?`), id, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//GetTagCounts returns every tag carried by an article outside the trash with the number of
+//such articles carrying it, restricted to a single status when status is non-empty
+func (s *SQLStorage) GetTagCounts(ctx context.Context, status string) ([]models.TagCount, error) {
+	query := `SELECT t.tag, COUNT(*) FROM article_tags t JOIN articles a ON a.article_id = t.article_id WHERE a.deleted_at IS NULL`
+	var args []interface{}
+	if status != "" {
+		query += ` AND a.status = ?`
+		args = append(args, status)
+	}
+	rows, err := s.db.QueryContext(ctx, s.rebind(query+` GROUP BY t.tag ORDER BY t.tag`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.TagCount{}
+	for rows.Next() {
+		var c models.TagCount
+		if err := rows.Scan(&c.Tag, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+//GetRevisions returns every revision of an article ordered by version
+func (s *SQLStorage) GetRevisions(ctx context.Context, id int) ([]models.Article, error) {
+	revisions, err := s.queryArticles(ctx, `SELECT `+articleColumns+` FROM article_revisions WHERE article_id = ? ORDER BY version`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, ErrResourceNotFound
+	}
+	return revisions, nil
+}
+
+//GetRevision returns the revision of an article at the given version
+func (s *SQLStorage) GetRevision(ctx context.Context, id, version int) (models.Article, error) {
+	row := s.db.QueryRowContext(ctx, s.rebind(`SELECT `+articleColumns+` FROM article_revisions WHERE article_id = ? AND version = ?`), id, version)
+
+	a, err := scanArticle(row)
+	if err == sql.ErrNoRows {
+		return models.Article{}, ErrResourceNotFound
+	}
+	if err != nil {
+		return models.Article{}, err
+	}
+	return a, nil
+}
+
+//inTx runs fn inside a transaction, committing when it succeeds and rolling back otherwise
+func (s *SQLStorage) inTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+//queryTagged runs an articles query and fills in the tags of the articles it returns
+func (s *SQLStorage) queryTagged(ctx context.Context, query string, args ...interface{}) ([]models.Article, error) {
+	articles, err := s.queryArticles(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fillTags(ctx, articles); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
+
+//fillTags reads the tags of the given articles in batches of idBatch and sets them in sorted
+//order
+func (s *SQLStorage) fillTags(ctx context.Context, articles []models.Article) error {
+	byID := make(map[int]*models.Article, len(articles))
+	for i := range articles {
+		byID[articles[i].ArticleID] = &articles[i]
+	}
+	for start := 0; start < len(articles); start += idBatch {
+		batch := articles[start:]
+		if len(batch) > idBatch {
+			batch = batch[:idBatch]
+		}
+		ids := make([]interface{}, len(batch))
+		for i, a := range batch {
+			ids[i] = a.ArticleID
+		}
+		marks := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+		rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT article_id, tag FROM article_tags WHERE article_id IN (`+marks+`) ORDER BY article_id, tag`), ids...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var (
+				id  int
+				tag string
+			)
+			if err := rows.Scan(&id, &tag); err != nil {
+				rows.Close()
+				return err
+			}
+			byID[id].Tags = append(byID[id].Tags, tag)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStorage) queryArticles(ctx context.Context, query string, args ...interface{}) ([]models.Article, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var articles []models.Article
+	for rows.Next() {
+		a, err := scanArticle(rows)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
+
+//scanArticle reads a row selected with articleColumns, the transition columns are null until
+//the article's status first changes
+func scanArticle(row interface{ Scan(...interface{}) error }) (models.Article, error) {
+	var (
+		a                  models.Article
+		from, to           sql.NullString
+		by                 sql.NullInt64
+		at                 sql.NullTime
+		publish, unpublish sql.NullTime
+		deleted            sql.NullTime
+		created, updated   sql.NullTime
+	)
+	err := row.Scan(&a.ArticleID, &a.UserID, &a.Title, &a.Body, &a.Version, &a.Status, &from, &to, &by, &at, &publish, &unpublish, &deleted, &created, &updated, &a.CreatedBy, &a.UpdatedBy, &a.Category, &a.Slug)
+	if err != nil {
+		return models.Article{}, err
+	}
+	if to.Valid {
+		a.LastTransition = &models.Transition{From: from.String, To: to.String, By: int(by.Int64), At: at.Time}
+	}
+	if publish.Valid {
+		a.PublishAt = &publish.Time
+	}
+	if unpublish.Valid {
+		a.UnpublishAt = &unpublish.Time
+	}
+	if deleted.Valid {
+		a.DeletedAt = &deleted.Time
+	}
+	a.CreatedAt, a.UpdatedAt = created.Time.UTC(), updated.Time.UTC()
+	return a, nil
+}
+
+//nullTime converts an optional time into a column value, storing it in UTC so that textual
+//timestamps compare in order
+func nullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+//transitionArgs flattens an article's last transition into the values of the transition columns
+func transitionArgs(t *models.Transition) []interface{} {
+	if t == nil {
+		return []interface{}{nil, nil, nil, nil}
+	}
+	return []interface{}{t.From, t.To, t.By, t.At.UTC()}
+}
+
+func (s *SQLStorage) rebind(query string) string {
+	return rebind(s.dialect, query)
+}
+
+//versioned appends a version check to a statement whose WHERE clause is last unless version
+//is AnyVersion
+func versioned(query string, version int, args ...interface{}) (string, []interface{}) {
+	if version == AnyVersion {
+		return query, args
+	}
+	return query + ` AND version = ?`, append(args, version)
+}
+
+//requireAffected reports ErrResourceNotFound when a statement on article id matched no rows
+//because the article is missing, or ErrVersionConflict when it exists at another version
+func (s *SQLStorage) requireAffected(ctx context.Context, tx *sql.Tx, res sql.Result, id int) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	if err := s.requireArticle(ctx, tx, id); err != nil {
+		return err
+	}
+	return ErrVersionConflict
+}
+
+//claimSlug reserves a slug for an article unless it is empty or the article holds it already.
+//The holder is read back after the insert since a concurrent claim makes it do nothing
+func (s *SQLStorage) claimSlug(ctx context.Context, tx *sql.Tx, id int, slug string) error {
+	if slug == "" {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, s.rebind(`INSERT INTO article_slugs (slug, article_id) VALUES (?, ?) ON CONFLICT DO NOTHING`), slug, id)
+	if err != nil {
+		return err
+	}
+	var holder int
+	if err := tx.QueryRowContext(ctx, s.rebind(`SELECT article_id FROM article_slugs WHERE slug = ?`), slug).Scan(&holder); err != nil {
+		return err
+	}
+	if holder != id {
+		return ErrSlugTaken
+	}
+	return nil
+}
+
+//requireArticle returns ErrResourceNotFound when no article has the given id
+func (s *SQLStorage) requireArticle(ctx context.Context, tx *sql.Tx, id int) error {
+	var exists int
+	err := tx.QueryRowContext(ctx, s.rebind(`SELECT 1 FROM articles WHERE article_id = ?`), id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrResourceNotFound
+	}
+	return err
+}
+
+//rebind rewrites ? placeholders into the numbered $n form PostgreSQL expects
+func rebind(dialect, query string) string {
+	if dialect != DialectPostgres {
+		return query
+	}
+	var (
+		b strings.Builder
+		n = 0
+	)
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
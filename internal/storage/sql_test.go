@@ -0,0 +1,50 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/storage"
+	"github.com/Perezonance/article-management-service/internal/storage/storagetest"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//newSQLiteStorage opens a migrated SQLite database of its own in the test's temporary directory
+func newSQLiteStorage(t *testing.T) *storage.SQLStorage {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "articles.db"))
+	if err != nil {
+		t.Fatalf("sql.Open returned %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := storage.Migrate(context.Background(), db, storage.DialectSQLite); err != nil {
+		t.Fatalf("Migrate returned %v", err)
+	}
+	return storage.NewSQLStorage(db, storage.DialectSQLite)
+}
+
+func TestSQLiteStorageConformance(t *testing.T) {
+	storagetest.RunConformanceTests(t, func(t *testing.T) storage.Storage {
+		return newSQLiteStorage(t)
+	})
+}
+
+func TestSQLiteCommentStorageConformance(t *testing.T) {
+	storagetest.RunCommentConformanceTests(t, func(t *testing.T) storage.CommentStorage {
+		return newSQLiteStorage(t)
+	})
+}
+
+func TestSQLiteReactionStorageConformance(t *testing.T) {
+	storagetest.RunReactionConformanceTests(t, func(t *testing.T) storage.ReactionStorage {
+		return newSQLiteStorage(t)
+	})
+}
+
+func TestSQLiteViewStorageConformance(t *testing.T) {
+	storagetest.RunViewConformanceTests(t, func(t *testing.T) storage.ViewStorage {
+		return newSQLiteStorage(t)
+	})
+}
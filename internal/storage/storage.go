@@ -1,13 +1,225 @@
 package storage
 
-import "github.com/Perezonance/article-management-service/internal/models"
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
 
-//Storage defines the behavior for a db accessing tool
+	"github.com/Perezonance/article-management-service/internal/models"
+)
+
+//AnyVersion may be passed as the expected version of a write to skip the version check
+const AnyVersion = 0
+
+//Storage defines the behavior for a db accessing tool. Every method takes the caller's context
+//so cancellation and deadlines reach the backend
 type Storage interface {
-	GetArticleByID(int) (models.Article, error)
-	GetAllArticles() ([]models.Article, error)
-	GetArticleByUserID(int) ([]models.Article, error)
-	CreateArticle(models.NewArticle) (int, error)
-	UpdateArticle(int, models.Article) error
-	DeleteArticle(int) error
+	//GetArticleByID returns an article along with its sorted tags, in the trash or not
+	GetArticleByID(context.Context, int) (models.Article, error)
+	//GetAllArticles returns every article
+	GetAllArticles(context.Context) ([]models.Article, error)
+	//GetArticleByUserID returns the articles of a user ordered by id
+	GetArticleByUserID(context.Context, int) ([]models.Article, error)
+	//GetArticleBySlug returns the article holding a slug, now or before it moved on to another one
+	GetArticleBySlug(context.Context, string) (models.Article, error)
+	//ListArticles returns a page of articles, leaving the trash out unless the query asks for it
+	ListArticles(context.Context, PageQuery) (Page, error)
+	//CreateArticle stores an article under a fresh id as a draft at version 1 and claims its slug
+	CreateArticle(context.Context, models.Article) (int, error)
+	//UpdateArticle stores the next version of an article if article.Version is still current
+	UpdateArticle(context.Context, int, models.Article) error
+	//DeleteArticle removes an article at a version for good with its revisions, tags and slugs
+	DeleteArticle(context.Context, int, int) error
+	//GetRevisions returns every version an article reached ordered by version
+	GetRevisions(context.Context, int) ([]models.Article, error)
+	//GetRevision returns one version of an article, revisions do not record tags
+	GetRevision(context.Context, int, int) (models.Article, error)
+	//GetScheduledArticles returns the articles outside the trash with a change due by a time
+	GetScheduledArticles(context.Context, time.Time) ([]models.Article, error)
+	//AddTags adds tags to an article without bumping its version
+	AddTags(context.Context, int, []string) error
+	//RemoveTags removes tags from an article without bumping its version
+	RemoveTags(context.Context, int, []string) error
+	//GetTagCounts counts the articles outside the trash carrying each tag, in one status unless ""
+	GetTagCounts(context.Context, string) ([]models.TagCount, error)
+}
+
+//Orders a listing can be sorted in besides the default of ascending articleID, ties are always
+//broken by articleID
+const (
+	SortByCreatedAt = "createdAt"
+	SortByUpdatedAt = "updatedAt"
+	SortByTitle     = "title"
+)
+
+//PageQuery selects one page of a listing of articles. Zero valued filters match every article
+type PageQuery struct {
+	//UserID restricts the page to a single author
+	UserID int
+	//Status restricts the page to articles in one status
+	Status string
+	//Tag restricts the page to articles carrying the tag and Category to those in the category
+	Tag      string
+	Category string
+	//CreatedAfter, CreatedBefore, UpdatedAfter and UpdatedBefore restrict the page to articles
+	//created or updated strictly inside the bounds
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	//Trashed selects articles in the trash instead of those outside it
+	Trashed bool
+	//Sort is one of the SortBy orders, "" sorts by articleID. Desc reverses it
+	Sort string
+	Desc bool
+	//AfterID resumes the listing after the article with that id, which stood at AfterTitle or
+	//AfterTime when sorting by title or a timestamp. 0 starts from the beginning
+	AfterID    int
+	AfterTitle string
+	AfterTime  time.Time
+	//Limit is the maximum number of articles in the page
+	Limit int
+}
+
+//Page is a slice of articles ordered by ascending articleID
+type Page struct {
+	Articles []models.Article
+	//More reports whether further articles follow the last one in Articles
+	More bool
+}
+
+//withLegacyDefaults fills in the fields of an article stored by an older release before they
+//existed. Articles stored before versioning count as version 1 and those stored before the
+//publishing workflow count as published, as every article was public then
+func withLegacyDefaults(a models.Article) models.Article {
+	if a.Version == 0 {
+		a.Version = 1
+	}
+	if a.Status == "" {
+		a.Status = models.StatusPublished
+	}
+	return a
+}
+
+//scheduledBy reports whether an article outside the trash has a change due by t, a publishAt at
+//or before t while in review or an unpublishAt at or before t while published
+func scheduledBy(a models.Article, t time.Time) bool {
+	if a.DeletedAt != nil {
+		return false
+	}
+	return (a.PublishAt != nil && !a.PublishAt.After(t) && a.Status == models.StatusInReview) ||
+		(a.UnpublishAt != nil && !a.UnpublishAt.After(t) && a.Status == models.StatusPublished)
+}
+
+//Matches reports whether an article passes the filters of the query
+func (q PageQuery) Matches(a models.Article) bool {
+	return (q.UserID == 0 || a.UserID == q.UserID) &&
+		(q.Status == "" || a.Status == q.Status) &&
+		(q.Tag == "" || hasTag(a, q.Tag)) &&
+		(q.Category == "" || a.Category == q.Category) &&
+		(a.DeletedAt != nil) == q.Trashed &&
+		within(a.CreatedAt, q.CreatedAfter, q.CreatedBefore) &&
+		within(a.UpdatedAt, q.UpdatedAfter, q.UpdatedBefore)
+}
+
+//selects reports whether an article passes the filters of the query and comes after its cursor
+func (q PageQuery) selects(a models.Article) bool {
+	return q.Matches(a) && (q.AfterID == 0 || q.Less(q.position(), a))
+}
+
+//Less reports whether a comes before b in the sort order of the query
+func (q PageQuery) Less(a, b models.Article) bool {
+	c := 0
+	switch q.Sort {
+	case SortByCreatedAt:
+		c = compareTime(a.CreatedAt, b.CreatedAt)
+	case SortByUpdatedAt:
+		c = compareTime(a.UpdatedAt, b.UpdatedAt)
+	case SortByTitle:
+		c = strings.Compare(a.Title, b.Title)
+	}
+	if c == 0 {
+		c = a.ArticleID - b.ArticleID
+	}
+	if q.Desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+//position returns an article standing where the cursor of the query points in its sort order
+func (q PageQuery) position() models.Article {
+	return models.Article{ArticleID: q.AfterID, Title: q.AfterTitle, CreatedAt: q.AfterTime, UpdatedAt: q.AfterTime}
+}
+
+//Next returns the query for the page following the one that ended with last
+func (q PageQuery) Next(last models.Article) PageQuery {
+	q.AfterID, q.AfterTitle, q.AfterTime = last.ArticleID, "", time.Time{}
+	switch q.Sort {
+	case SortByCreatedAt:
+		q.AfterTime = last.CreatedAt
+	case SortByUpdatedAt:
+		q.AfterTime = last.UpdatedAt
+	case SortByTitle:
+		q.AfterTitle = last.Title
+	}
+	return q
+}
+
+//sortPage orders the articles selected by a query and trims them to a page
+func sortPage(articles []models.Article, q PageQuery) Page {
+	sort.Slice(articles, func(i, j int) bool { return q.Less(articles[i], articles[j]) })
+	return newPage(articles, q.Limit)
+}
+
+func hasTag(a models.Article, tag string) bool {
+	for _, t := range a.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+//countTags tallies the tags of the articles outside the trash, restricted to a single status
+//when status is non-empty, ordered by tag
+func countTags(arts []models.Article, status string) []models.TagCount {
+	counts := map[string]int{}
+	for _, a := range arts {
+		if a.DeletedAt == nil && (status == "" || a.Status == status) {
+			for _, t := range a.Tags {
+				counts[t]++
+			}
+		}
+	}
+	res := make([]models.TagCount, 0, len(counts))
+	for t, n := range counts {
+		res = append(res, models.TagCount{Tag: t, Count: n})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Tag < res[j].Tag })
+	return res
+}
+
+//within reports whether t lies strictly between after and before, a zero bound is open
+func within(t, after, before time.Time) bool {
+	return (after.IsZero() || t.After(after)) && (before.IsZero() || t.Before(before))
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+//newPage trims sorted matches down to the query limit and records whether any were cut
+func newPage(sorted []models.Article, limit int) Page {
+	if len(sorted) > limit {
+		return Page{Articles: sorted[:limit], More: true}
+	}
+	return Page{Articles: sorted}
 }
@@ -0,0 +1,115 @@
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+//RunCommentConformanceTests checks that a CommentStorage implementation honors its contract.
+//newStorage is called once per subtest and must return an empty store
+func RunCommentConformanceTests(t *testing.T, newStorage func(t *testing.T) storage.CommentStorage) {
+	ctx := context.Background()
+
+	t.Run("GetMissingComment", func(t *testing.T) {
+		db := newStorage(t)
+		if _, err := db.GetComment(ctx, missingID); err != storage.ErrResourceNotFound {
+			t.Fatalf("GetComment on missing id returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if err := db.UpdateComment(ctx, models.Comment{CommentID: missingID, Body: "b"}); err != storage.ErrResourceNotFound {
+			t.Fatalf("UpdateComment on missing id returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if err := db.DeleteComment(ctx, missingID); err != storage.ErrResourceNotFound {
+			t.Fatalf("DeleteComment on missing id returned %v, want storage.ErrResourceNotFound", err)
+		}
+	})
+
+	t.Run("Threads", func(t *testing.T) {
+		db := newStorage(t)
+		create := func(articleID, parentID int) int {
+			t.Helper()
+			id, err := db.CreateComment(ctx, models.Comment{ArticleID: articleID, ParentID: parentID, UserID: 1, Body: "b"})
+			if err != nil {
+				t.Fatalf("CreateComment(%v, %v) returned %v", articleID, parentID, err)
+			}
+			return id
+		}
+		first, second := create(1, 0), create(1, 0)
+		reply := create(1, first)
+		sibling := create(1, first)
+		nested := create(1, reply)
+		other := create(2, 0)
+
+		if _, err := db.CreateComment(ctx, models.Comment{ArticleID: 1, ParentID: other, Body: "b"}); err != storage.ErrResourceNotFound {
+			t.Fatalf("CreateComment replying to a comment on another article returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if _, err := db.CreateComment(ctx, models.Comment{ArticleID: 1, ParentID: missingID, Body: "b"}); err != storage.ErrResourceNotFound {
+			t.Fatalf("CreateComment replying to a missing comment returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if c, err := db.GetComment(ctx, first); err != nil || c.Replies != 2 || c.ArticleID != 1 || c.Body != "b" {
+			t.Fatalf("GetComment(%v) = %+v, %v, want 2 replies", first, c, err)
+		}
+
+		for _, tc := range []struct {
+			q    storage.CommentQuery
+			want []int
+		}{
+			{storage.CommentQuery{ArticleID: 1}, []int{first, second}},
+			{storage.CommentQuery{ArticleID: 1, ParentID: first}, []int{reply, sibling}},
+			{storage.CommentQuery{ArticleID: 2}, []int{other}},
+			{storage.CommentQuery{ArticleID: 3}, nil},
+		} {
+			var got []int
+			q := tc.q
+			q.Limit = 1
+			for {
+				page, err := db.ListComments(ctx, q)
+				if err != nil {
+					t.Fatalf("ListComments(%+v) returned %v", q, err)
+				}
+				for _, c := range page.Comments {
+					got = append(got, c.CommentID)
+				}
+				if !page.More {
+					break
+				}
+				q.AfterID = page.Comments[len(page.Comments)-1].CommentID
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Fatalf("paging %+v returned ids %v, want %v", tc.q, got, tc.want)
+			}
+		}
+
+		if err := db.DeleteComment(ctx, reply); err != storage.ErrCommentHasReplies {
+			t.Fatalf("DeleteComment of a comment with replies returned %v, want storage.ErrCommentHasReplies", err)
+		}
+		if err := db.UpdateComment(ctx, models.Comment{CommentID: reply, ArticleID: 9, ParentID: 9, Body: "", Deleted: true}); err != nil {
+			t.Fatalf("UpdateComment returned %v", err)
+		}
+		if c, _ := db.GetComment(ctx, reply); !c.Deleted || c.Body != "" || c.ArticleID != 1 || c.ParentID != first || c.Replies != 1 {
+			t.Fatalf("UpdateComment left %+v, want only the body and deleted flag changed", c)
+		}
+		if err := db.DeleteComment(ctx, nested); err != nil {
+			t.Fatalf("DeleteComment returned %v", err)
+		}
+		if err := db.DeleteComment(ctx, reply); err != nil {
+			t.Fatalf("DeleteComment of a comment whose replies are gone returned %v", err)
+		}
+		if c, _ := db.GetComment(ctx, first); c.Replies != 1 {
+			t.Fatalf("deleting a reply left %v replies on its parent, want 1", c.Replies)
+		}
+
+		if n, err := db.DeleteArticleComments(ctx, 1); err != nil || n != 3 {
+			t.Fatalf("DeleteArticleComments(1) = %v, %v, want 3", n, err)
+		}
+		if _, err := db.GetComment(ctx, first); err != storage.ErrResourceNotFound {
+			t.Fatalf("GetComment after DeleteArticleComments returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if _, err := db.GetComment(ctx, other); err != nil {
+			t.Fatalf("DeleteArticleComments removed a comment on another article, GetComment returned %v", err)
+		}
+	})
+}
@@ -0,0 +1,988 @@
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+//missingID is an article id no conformance test ever creates
+const missingID = 987654321
+
+//RunConformanceTests checks that a Storage implementation honors the contract shared by every
+//backend. newStorage is called once per subtest and must return an empty store
+func RunConformanceTests(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
+	ctx := context.Background()
+
+	t.Run("GetMissingArticle", func(t *testing.T) {
+		db := newStorage(t)
+		if _, err := db.GetArticleByID(ctx, missingID); err != storage.ErrResourceNotFound {
+			t.Fatalf("GetArticleByID on missing id returned %v, want storage.ErrResourceNotFound", err)
+		}
+	})
+
+	t.Run("UpdateMissingArticle", func(t *testing.T) {
+		db := newStorage(t)
+		err := db.UpdateArticle(ctx, missingID, models.Article{ArticleID: missingID, UserID: 1, Title: "t"})
+		if err != storage.ErrResourceNotFound {
+			t.Fatalf("UpdateArticle on missing id returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if _, err := db.GetArticleByID(ctx, missingID); err != storage.ErrResourceNotFound {
+			t.Fatalf("UpdateArticle on missing id created the article, GetArticleByID returned %v", err)
+		}
+	})
+
+	t.Run("DeleteMissingArticle", func(t *testing.T) {
+		db := newStorage(t)
+		if err := db.DeleteArticle(ctx, missingID, storage.AnyVersion); err != storage.ErrResourceNotFound {
+			t.Fatalf("DeleteArticle on missing id returned %v, want storage.ErrResourceNotFound", err)
+		}
+	})
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		db := newStorage(t)
+		created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		in := models.Article{
+			UserID:    7,
+			Title:     "Title",
+			Body:      "Body",
+			Category:  "news",
+			CreatedAt: created,
+			UpdatedAt: created.Add(time.Second),
+			CreatedBy: 8,
+			UpdatedBy: 9,
+		}
+		id, err := db.CreateArticle(ctx, in)
+		if err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+		got, err := db.GetArticleByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetArticleByID(%v) returned %v", id, err)
+		}
+		want := in
+		want.ArticleID, want.Version, want.Status = id, 1, models.StatusDraft
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("GetArticleByID(%v) = %+v, want %+v", id, got, want)
+		}
+	})
+
+	t.Run("UniqueIDs", func(t *testing.T) {
+		db := newStorage(t)
+		seen := make(map[int]bool)
+		for i := 0; i < 20; i++ {
+			id, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: fmt.Sprint(i)})
+			if err != nil {
+				t.Fatalf("CreateArticle returned %v", err)
+			}
+			if seen[id] {
+				t.Fatalf("CreateArticle issued duplicate id %v", id)
+			}
+			seen[id] = true
+		}
+		all, err := db.GetAllArticles(ctx)
+		if err != nil {
+			t.Fatalf("GetAllArticles returned %v", err)
+		}
+		if len(all) != len(seen) {
+			t.Fatalf("GetAllArticles returned %v articles, want %v", len(all), len(seen))
+		}
+	})
+
+	t.Run("FilterByUser", func(t *testing.T) {
+		db := newStorage(t)
+		want := make(map[int][]int)
+		for i := 0; i < 9; i++ {
+			userID := i%3 + 1
+			id, err := db.CreateArticle(ctx, models.Article{UserID: userID, Title: fmt.Sprint(i)})
+			if err != nil {
+				t.Fatalf("CreateArticle returned %v", err)
+			}
+			want[userID] = append(want[userID], id)
+		}
+		for userID, ids := range want {
+			arts, err := db.GetArticleByUserID(ctx, userID)
+			if err != nil {
+				t.Fatalf("GetArticleByUserID(%v) returned %v", userID, err)
+			}
+			if got := articleIDs(arts); fmt.Sprint(got) != fmt.Sprint(ids) {
+				t.Fatalf("GetArticleByUserID(%v) returned ids %v, want %v", userID, got, ids)
+			}
+			for _, a := range arts {
+				if a.UserID != userID {
+					t.Fatalf("GetArticleByUserID(%v) returned article of user %v", userID, a.UserID)
+				}
+			}
+		}
+		arts, err := db.GetArticleByUserID(ctx, missingID)
+		if err != nil || len(arts) != 0 {
+			t.Fatalf("GetArticleByUserID on unknown user returned %v, %v, want no articles", arts, err)
+		}
+	})
+
+	t.Run("ListArticlesPages", func(t *testing.T) {
+		db := newStorage(t)
+		var all, user2 []int
+		for i := 0; i < 7; i++ {
+			userID := i%2 + 1
+			id, err := db.CreateArticle(ctx, models.Article{UserID: userID, Title: fmt.Sprint(i)})
+			if err != nil {
+				t.Fatalf("CreateArticle returned %v", err)
+			}
+			all = append(all, id)
+			if userID == 2 {
+				user2 = append(user2, id)
+			}
+		}
+		for _, tc := range []struct {
+			userID int
+			want   []int
+		}{{0, all}, {2, user2}} {
+			var (
+				got   []int
+				after = 0
+			)
+			for {
+				page, err := db.ListArticles(ctx, storage.PageQuery{UserID: tc.userID, AfterID: after, Limit: 3})
+				if err != nil {
+					t.Fatalf("ListArticles returned %v", err)
+				}
+				if len(page.Articles) > 3 {
+					t.Fatalf("ListArticles returned %v articles, limit was 3", len(page.Articles))
+				}
+				for _, a := range page.Articles {
+					got = append(got, a.ArticleID)
+					after = a.ArticleID
+				}
+				if !page.More {
+					break
+				}
+				if len(page.Articles) == 0 {
+					t.Fatalf("ListArticles reported more articles after an empty page")
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Fatalf("paging user %v returned ids %v, want %v in order", tc.userID, got, tc.want)
+			}
+		}
+		page, err := db.ListArticles(ctx, storage.PageQuery{Limit: len(all)})
+		if err != nil || len(page.Articles) != len(all) || page.More {
+			t.Fatalf("ListArticles with an exact limit returned %v articles, More=%v, %v", len(page.Articles), page.More, err)
+		}
+	})
+
+	t.Run("ListArticlesSortAndFilter", func(t *testing.T) {
+		db := newStorage(t)
+		base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		//Titles and times repeat so that ties have to be broken by id
+		var arts []models.Article
+		for i, title := range []string{"b", "a", "c", "a", "b", "ab"} {
+			a := models.Article{
+				UserID:    i%2 + 1,
+				Title:     title,
+				CreatedAt: base.Add(time.Duration(i/2) * time.Hour),
+				UpdatedAt: base.Add(time.Duration(5-i) * time.Minute),
+			}
+			id, err := db.CreateArticle(ctx, a)
+			if err != nil {
+				t.Fatalf("CreateArticle returned %v", err)
+			}
+			a.ArticleID = id
+			arts = append(arts, a)
+		}
+
+		east := time.FixedZone("east", 3*60*60)
+		for _, q := range []storage.PageQuery{
+			{},
+			{Desc: true},
+			{Sort: storage.SortByCreatedAt},
+			{Sort: storage.SortByCreatedAt, Desc: true},
+			{Sort: storage.SortByUpdatedAt},
+			{Sort: storage.SortByUpdatedAt, Desc: true},
+			{Sort: storage.SortByTitle},
+			{Sort: storage.SortByTitle, Desc: true},
+			{Sort: storage.SortByTitle, UserID: 2},
+			{Sort: storage.SortByCreatedAt, CreatedAfter: base, CreatedBefore: base.Add(2 * time.Hour)},
+			{Sort: storage.SortByCreatedAt, Desc: true, CreatedAfter: base.Add(-time.Hour), UpdatedBefore: base.Add(3 * time.Minute)},
+			{Sort: storage.SortByUpdatedAt, UpdatedAfter: base.Add(time.Minute).In(east), UpdatedBefore: base.Add(4 * time.Minute)},
+			{Sort: storage.SortByTitle, CreatedAfter: base.Add(30 * time.Minute), UpdatedAfter: base},
+			{CreatedBefore: base.Add(time.Hour), Desc: true},
+			{Sort: storage.SortByCreatedAt, CreatedAfter: base.Add(2 * time.Hour), CreatedBefore: base.Add(time.Hour)},
+		} {
+			var want []models.Article
+			for _, a := range arts {
+				if q.Matches(a) {
+					want = append(want, a)
+				}
+			}
+			sort.Slice(want, func(i, j int) bool { return q.Less(want[i], want[j]) })
+
+			var got []models.Article
+			q.Limit = 2
+			for {
+				page, err := db.ListArticles(ctx, q)
+				if err != nil {
+					t.Fatalf("ListArticles(%+v) returned %v", q, err)
+				}
+				got = append(got, page.Articles...)
+				if !page.More {
+					break
+				}
+				if len(page.Articles) == 0 {
+					t.Fatalf("ListArticles reported more articles after an empty page")
+				}
+				q = q.Next(page.Articles[len(page.Articles)-1])
+			}
+			if fmt.Sprint(orderedIDs(got)) != fmt.Sprint(orderedIDs(want)) {
+				t.Fatalf("paging sort %q desc %v returned ids %v, want %v in order", q.Sort, q.Desc, orderedIDs(got), orderedIDs(want))
+			}
+		}
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		db := newStorage(t)
+		var ids []int
+		for i := 0; i < 4; i++ {
+			id, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: fmt.Sprint(3 - i), Category: fmt.Sprint(i % 2)})
+			if err != nil {
+				t.Fatalf("CreateArticle returned %v", err)
+			}
+			ids = append(ids, id)
+		}
+		if err := db.AddTags(ctx, missingID, []string{"a"}); err != storage.ErrResourceNotFound {
+			t.Fatalf("AddTags on missing id returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if err := db.RemoveTags(ctx, missingID, []string{"a"}); err != storage.ErrResourceNotFound {
+			t.Fatalf("RemoveTags on missing id returned %v, want storage.ErrResourceNotFound", err)
+		}
+		for i, tags := range [][]string{{"b", "a"}, {"a"}, {"a", "c"}, {"go lang"}} {
+			if err := db.AddTags(ctx, ids[i], tags); err != nil {
+				t.Fatalf("AddTags returned %v", err)
+			}
+		}
+		if err := db.AddTags(ctx, ids[0], []string{"a"}); err != nil {
+			t.Fatalf("AddTags of a tag already carried returned %v", err)
+		}
+
+		got, err := db.GetArticleByID(ctx, ids[0])
+		if err != nil || fmt.Sprint(got.Tags) != "[a b]" || got.Version != 1 {
+			t.Fatalf("GetArticleByID of a tagged article = %+v, %v, want tags [a b] at version 1", got, err)
+		}
+		got.Title = "updated"
+		if err := db.UpdateArticle(ctx, ids[0], got); err != nil {
+			t.Fatalf("UpdateArticle returned %v", err)
+		}
+		if got, _ := db.GetArticleByID(ctx, ids[0]); fmt.Sprint(got.Tags) != "[a b]" {
+			t.Fatalf("UpdateArticle changed the tags to %v", got.Tags)
+		}
+		if rev, _ := db.GetRevision(ctx, ids[0], 1); len(rev.Tags) != 0 {
+			t.Fatalf("GetRevision returned tags %v, revisions do not record them", rev.Tags)
+		}
+
+		trashed, err := db.GetArticleByID(ctx, ids[2])
+		if err != nil {
+			t.Fatalf("GetArticleByID returned %v", err)
+		}
+		deleted := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		trashed.DeletedAt = &deleted
+		if err := db.UpdateArticle(ctx, ids[2], trashed); err != nil {
+			t.Fatalf("UpdateArticle returned %v", err)
+		}
+		published, err := db.GetArticleByID(ctx, ids[1])
+		if err != nil {
+			t.Fatalf("GetArticleByID returned %v", err)
+		}
+		published.Status = models.StatusPublished
+		if err := db.UpdateArticle(ctx, ids[1], published); err != nil {
+			t.Fatalf("UpdateArticle returned %v", err)
+		}
+
+		for _, tc := range []struct {
+			status string
+			want   string
+		}{
+			{"", "[{a 2} {b 1} {go lang 1}]"},
+			{models.StatusPublished, "[{a 1}]"},
+			{models.StatusArchived, "[]"},
+		} {
+			counts, err := db.GetTagCounts(ctx, tc.status)
+			if err != nil || fmt.Sprint(counts) != tc.want {
+				t.Fatalf("GetTagCounts(%q) = %v, %v, want %v", tc.status, counts, err, tc.want)
+			}
+		}
+
+		for _, tc := range []struct {
+			q    storage.PageQuery
+			want []int
+		}{
+			{storage.PageQuery{Tag: "a"}, []int{ids[0], ids[1]}},
+			{storage.PageQuery{Tag: "a", Desc: true}, []int{ids[1], ids[0]}},
+			{storage.PageQuery{Tag: "a", Sort: storage.SortByTitle}, []int{ids[1], ids[0]}},
+			{storage.PageQuery{Tag: "a", Trashed: true}, []int{ids[2]}},
+			{storage.PageQuery{Tag: "a", Status: models.StatusPublished}, []int{ids[1]}},
+			{storage.PageQuery{Tag: "go lang", UserID: 1}, []int{ids[3]}},
+			{storage.PageQuery{Tag: "missing"}, nil},
+			{storage.PageQuery{Category: "1"}, []int{ids[1], ids[3]}},
+			{storage.PageQuery{Category: "0", Tag: "b"}, []int{ids[0]}},
+		} {
+			var got []int
+			q := tc.q
+			q.Limit = 1
+			for {
+				page, err := db.ListArticles(ctx, q)
+				if err != nil {
+					t.Fatalf("ListArticles(%+v) returned %v", q, err)
+				}
+				got = append(got, orderedIDs(page.Articles)...)
+				for _, a := range page.Articles {
+					if tc.q.Tag != "" && !tagged(a, tc.q.Tag) {
+						t.Fatalf("ListArticles(%+v) returned article %v tagged %v", q, a.ArticleID, a.Tags)
+					}
+				}
+				if !page.More {
+					break
+				}
+				q = q.Next(page.Articles[len(page.Articles)-1])
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Fatalf("paging %+v returned ids %v, want %v", tc.q, got, tc.want)
+			}
+		}
+
+		if err := db.RemoveTags(ctx, ids[0], []string{"b", "unknown"}); err != nil {
+			t.Fatalf("RemoveTags returned %v", err)
+		}
+		if got, _ := db.GetArticleByID(ctx, ids[0]); fmt.Sprint(got.Tags) != "[a]" {
+			t.Fatalf("RemoveTags left tags %v, want [a]", got.Tags)
+		}
+		if err := db.DeleteArticle(ctx, ids[1], storage.AnyVersion); err != nil {
+			t.Fatalf("DeleteArticle returned %v", err)
+		}
+		counts, err := db.GetTagCounts(ctx, "")
+		if err != nil || fmt.Sprint(counts) != "[{a 1} {go lang 1}]" {
+			t.Fatalf("GetTagCounts after delete = %v, %v", counts, err)
+		}
+		if page, err := db.ListArticles(ctx, storage.PageQuery{Tag: "a", Limit: 10}); err != nil || fmt.Sprint(orderedIDs(page.Articles)) != fmt.Sprint([]int{ids[0]}) {
+			t.Fatalf("ListArticles by tag after delete returned %v, %v", orderedIDs(page.Articles), err)
+		}
+
+		//Tags of an article in the trash count again once it is restored
+		if err := db.AddTags(ctx, ids[2], []string{"d"}); err != nil {
+			t.Fatalf("AddTags returned %v", err)
+		}
+		if counts, err := db.GetTagCounts(ctx, ""); err != nil || fmt.Sprint(counts) != "[{a 1} {go lang 1}]" {
+			t.Fatalf("GetTagCounts after tagging a trashed article = %v, %v", counts, err)
+		}
+		restored, err := db.GetArticleByID(ctx, ids[2])
+		if err != nil {
+			t.Fatalf("GetArticleByID returned %v", err)
+		}
+		restored.DeletedAt = nil
+		if err := db.UpdateArticle(ctx, ids[2], restored); err != nil {
+			t.Fatalf("UpdateArticle returned %v", err)
+		}
+		if err := db.RemoveTags(ctx, ids[2], []string{"c"}); err != nil {
+			t.Fatalf("RemoveTags returned %v", err)
+		}
+		for _, tc := range []struct {
+			status string
+			want   string
+		}{
+			{"", "[{a 2} {d 1} {go lang 1}]"},
+			{models.StatusDraft, "[{a 2} {d 1} {go lang 1}]"},
+			{models.StatusPublished, "[]"},
+		} {
+			counts, err := db.GetTagCounts(ctx, tc.status)
+			if err != nil || fmt.Sprint(counts) != tc.want {
+				t.Fatalf("GetTagCounts(%q) after a restore = %v, %v, want %v", tc.status, counts, err, tc.want)
+			}
+		}
+	})
+
+	t.Run("Slugs", func(t *testing.T) {
+		db := newStorage(t)
+		first, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "Hello", Slug: "hello"})
+		if err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+		if _, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "Hello", Slug: "hello"}); err != storage.ErrSlugTaken {
+			t.Fatalf("CreateArticle with a slug in use returned %v, want storage.ErrSlugTaken", err)
+		}
+		second, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "Hello", Slug: "hello-2"})
+		if err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "untitled"}); err != nil {
+				t.Fatalf("CreateArticle without a slug returned %v", err)
+			}
+		}
+		if all, _ := db.GetAllArticles(ctx); len(all) != 4 {
+			t.Fatalf("GetAllArticles returned %v articles, want 4 as a refused create stores nothing", len(all))
+		}
+
+		if got, err := db.GetArticleBySlug(ctx, "hello"); err != nil || got.ArticleID != first || got.Slug != "hello" {
+			t.Fatalf("GetArticleBySlug(hello) = %+v, %v, want article %v", got, err, first)
+		}
+		if _, err := db.GetArticleBySlug(ctx, "missing"); err != storage.ErrResourceNotFound {
+			t.Fatalf("GetArticleBySlug of an unknown slug returned %v, want storage.ErrResourceNotFound", err)
+		}
+
+		a, _ := db.GetArticleByID(ctx, first)
+		a.Title, a.Slug = "World", "world"
+		if err := db.UpdateArticle(ctx, first, a); err != nil {
+			t.Fatalf("UpdateArticle returned %v", err)
+		}
+		for _, slug := range []string{"hello", "world"} {
+			if got, err := db.GetArticleBySlug(ctx, slug); err != nil || got.ArticleID != first || got.Slug != "world" {
+				t.Fatalf("GetArticleBySlug(%v) = %+v, %v, want article %v now at world", slug, got, err, first)
+			}
+		}
+		if rev, _ := db.GetRevision(ctx, first, 1); rev.Slug != "hello" {
+			t.Fatalf("GetRevision(%v, 1) has slug %q, want hello", first, rev.Slug)
+		}
+
+		b, _ := db.GetArticleByID(ctx, second)
+		b.Slug = "hello"
+		if err := db.UpdateArticle(ctx, second, b); err != storage.ErrSlugTaken {
+			t.Fatalf("UpdateArticle to a slug held before by another article returned %v, want storage.ErrSlugTaken", err)
+		}
+		if got, _ := db.GetArticleByID(ctx, second); got.Version != 1 || got.Slug != "hello-2" {
+			t.Fatalf("refused UpdateArticle left %+v, want it unchanged", got)
+		}
+
+		a, _ = db.GetArticleByID(ctx, first)
+		a.Slug = "hello"
+		if err := db.UpdateArticle(ctx, first, a); err != nil {
+			t.Fatalf("UpdateArticle back to a slug held before returned %v", err)
+		}
+
+		if err := db.DeleteArticle(ctx, first, storage.AnyVersion); err != nil {
+			t.Fatalf("DeleteArticle returned %v", err)
+		}
+		if _, err := db.GetArticleBySlug(ctx, "world"); err != storage.ErrResourceNotFound {
+			t.Fatalf("GetArticleBySlug of a deleted article's slug returned %v, want storage.ErrResourceNotFound", err)
+		}
+		for _, slug := range []string{"hello", "world"} {
+			if _, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: slug, Slug: slug}); err != nil {
+				t.Fatalf("CreateArticle with a slug released by a delete returned %v", err)
+			}
+		}
+	})
+
+	t.Run("StatusAndTransition", func(t *testing.T) {
+		db := newStorage(t)
+		var ids []int
+		for i := 0; i < 6; i++ {
+			id, err := db.CreateArticle(ctx, models.Article{UserID: i%2 + 1, Title: fmt.Sprint(i)})
+			if err != nil {
+				t.Fatalf("CreateArticle returned %v", err)
+			}
+			ids = append(ids, id)
+		}
+		at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+		var published []int
+		for _, id := range ids[1:5] {
+			a, err := db.GetArticleByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetArticleByID returned %v", err)
+			}
+			a.Status = models.StatusPublished
+			a.LastTransition = &models.Transition{From: models.StatusInReview, To: models.StatusPublished, By: 9, At: at}
+			if err := db.UpdateArticle(ctx, id, a); err != nil {
+				t.Fatalf("UpdateArticle returned %v", err)
+			}
+			published = append(published, id)
+		}
+
+		got, err := db.GetArticleByID(ctx, ids[1])
+		if err != nil {
+			t.Fatalf("GetArticleByID returned %v", err)
+		}
+		tr := got.LastTransition
+		if got.Status != models.StatusPublished || tr == nil || tr.From != models.StatusInReview || tr.To != models.StatusPublished || tr.By != 9 || !tr.At.Equal(at) {
+			t.Fatalf("GetArticleByID after transition = %+v, transition %+v", got, tr)
+		}
+		if rev, err := db.GetRevision(ctx, ids[1], 2); err != nil || rev.Status != models.StatusPublished {
+			t.Fatalf("GetRevision after transition = %+v, %v, want a published revision", rev, err)
+		}
+
+		var paged []int
+		q := storage.PageQuery{Status: models.StatusPublished, Limit: 1}
+		for {
+			page, err := db.ListArticles(ctx, q)
+			if err != nil {
+				t.Fatalf("ListArticles returned %v", err)
+			}
+			paged = append(paged, articleIDs(page.Articles)...)
+			if !page.More {
+				break
+			}
+			q.AfterID = page.Articles[len(page.Articles)-1].ArticleID
+		}
+		if fmt.Sprint(paged) != fmt.Sprint(published) {
+			t.Fatalf("paging published articles returned ids %v, want %v", paged, published)
+		}
+		page, err := db.ListArticles(ctx, storage.PageQuery{UserID: 1, Status: models.StatusDraft, Limit: 10})
+		if err != nil || fmt.Sprint(articleIDs(page.Articles)) != fmt.Sprint([]int{ids[0]}) {
+			t.Fatalf("ListArticles of user 1 drafts returned %v, %v, want [%v]", articleIDs(page.Articles), err, ids[0])
+		}
+	})
+
+	t.Run("ScheduledArticles", func(t *testing.T) {
+		db := newStorage(t)
+		now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		past, future := now.Add(-time.Minute), now.Add(time.Hour)
+		//Only a publishAt in review and an unpublishAt once published are ever due
+		schedules := []struct {
+			publish, unpublish *time.Time
+			status             string
+		}{
+			{nil, nil, models.StatusInReview},
+			{&past, nil, models.StatusInReview},
+			{&future, nil, models.StatusInReview},
+			{nil, &now, models.StatusPublished},
+			{&future, &future, models.StatusInReview},
+			{&past, nil, models.StatusDraft},
+			{&past, nil, models.StatusPublished},
+			{nil, &past, models.StatusInReview},
+			{nil, &past, models.StatusArchived},
+		}
+		var ids []int
+		for i, sc := range schedules {
+			id, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: fmt.Sprint(i)})
+			if err != nil {
+				t.Fatalf("CreateArticle returned %v", err)
+			}
+			a, err := db.GetArticleByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetArticleByID returned %v", err)
+			}
+			a.PublishAt, a.UnpublishAt, a.Status = sc.publish, sc.unpublish, sc.status
+			if err := db.UpdateArticle(ctx, id, a); err != nil {
+				t.Fatalf("UpdateArticle returned %v", err)
+			}
+			ids = append(ids, id)
+		}
+
+		got, err := db.GetArticleByID(ctx, ids[4])
+		if err != nil || got.PublishAt == nil || !got.PublishAt.Equal(future) || got.UnpublishAt == nil || !got.UnpublishAt.Equal(future) {
+			t.Fatalf("GetArticleByID of a scheduled article = %+v, %v", got, err)
+		}
+		due, err := db.GetScheduledArticles(ctx, now)
+		if err != nil {
+			t.Fatalf("GetScheduledArticles returned %v", err)
+		}
+		if fmt.Sprint(articleIDs(due)) != fmt.Sprint([]int{ids[1], ids[3]}) {
+			t.Fatalf("GetScheduledArticles returned ids %v, want %v", articleIDs(due), []int{ids[1], ids[3]})
+		}
+		due, err = db.GetScheduledArticles(ctx, future.In(time.FixedZone("east", 3*60*60)))
+		if err != nil || len(due) != 4 {
+			t.Fatalf("GetScheduledArticles at a later time returned %v articles, %v, want 4", len(due), err)
+		}
+	})
+
+	t.Run("Trash", func(t *testing.T) {
+		db := newStorage(t)
+		var ids []int
+		for i := 0; i < 3; i++ {
+			id, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: fmt.Sprint(i)})
+			if err != nil {
+				t.Fatalf("CreateArticle returned %v", err)
+			}
+			ids = append(ids, id)
+		}
+		deleted := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		a, err := db.GetArticleByID(ctx, ids[1])
+		if err != nil {
+			t.Fatalf("GetArticleByID returned %v", err)
+		}
+		a.DeletedAt, a.PublishAt = &deleted, &deleted
+		if err := db.UpdateArticle(ctx, ids[1], a); err != nil {
+			t.Fatalf("UpdateArticle returned %v", err)
+		}
+
+		got, err := db.GetArticleByID(ctx, ids[1])
+		if err != nil || got.DeletedAt == nil || !got.DeletedAt.Equal(deleted) {
+			t.Fatalf("GetArticleByID of a trashed article = %+v, %v", got, err)
+		}
+		page, err := db.ListArticles(ctx, storage.PageQuery{Limit: 10})
+		if err != nil || fmt.Sprint(articleIDs(page.Articles)) != fmt.Sprint([]int{ids[0], ids[2]}) {
+			t.Fatalf("ListArticles returned %v, %v, want %v", articleIDs(page.Articles), err, []int{ids[0], ids[2]})
+		}
+		page, err = db.ListArticles(ctx, storage.PageQuery{UserID: 1, Limit: 10, Trashed: true})
+		if err != nil || fmt.Sprint(articleIDs(page.Articles)) != fmt.Sprint([]int{ids[1]}) {
+			t.Fatalf("ListArticles of the trash returned %v, %v, want [%v]", articleIDs(page.Articles), err, ids[1])
+		}
+		if due, err := db.GetScheduledArticles(ctx, deleted); err != nil || len(due) != 0 {
+			t.Fatalf("GetScheduledArticles returned %v articles, %v, want none from the trash", len(due), err)
+		}
+
+		got.DeletedAt = nil
+		if err := db.UpdateArticle(ctx, ids[1], got); err != nil {
+			t.Fatalf("UpdateArticle restoring from the trash returned %v", err)
+		}
+		if page, err := db.ListArticles(ctx, storage.PageQuery{Limit: 10, Trashed: true}); err != nil || len(page.Articles) != 0 {
+			t.Fatalf("ListArticles of the trash after restoring returned %v, %v", articleIDs(page.Articles), err)
+		}
+	})
+
+	t.Run("UpdateReplacesArticle", func(t *testing.T) {
+		db := newStorage(t)
+		id, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "old", Body: "old"})
+		if err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+		want := models.Article{ArticleID: id, UserID: 2, Title: "new", Body: "new", Version: storage.AnyVersion, Status: models.StatusInReview, CreatedBy: 1, UpdatedBy: 2}
+		if err := db.UpdateArticle(ctx, id, want); err != nil {
+			t.Fatalf("UpdateArticle returned %v", err)
+		}
+		want.Version = 2
+		got, err := db.GetArticleByID(ctx, id)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("GetArticleByID after update = %+v, %v, want %+v", got, err, want)
+		}
+		if arts, _ := db.GetArticleByUserID(ctx, 1); len(arts) != 0 {
+			t.Fatalf("article still listed under its previous user after update: %+v", arts)
+		}
+		if arts, _ := db.GetArticleByUserID(ctx, 2); len(arts) != 1 {
+			t.Fatalf("article not listed under its new user after update: %+v", arts)
+		}
+	})
+
+	t.Run("VersionConflict", func(t *testing.T) {
+		db := newStorage(t)
+		id, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "v1"})
+		if err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+		if err := db.UpdateArticle(ctx, id, models.Article{UserID: 1, Title: "v2", Version: 1}); err != nil {
+			t.Fatalf("UpdateArticle at the current version returned %v", err)
+		}
+		if err := db.UpdateArticle(ctx, id, models.Article{UserID: 1, Title: "stale", Version: 1}); err != storage.ErrVersionConflict {
+			t.Fatalf("UpdateArticle at a stale version returned %v, want storage.ErrVersionConflict", err)
+		}
+		if err := db.DeleteArticle(ctx, id, 1); err != storage.ErrVersionConflict {
+			t.Fatalf("DeleteArticle at a stale version returned %v, want storage.ErrVersionConflict", err)
+		}
+		got, err := db.GetArticleByID(ctx, id)
+		if err != nil || got.Title != "v2" || got.Version != 2 {
+			t.Fatalf("stale writes modified the article: %+v, %v", got, err)
+		}
+		if err := db.UpdateArticle(ctx, missingID, models.Article{UserID: 1, Title: "t", Version: 1}); err != storage.ErrResourceNotFound {
+			t.Fatalf("versioned UpdateArticle on missing id returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if err := db.DeleteArticle(ctx, missingID, 1); err != storage.ErrResourceNotFound {
+			t.Fatalf("versioned DeleteArticle on missing id returned %v, want storage.ErrResourceNotFound", err)
+		}
+
+		//Writers racing from the same version must not overwrite each other
+		const writers = 8
+		var (
+			wg  sync.WaitGroup
+			won = make(chan int, writers)
+		)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				err := db.UpdateArticle(ctx, id, models.Article{UserID: 1, Title: fmt.Sprint(i), Version: 2})
+				if err == nil {
+					won <- i
+				} else if err != storage.ErrVersionConflict {
+					t.Errorf("racing UpdateArticle returned %v", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(won)
+		if len(won) != 1 {
+			t.Fatalf("%v racing writers succeeded from the same version, want 1", len(won))
+		}
+		if err := db.DeleteArticle(ctx, id, 3); err != nil {
+			t.Fatalf("DeleteArticle at the current version returned %v", err)
+		}
+	})
+
+	t.Run("Revisions", func(t *testing.T) {
+		db := newStorage(t)
+		id, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "v1", Body: "b"})
+		if err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+		if err := db.UpdateArticle(ctx, id, models.Article{UserID: 2, Title: "v2", Body: "b", Version: 1, Status: models.StatusInReview}); err != nil {
+			t.Fatalf("UpdateArticle returned %v", err)
+		}
+		if err := db.UpdateArticle(ctx, id, models.Article{UserID: 2, Title: "v3", Body: "b"}); err != nil {
+			t.Fatalf("UpdateArticle returned %v", err)
+		}
+		if err := db.UpdateArticle(ctx, id, models.Article{UserID: 2, Title: "stale", Body: "b", Version: 1}); err != storage.ErrVersionConflict {
+			t.Fatalf("stale UpdateArticle returned %v, want storage.ErrVersionConflict", err)
+		}
+
+		revs, err := db.GetRevisions(ctx, id)
+		if err != nil {
+			t.Fatalf("GetRevisions returned %v", err)
+		}
+		var titles []string
+		for i, r := range revs {
+			if r.ArticleID != id || r.Version != i+1 {
+				t.Fatalf("revision %v = %+v, want article %v at version %v", i, r, id, i+1)
+			}
+			titles = append(titles, r.Title)
+		}
+		if fmt.Sprint(titles) != "[v1 v2 v3]" {
+			t.Fatalf("GetRevisions returned titles %v, want [v1 v2 v3]", titles)
+		}
+		want := models.Article{ArticleID: id, UserID: 2, Title: "v2", Body: "b", Version: 2, Status: models.StatusInReview}
+		if got, err := db.GetRevision(ctx, id, 2); err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("GetRevision(%v, 2) = %+v, %v, want %+v", id, got, err, want)
+		}
+		if _, err := db.GetRevision(ctx, id, 4); err != storage.ErrResourceNotFound {
+			t.Fatalf("GetRevision of an unknown version returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if _, err := db.GetRevisions(ctx, missingID); err != storage.ErrResourceNotFound {
+			t.Fatalf("GetRevisions on missing id returned %v, want storage.ErrResourceNotFound", err)
+		}
+
+		if err := db.DeleteArticle(ctx, id, storage.AnyVersion); err != nil {
+			t.Fatalf("DeleteArticle returned %v", err)
+		}
+		if _, err := db.GetRevisions(ctx, id); err != storage.ErrResourceNotFound {
+			t.Fatalf("GetRevisions after delete returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if _, err := db.GetRevision(ctx, id, 1); err != storage.ErrResourceNotFound {
+			t.Fatalf("GetRevision after delete returned %v, want storage.ErrResourceNotFound", err)
+		}
+	})
+
+	t.Run("DeleteRemovesArticle", func(t *testing.T) {
+		db := newStorage(t)
+		id, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "t"})
+		if err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+		if err := db.DeleteArticle(ctx, id, storage.AnyVersion); err != nil {
+			t.Fatalf("DeleteArticle returned %v", err)
+		}
+		if _, err := db.GetArticleByID(ctx, id); err != storage.ErrResourceNotFound {
+			t.Fatalf("GetArticleByID after delete returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if err := db.DeleteArticle(ctx, id, storage.AnyVersion); err != storage.ErrResourceNotFound {
+			t.Fatalf("second DeleteArticle returned %v, want storage.ErrResourceNotFound", err)
+		}
+		if arts, _ := db.GetArticleByUserID(ctx, 1); len(arts) != 0 {
+			t.Fatalf("deleted article still listed under its user: %+v", arts)
+		}
+	})
+
+	t.Run("CanceledContext", func(t *testing.T) {
+		db := newStorage(t)
+		id, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "t"})
+		if err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := db.CreateArticle(canceled, models.Article{UserID: 1, Title: "canceled"}); err == nil {
+			t.Fatalf("CreateArticle with a canceled context succeeded")
+		}
+		if err := db.UpdateArticle(canceled, id, models.Article{ArticleID: id, UserID: 1, Title: "canceled"}); err == nil {
+			t.Fatalf("UpdateArticle with a canceled context succeeded")
+		}
+		if err := db.DeleteArticle(canceled, id, storage.AnyVersion); err == nil {
+			t.Fatalf("DeleteArticle with a canceled context succeeded")
+		}
+		if _, err := db.GetArticleByID(canceled, id); err == nil {
+			t.Fatalf("GetArticleByID with a canceled context succeeded")
+		}
+		got, err := db.GetArticleByID(ctx, id)
+		if err != nil || got.Title != "t" {
+			t.Fatalf("canceled calls modified the article: %+v, %v", got, err)
+		}
+		if all, _ := db.GetAllArticles(ctx); len(all) != 1 {
+			t.Fatalf("canceled CreateArticle stored an article: %+v", all)
+		}
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		db := newStorage(t)
+		shared, err := db.CreateArticle(ctx, models.Article{UserID: 1, Title: "shared", Slug: "shared"})
+		if err != nil {
+			t.Fatalf("CreateArticle returned %v", err)
+		}
+		const workers = 16
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			ids  = make(map[int]bool)
+			errc = make(chan error, workers)
+		)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if err := hammer(ctx, db, shared, i); err != nil {
+					errc <- fmt.Errorf("worker %v: %v", i, err)
+					return
+				}
+				id, err := db.CreateArticle(ctx, models.Article{UserID: i % 4, Title: fmt.Sprint(i), Slug: fmt.Sprintf("worker-%v", i)})
+				if err != nil {
+					errc <- err
+					return
+				}
+				mu.Lock()
+				dup := ids[id]
+				ids[id] = true
+				mu.Unlock()
+				if dup {
+					errc <- fmt.Errorf("duplicate id %v issued concurrently", id)
+					return
+				}
+				if _, err := db.GetArticleByID(ctx, id); err != nil {
+					errc <- err
+					return
+				}
+				err = db.UpdateArticle(ctx, id, models.Article{ArticleID: id, UserID: i % 4, Title: "updated", Slug: fmt.Sprintf("worker-%v-updated", i)})
+				if err != nil {
+					errc <- err
+					return
+				}
+				if i%2 == 0 {
+					if err := db.DeleteArticle(ctx, id, storage.AnyVersion); err != nil {
+						errc <- err
+						return
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errc)
+		for err := range errc {
+			t.Error(err)
+		}
+		all, err := db.GetAllArticles(ctx)
+		if err != nil {
+			t.Fatalf("GetAllArticles returned %v", err)
+		}
+		if len(all) != workers/2+1 {
+			t.Fatalf("GetAllArticles returned %v articles after concurrent run, want %v", len(all), workers/2+1)
+		}
+
+		art, err := db.GetArticleByID(ctx, shared)
+		if err != nil {
+			t.Fatalf("GetArticleByID returned %v", err)
+		}
+		if want := 1 + workers*hammerRounds; art.Version != want {
+			t.Fatalf("shared article reached version %v after concurrent updates, want %v", art.Version, want)
+		}
+		revs, err := db.GetRevisions(ctx, shared)
+		if err != nil {
+			t.Fatalf("GetRevisions returned %v", err)
+		}
+		if len(revs) != art.Version {
+			t.Fatalf("GetRevisions returned %v revisions for version %v", len(revs), art.Version)
+		}
+		if len(art.Tags) != workers+1 {
+			t.Fatalf("shared article carries tags %v after concurrent tagging, want common and one per worker", art.Tags)
+		}
+	})
+}
+
+//hammerRounds is the number of times hammer updates the shared article
+const hammerRounds = 3
+
+//hammer exercises every Storage method against the shared article and one of its own, leaving
+//the shared article tagged with worker and updated hammerRounds times
+func hammer(ctx context.Context, db storage.Storage, shared, worker int) error {
+	tag := fmt.Sprintf("worker-%v", worker)
+	past := time.Now().Add(-time.Hour)
+	own, err := db.CreateArticle(ctx, models.Article{UserID: worker, Title: tag, Slug: "own-" + tag, PublishAt: &past})
+	if err != nil {
+		return fmt.Errorf("CreateArticle: %v", err)
+	}
+	for round := 0; round < hammerRounds; round++ {
+		if err := db.UpdateArticle(ctx, shared, models.Article{ArticleID: shared, UserID: 1, Title: tag, Slug: "shared"}); err != nil {
+			return fmt.Errorf("UpdateArticle: %v", err)
+		}
+		if err := db.AddTags(ctx, shared, []string{tag, "common"}); err != nil {
+			return fmt.Errorf("AddTags: %v", err)
+		}
+		if err := db.AddTags(ctx, own, []string{"scratch"}); err != nil {
+			return fmt.Errorf("AddTags: %v", err)
+		}
+		if err := db.RemoveTags(ctx, own, []string{"scratch"}); err != nil {
+			return fmt.Errorf("RemoveTags: %v", err)
+		}
+		if _, err := db.GetArticleByID(ctx, shared); err != nil {
+			return fmt.Errorf("GetArticleByID: %v", err)
+		}
+		if _, err := db.GetArticleBySlug(ctx, "shared"); err != nil {
+			return fmt.Errorf("GetArticleBySlug: %v", err)
+		}
+		if _, err := db.GetAllArticles(ctx); err != nil {
+			return fmt.Errorf("GetAllArticles: %v", err)
+		}
+		if _, err := db.GetArticleByUserID(ctx, worker); err != nil {
+			return fmt.Errorf("GetArticleByUserID: %v", err)
+		}
+		for _, q := range []storage.PageQuery{{Limit: 5}, {Tag: "common", Sort: storage.SortByUpdatedAt, Desc: true, Limit: 5}, {Status: models.StatusDraft, Sort: storage.SortByTitle, Limit: 5}} {
+			if _, err := db.ListArticles(ctx, q); err != nil {
+				return fmt.Errorf("ListArticles: %v", err)
+			}
+		}
+		revs, err := db.GetRevisions(ctx, shared)
+		if err != nil {
+			return fmt.Errorf("GetRevisions: %v", err)
+		}
+		if _, err := db.GetRevision(ctx, shared, revs[len(revs)-1].Version); err != nil {
+			return fmt.Errorf("GetRevision: %v", err)
+		}
+		if _, err := db.GetScheduledArticles(ctx, time.Now()); err != nil {
+			return fmt.Errorf("GetScheduledArticles: %v", err)
+		}
+		if _, err := db.GetTagCounts(ctx, ""); err != nil {
+			return fmt.Errorf("GetTagCounts: %v", err)
+		}
+	}
+	if err := db.DeleteArticle(ctx, own, storage.AnyVersion); err != nil {
+		return fmt.Errorf("DeleteArticle: %v", err)
+	}
+	return nil
+}
+
+//orderedIDs returns the ids of the given articles in their order
+func orderedIDs(arts []models.Article) []int {
+	ids := make([]int, len(arts))
+	for i, a := range arts {
+		ids[i] = a.ArticleID
+	}
+	return ids
+}
+
+//articleIDs returns the sorted ids of the given articles
+func articleIDs(arts []models.Article) []int {
+	ids := make([]int, len(arts))
+	for i, a := range arts {
+		ids[i] = a.ArticleID
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+//tagged reports whether an article carries the tag
+func tagged(a models.Article, tag string) bool {
+	for _, t := range a.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
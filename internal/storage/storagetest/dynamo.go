@@ -0,0 +1,889 @@
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+//item is a DynamoDB item, a map of attribute names to values
+type item = map[string]types.AttributeValue
+
+//FakeDynamo is an in-process stand-in for the DynamoDB API used by storage.DynamoStorage. It
+//keeps tables in memory, is strongly consistent and understands the subset of condition, key
+//and update expressions DynamoStorage sends, failing loudly on anything else
+type FakeDynamo struct {
+	mu     sync.Mutex
+	tables map[string]*fakeTable
+}
+
+//fakeTable holds the items of a table keyed by their encoded primary key
+type fakeTable struct {
+	key     keySchema
+	indexes map[string]keySchema
+	items   map[string]item
+}
+
+//keySchema names the hash and, when there is one, the range attribute of a table or index
+type keySchema struct {
+	hash, rng string
+}
+
+//NewFakeDynamo creates a FakeDynamo without any tables
+func NewFakeDynamo() *FakeDynamo {
+	return &FakeDynamo{tables: map[string]*fakeTable{}}
+}
+
+//CreateTable creates a table along with its global secondary indexes
+func (f *FakeDynamo) CreateTable(ctx context.Context, in *dynamodb.CreateTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name := aws.ToString(in.TableName)
+	if _, ok := f.tables[name]; ok {
+		return nil, &types.ResourceInUseException{Message: aws.String("table already exists: " + name)}
+	}
+	t := &fakeTable{key: schemaOf(in.KeySchema), indexes: map[string]keySchema{}, items: map[string]item{}}
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		t.indexes[aws.ToString(gsi.IndexName)] = schemaOf(gsi.KeySchema)
+	}
+	f.tables[name] = t
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+//DescribeTable describes the key schema and global secondary indexes of a table, all of which
+//are active as the fake builds indexes at once
+func (f *FakeDynamo) DescribeTable(ctx context.Context, in *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	t, err := f.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	desc := &types.TableDescription{
+		TableName:   in.TableName,
+		TableStatus: types.TableStatusActive,
+		KeySchema:   t.key.elements(),
+	}
+	names := make([]string, 0, len(t.indexes))
+	for name := range t.indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:   aws.String(name),
+			IndexStatus: types.IndexStatusActive,
+			KeySchema:   t.indexes[name].elements(),
+		})
+	}
+	return &dynamodb.DescribeTableOutput{Table: desc}, nil
+}
+
+//UpdateTable creates and deletes global secondary indexes, other updates are not supported
+func (f *FakeDynamo) UpdateTable(ctx context.Context, in *dynamodb.UpdateTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	t, err := f.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range in.GlobalSecondaryIndexUpdates {
+		switch {
+		case u.Create != nil:
+			name := aws.ToString(u.Create.IndexName)
+			if _, ok := t.indexes[name]; ok {
+				return nil, &types.ResourceInUseException{Message: aws.String("index already exists: " + name)}
+			}
+			t.indexes[name] = schemaOf(u.Create.KeySchema)
+		case u.Delete != nil:
+			delete(t.indexes, aws.ToString(u.Delete.IndexName))
+		default:
+			return nil, fmt.Errorf("fake dynamo: unsupported index update")
+		}
+	}
+	return &dynamodb.UpdateTableOutput{}, nil
+}
+
+//GetItem returns the item with the given key, an empty output when there is none
+func (f *FakeDynamo) GetItem(ctx context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	t, err := f.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: copyItem(t.items[t.encode(in.Key)])}, nil
+}
+
+//BatchGetItem returns the items with each of the given keys, leaving out keys without one. Like
+//DynamoDB it refuses more than 100 keys or the same key twice, and it never leaves keys
+//unprocessed
+func (f *FakeDynamo) BatchGetItem(ctx context.Context, in *dynamodb.BatchGetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	out := &dynamodb.BatchGetItemOutput{Responses: map[string][]item{}}
+	n := 0
+	for name, ka := range in.RequestItems {
+		t, err := f.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+		seen := map[string]bool{}
+		for _, key := range ka.Keys {
+			k := t.encode(key)
+			if seen[k] {
+				return nil, fmt.Errorf("fake dynamo: duplicate key in batch get of %v", name)
+			}
+			seen[k] = true
+			if it, ok := t.items[k]; ok {
+				out.Responses[name] = append(out.Responses[name], copyItem(it))
+			}
+		}
+		n += len(ka.Keys)
+	}
+	if n > 100 {
+		return nil, fmt.Errorf("fake dynamo: batch get of %v keys exceeds 100", n)
+	}
+	return out, nil
+}
+
+//UpdateItem applies an update expression made of SET and ADD actions to an item, creating it
+//when it does not exist. SET understands plain values, if_not_exists and the sum of two operands
+func (f *FakeDynamo) UpdateItem(ctx context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	t, err := f.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	k := t.encode(in.Key)
+	cur := t.items[k]
+	if in.ConditionExpression != nil {
+		ok, err := evaluate(aws.ToString(in.ConditionExpression), cur, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			ccf := &types.ConditionalCheckFailedException{Message: aws.String("the conditional request failed")}
+			if in.ReturnValuesOnConditionCheckFailure == types.ReturnValuesOnConditionCheckFailureAllOld {
+				ccf.Item = copyItem(cur)
+			}
+			return nil, ccf
+		}
+	}
+
+	next, updated, err := applyUpdate(cur, in.Key, aws.ToString(in.UpdateExpression), in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	t.items[k] = next
+
+	out := &dynamodb.UpdateItemOutput{}
+	switch in.ReturnValues {
+	case types.ReturnValueUpdatedNew:
+		out.Attributes = updated
+	case types.ReturnValueAllNew:
+		out.Attributes = copyItem(next)
+	}
+	return out, nil
+}
+
+//applyUpdate returns the item an update expression made of SET and ADD actions turns cur into,
+//starting from key when the item does not exist, along with the attributes it updated
+func applyUpdate(cur, key item, expr string, names map[string]string, values item) (item, item, error) {
+	next := copyItem(cur)
+	if next == nil {
+		next = copyItem(key)
+	}
+	updated := item{}
+	u := &exprParser{tokens: tokenize(expr), it: cur, names: names, values: values}
+	for u.peek() != "" {
+		clause := strings.ToUpper(u.next())
+		if clause != "SET" && clause != "ADD" {
+			return nil, nil, fmt.Errorf("fake dynamo: unsupported update expression %q", expr)
+		}
+		for {
+			name := attrName(u.next(), names)
+			var (
+				v   types.AttributeValue
+				err error
+			)
+			if clause == "SET" {
+				if err := u.expect("="); err != nil {
+					return nil, nil, fmt.Errorf("fake dynamo: %v in %q", err, expr)
+				}
+				v, err = u.sum()
+			} else {
+				var add types.AttributeValue
+				if add, err = u.term(); err == nil {
+					v, err = addNumbers(next[name], add)
+				}
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("fake dynamo: %v in %q", err, expr)
+			}
+			next[name] = v
+			updated[name] = v
+			if u.peek() != "," {
+				break
+			}
+			u.next()
+		}
+	}
+	return next, updated, nil
+}
+
+//sum parses an update operand optionally followed by + and a second operand
+func (u *exprParser) sum() (types.AttributeValue, error) {
+	v, err := u.term()
+	if err != nil || u.peek() != "+" {
+		return v, err
+	}
+	u.next()
+	w, err := u.term()
+	if err != nil {
+		return nil, err
+	}
+	return addNumbers(v, w)
+}
+
+//term parses a value, an attribute or if_not_exists(attribute, operand)
+func (u *exprParser) term() (types.AttributeValue, error) {
+	if u.peek() != "if_not_exists" {
+		return u.operand()
+	}
+	u.next()
+	if err := u.expect("("); err != nil {
+		return nil, err
+	}
+	v, err := u.operand()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.expect(","); err != nil {
+		return nil, err
+	}
+	fallback, err := u.term()
+	if err != nil {
+		return nil, err
+	}
+	if err := u.expect(")"); err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return fallback, nil
+	}
+	return v, nil
+}
+
+//addNumbers adds two number values, a missing first value counting as zero
+func addNumbers(a, b types.AttributeValue) (types.AttributeValue, error) {
+	y, ok := b.(*types.AttributeValueMemberN)
+	if !ok {
+		return nil, fmt.Errorf("adding needs number values")
+	}
+	sum, err := strconv.Atoi(y.Value)
+	if err != nil {
+		return nil, err
+	}
+	if a != nil {
+		x, ok := a.(*types.AttributeValueMemberN)
+		if !ok {
+			return nil, fmt.Errorf("adding needs number values")
+		}
+		n, err := strconv.Atoi(x.Value)
+		if err != nil {
+			return nil, err
+		}
+		sum += n
+	}
+	return &types.AttributeValueMemberN{Value: strconv.Itoa(sum)}, nil
+}
+
+//DeleteItem removes the item with the given key if its condition holds, returning the old item
+//when asked
+func (f *FakeDynamo) DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	t, err := f.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	k := t.encode(in.Key)
+	cur := t.items[k]
+	if in.ConditionExpression != nil {
+		ok, err := evaluate(aws.ToString(in.ConditionExpression), cur, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			ccf := &types.ConditionalCheckFailedException{Message: aws.String("the conditional request failed")}
+			if in.ReturnValuesOnConditionCheckFailure == types.ReturnValuesOnConditionCheckFailureAllOld {
+				ccf.Item = copyItem(cur)
+			}
+			return nil, ccf
+		}
+	}
+	delete(t.items, k)
+	out := &dynamodb.DeleteItemOutput{}
+	if in.ReturnValues == types.ReturnValueAllOld {
+		out.Attributes = copyItem(cur)
+	}
+	return out, nil
+}
+
+//Query returns the items of a table or index matching the key condition in key order,
+//evaluating at most Limit of them before applying the filter
+func (f *FakeDynamo) Query(ctx context.Context, in *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	t, err := f.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	schema := t.key
+	if in.IndexName != nil {
+		s, ok := t.indexes[aws.ToString(in.IndexName)]
+		if !ok {
+			return nil, &types.ResourceNotFoundException{Message: aws.String("index not found: " + aws.ToString(in.IndexName))}
+		}
+		schema = s
+	}
+	items := t.sorted(schema, in.ScanIndexForward == nil || *in.ScanIndexForward)
+	page, last, err := t.page(items, schema, in.ExclusiveStartKey, in.Limit, func(it item) (bool, bool, error) {
+		match, err := evaluate(aws.ToString(in.KeyConditionExpression), it, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+		if err != nil || !match || in.FilterExpression == nil {
+			return match, match, err
+		}
+		keep, err := evaluate(aws.ToString(in.FilterExpression), it, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+		return true, keep, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.QueryOutput{Items: page, Count: int32(len(page)), LastEvaluatedKey: last}, nil
+}
+
+//Scan returns the items of a table passing the filter in primary key order, evaluating at most
+//Limit of them
+func (f *FakeDynamo) Scan(ctx context.Context, in *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	t, err := f.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	items := t.sorted(t.key, true)
+	page, last, err := t.page(items, t.key, in.ExclusiveStartKey, in.Limit, func(it item) (bool, bool, error) {
+		if in.FilterExpression == nil {
+			return true, true, nil
+		}
+		keep, err := evaluate(aws.ToString(in.FilterExpression), it, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+		return true, keep, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.ScanOutput{Items: page, Count: int32(len(page)), LastEvaluatedKey: last}, nil
+}
+
+//TransactWriteItems applies puts, updates, deletes and condition checks all together once every
+//condition holds. Otherwise nothing is written and the cancellation reasons name the failing
+//conditions in the order of the writes
+func (f *FakeDynamo) TransactWriteItems(ctx context.Context, in *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type write struct {
+		t    *fakeTable
+		k    string
+		next item
+	}
+	var (
+		writes   []write
+		reasons  = make([]types.CancellationReason, len(in.TransactItems))
+		canceled bool
+	)
+	for i, ti := range in.TransactItems {
+		var (
+			table, cond *string
+			key, next   item
+			names       map[string]string
+			values      item
+			onFailure   types.ReturnValuesOnConditionCheckFailure
+			remove      bool
+			update      *types.Update
+		)
+		switch {
+		case ti.Put != nil:
+			p := ti.Put
+			table, cond, names, values, onFailure, next = p.TableName, p.ConditionExpression, p.ExpressionAttributeNames, p.ExpressionAttributeValues, p.ReturnValuesOnConditionCheckFailure, p.Item
+		case ti.Update != nil:
+			u := ti.Update
+			table, cond, names, values, onFailure, key, update = u.TableName, u.ConditionExpression, u.ExpressionAttributeNames, u.ExpressionAttributeValues, u.ReturnValuesOnConditionCheckFailure, u.Key, u
+		case ti.Delete != nil:
+			d := ti.Delete
+			table, cond, names, values, onFailure, key, remove = d.TableName, d.ConditionExpression, d.ExpressionAttributeNames, d.ExpressionAttributeValues, d.ReturnValuesOnConditionCheckFailure, d.Key, true
+		case ti.ConditionCheck != nil:
+			c := ti.ConditionCheck
+			table, cond, names, values, onFailure, key = c.TableName, c.ConditionExpression, c.ExpressionAttributeNames, c.ExpressionAttributeValues, c.ReturnValuesOnConditionCheckFailure, c.Key
+		default:
+			return nil, fmt.Errorf("fake dynamo: unsupported transaction item %v", i)
+		}
+		t, err := f.table(table)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			key = next
+		}
+		k := t.encode(key)
+		cur := t.items[k]
+
+		reasons[i].Code = aws.String("None")
+		if cond != nil {
+			ok, err := evaluate(aws.ToString(cond), cur, names, values)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				canceled = true
+				reasons[i].Code = aws.String("ConditionalCheckFailed")
+				if onFailure == types.ReturnValuesOnConditionCheckFailureAllOld {
+					reasons[i].Item = copyItem(cur)
+				}
+				continue
+			}
+		}
+		if update != nil {
+			if next, _, err = applyUpdate(cur, key, aws.ToString(update.UpdateExpression), names, values); err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case remove:
+			writes = append(writes, write{t: t, k: k})
+		case next != nil:
+			writes = append(writes, write{t: t, k: k, next: copyItem(next)})
+		}
+	}
+	if canceled {
+		return nil, &types.TransactionCanceledException{Message: aws.String("transaction canceled"), CancellationReasons: reasons}
+	}
+	for _, w := range writes {
+		if w.next == nil {
+			delete(w.t.items, w.k)
+		} else {
+			w.t.items[w.k] = w.next
+		}
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+//table looks up a table by name. Callers must hold mu
+func (f *FakeDynamo) table(name *string) (*fakeTable, error) {
+	t, ok := f.tables[aws.ToString(name)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("table not found: " + aws.ToString(name))}
+	}
+	return t, nil
+}
+
+//encode renders the primary key attributes of an item as a map key
+func (t *fakeTable) encode(it item) string {
+	k := valueString(it[t.key.hash])
+	if t.key.rng != "" {
+		k += "\x00" + valueString(it[t.key.rng])
+	}
+	return k
+}
+
+//sorted returns the items carrying the key attributes of schema ordered by them, ties broken by
+//the table's primary key. Items without them stay out just as they do of a sparse index
+func (t *fakeTable) sorted(schema keySchema, forward bool) []item {
+	var items []item
+	for _, it := range t.items {
+		if it[schema.hash] != nil && (schema.rng == "" || it[schema.rng] != nil) {
+			items = append(items, it)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		for _, name := range []string{schema.hash, schema.rng, t.key.hash, t.key.rng} {
+			if name == "" {
+				continue
+			}
+			if c := compare(items[i][name], items[j][name]); c != 0 {
+				return (c < 0) == forward
+			}
+		}
+		return false
+	})
+	return items
+}
+
+//page walks items from just after the one named by start, reading at most limit of them. match
+//reports whether an item is read at all and whether it is kept. The last evaluated key is
+//returned when the limit cut the walk short
+func (t *fakeTable) page(items []item, schema keySchema, start item, limit *int32, match func(item) (bool, bool, error)) ([]item, item, error) {
+	keyNames := []string{t.key.hash, t.key.rng, schema.hash, schema.rng}
+	i := 0
+	if len(start) > 0 {
+		for i < len(items) && !sameKey(items[i], start, keyNames) {
+			i++
+		}
+		i++
+	}
+	var (
+		page []item
+		read int32
+	)
+	for ; i < len(items); i++ {
+		if limit != nil && read == *limit {
+			last := item{}
+			for _, name := range keyNames {
+				if name != "" {
+					last[name] = items[i-1][name]
+				}
+			}
+			return page, last, nil
+		}
+		evaluated, keep, err := match(items[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if evaluated {
+			read++
+		}
+		if keep {
+			page = append(page, copyItem(items[i]))
+		}
+	}
+	return page, nil, nil
+}
+
+//sameKey reports whether an item carries the key attributes of key
+func sameKey(it, key item, names []string) bool {
+	for _, name := range names {
+		if name != "" && key[name] != nil && compare(it[name], key[name]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func schemaOf(elems []types.KeySchemaElement) keySchema {
+	var s keySchema
+	for _, e := range elems {
+		if e.KeyType == types.KeyTypeHash {
+			s.hash = aws.ToString(e.AttributeName)
+		} else {
+			s.rng = aws.ToString(e.AttributeName)
+		}
+	}
+	return s
+}
+
+//elements renders the schema as the key schema elements DynamoDB describes it with
+func (s keySchema) elements() []types.KeySchemaElement {
+	elems := []types.KeySchemaElement{{AttributeName: aws.String(s.hash), KeyType: types.KeyTypeHash}}
+	if s.rng != "" {
+		elems = append(elems, types.KeySchemaElement{AttributeName: aws.String(s.rng), KeyType: types.KeyTypeRange})
+	}
+	return elems
+}
+
+func copyItem(it item) item {
+	if it == nil {
+		return nil
+	}
+	c := make(item, len(it))
+	for k, v := range it {
+		c[k] = v
+	}
+	return c
+}
+
+//valueString renders a scalar attribute value tagged with its type
+func valueString(v types.AttributeValue) string {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberS:
+		return "S" + v.Value
+	case *types.AttributeValueMemberN:
+		return "N" + v.Value
+	case *types.AttributeValueMemberBOOL:
+		return fmt.Sprint("B", v.Value)
+	}
+	return ""
+}
+
+//compare orders two scalar values of the same type, numbers by value and strings bytewise. A
+//missing value sorts first
+func compare(a, b types.AttributeValue) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if an, ok := a.(*types.AttributeValueMemberN); ok {
+		if bn, ok := b.(*types.AttributeValueMemberN); ok {
+			x, _ := strconv.ParseFloat(an.Value, 64)
+			y, _ := strconv.ParseFloat(bn.Value, 64)
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(valueString(a), valueString(b))
+}
+
+func attrName(token string, names map[string]string) string {
+	if strings.HasPrefix(token, "#") {
+		if n, ok := names[token]; ok {
+			return n
+		}
+	}
+	return token
+}
+
+//evaluate reports whether an item, nil when it does not exist, satisfies a condition, key
+//condition or filter expression
+func evaluate(expr string, it item, names map[string]string, values item) (bool, error) {
+	p := &exprParser{tokens: tokenize(expr), it: it, names: names, values: values}
+	ok, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	if err != nil {
+		return false, fmt.Errorf("fake dynamo: expression %q: %v", expr, err)
+	}
+	return ok, nil
+}
+
+//tokenize splits an expression into names, placeholders, operators and parentheses
+func tokenize(expr string) []string {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ':
+			i++
+		case strings.IndexByte("(),", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		case strings.IndexByte("=<>", c) >= 0:
+			j := i + 1
+			for j < len(expr) && strings.IndexByte("=<>", expr[j]) >= 0 {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		default:
+			j := i
+			for j < len(expr) && strings.IndexByte(" (),=<>", expr[j]) < 0 {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+//exprParser evaluates an expression by recursive descent as it parses it
+type exprParser struct {
+	tokens []string
+	pos    int
+	it     item
+	names  map[string]string
+	values item
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *exprParser) expect(t string) error {
+	if got := p.next(); got != t {
+		return fmt.Errorf("expected %q, got %q", t, got)
+	}
+	return nil
+}
+
+func (p *exprParser) or() (bool, error) {
+	ok, err := p.and()
+	for err == nil && strings.EqualFold(p.peek(), "OR") {
+		p.next()
+		var right bool
+		right, err = p.and()
+		ok = ok || right
+	}
+	return ok, err
+}
+
+func (p *exprParser) and() (bool, error) {
+	ok, err := p.unary()
+	for err == nil && strings.EqualFold(p.peek(), "AND") {
+		p.next()
+		var right bool
+		right, err = p.unary()
+		ok = ok && right
+	}
+	return ok, err
+}
+
+func (p *exprParser) unary() (bool, error) {
+	switch tok := p.peek(); {
+	case strings.EqualFold(tok, "NOT"):
+		p.next()
+		ok, err := p.unary()
+		return !ok, err
+	case tok == "(":
+		p.next()
+		ok, err := p.or()
+		if err != nil {
+			return false, err
+		}
+		return ok, p.expect(")")
+	case tok == "attribute_exists" || tok == "attribute_not_exists":
+		p.next()
+		if err := p.expect("("); err != nil {
+			return false, err
+		}
+		name := attrName(p.next(), p.names)
+		if err := p.expect(")"); err != nil {
+			return false, err
+		}
+		_, exists := p.it[name]
+		return exists == (tok == "attribute_exists"), nil
+	}
+	return p.comparison()
+}
+
+func (p *exprParser) comparison() (bool, error) {
+	left, err := p.operand()
+	if err != nil {
+		return false, err
+	}
+	op := p.next()
+	if strings.EqualFold(op, "BETWEEN") {
+		lo, err := p.operand()
+		if err != nil {
+			return false, err
+		}
+		if !strings.EqualFold(p.next(), "AND") {
+			return false, fmt.Errorf("BETWEEN without AND")
+		}
+		hi, err := p.operand()
+		if err != nil {
+			return false, err
+		}
+		return left != nil && lo != nil && hi != nil && compare(lo, left) <= 0 && compare(left, hi) <= 0, nil
+	}
+	right, err := p.operand()
+	if err != nil {
+		return false, err
+	}
+	if left == nil || right == nil {
+		return false, nil
+	}
+	c := compare(left, right)
+	switch op {
+	case "=":
+		return c == 0, nil
+	case "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("unsupported operator %q", op)
+}
+
+//operand resolves a value placeholder or an attribute of the item, nil when it is missing
+func (p *exprParser) operand() (types.AttributeValue, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case strings.HasPrefix(tok, ":"):
+		v, ok := p.values[tok]
+		if !ok {
+			return nil, fmt.Errorf("undefined value %v", tok)
+		}
+		return v, nil
+	case strings.HasPrefix(tok, "#"):
+		n, ok := p.names[tok]
+		if !ok {
+			return nil, fmt.Errorf("undefined name %v", tok)
+		}
+		return p.it[n], nil
+	}
+	return p.it[tok], nil
+}
@@ -0,0 +1,84 @@
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/models"
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+//RunReactionConformanceTests checks that a ReactionStorage implementation honors its contract.
+//newStorage is called once per subtest and must return an empty store
+func RunReactionConformanceTests(t *testing.T, newStorage func(t *testing.T) storage.ReactionStorage) {
+	ctx := context.Background()
+
+	t.Run("Idempotent", func(t *testing.T) {
+		db := newStorage(t)
+		for i, want := range []bool{true, false} {
+			if changed, err := db.AddReaction(ctx, 1, 7, models.ReactionLike); err != nil || changed != want {
+				t.Fatalf("AddReaction call %v = %v, %v, want %v", i+1, changed, err, want)
+			}
+		}
+		db.AddReaction(ctx, 1, 7, models.ReactionWow)
+		db.AddReaction(ctx, 1, 8, models.ReactionLike)
+		db.AddReaction(ctx, 2, 7, models.ReactionSad)
+
+		got, err := db.GetReactions(ctx, 7, []int{1, 2, 3})
+		if err != nil {
+			t.Fatalf("GetReactions returned %v", err)
+		}
+		for id, want := range map[int]string{
+			1: "{map[like:2 wow:1] [like wow]}",
+			2: "{map[sad:1] [sad]}",
+			3: "{map[] []}",
+		} {
+			if fmt.Sprint(got[id]) != want {
+				t.Fatalf("GetReactions for article %v = %v, want %v", id, got[id], want)
+			}
+		}
+		if got, _ := db.GetReactions(ctx, 0, []int{1}); got[1].Mine != nil || got[1].Counts[models.ReactionLike] != 2 {
+			t.Fatalf("GetReactions without a user = %v, want counts only", got[1])
+		}
+
+		for i, want := range []bool{true, false} {
+			if changed, err := db.RemoveReaction(ctx, 1, 7, models.ReactionLike); err != nil || changed != want {
+				t.Fatalf("RemoveReaction call %v = %v, %v, want %v", i+1, changed, err, want)
+			}
+		}
+		if got, _ := db.GetReactions(ctx, 7, []int{1}); fmt.Sprint(got[1]) != "{map[like:1 wow:1] [wow]}" {
+			t.Fatalf("GetReactions after RemoveReaction = %v", got[1])
+		}
+
+		if n, err := db.DeleteArticleReactions(ctx, 1); err != nil || n != 2 {
+			t.Fatalf("DeleteArticleReactions(1) = %v, %v, want 2", n, err)
+		}
+		if got, _ := db.GetReactions(ctx, 7, []int{1, 2}); len(got[1].Counts) != 0 || got[2].Counts[models.ReactionSad] != 1 {
+			t.Fatalf("GetReactions after DeleteArticleReactions(1) = %v", got)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		db := newStorage(t)
+		const users = 50
+		var wg sync.WaitGroup
+		for u := 1; u <= users; u++ {
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func(u int) {
+					defer wg.Done()
+					db.AddReaction(ctx, 1, u, models.ReactionLove)
+					db.AddReaction(ctx, 1, u, models.ReactionLike)
+					db.RemoveReaction(ctx, 1, u, models.ReactionLike)
+				}(u)
+			}
+		}
+		wg.Wait()
+		got, err := db.GetReactions(ctx, 0, []int{1})
+		if err != nil || got[1].Counts[models.ReactionLove] != users || got[1].Counts[models.ReactionLike] != 0 {
+			t.Fatalf("GetReactions after concurrent writers = %v, %v, want %v loves and no likes", got[1], err, users)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Perezonance/article-management-service/internal/storage"
+)
+
+//RunViewConformanceTests checks that a ViewStorage implementation honors its contract.
+//newStorage is called once per subtest and must return an empty store
+func RunViewConformanceTests(t *testing.T, newStorage func(t *testing.T) storage.ViewStorage) {
+	ctx := context.Background()
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Windows", func(t *testing.T) {
+		db := newStorage(t)
+		err := db.AddViews(ctx, []storage.ViewCount{
+			{ArticleID: 1, At: base.Add(5 * time.Minute), Views: 2},
+			{ArticleID: 1, At: base.Add(50 * time.Minute), Views: 1},
+			{ArticleID: 2, At: base.Add(2 * time.Hour), Views: 3},
+			{ArticleID: 3, At: base.Add(2*time.Hour + time.Minute), Views: 3},
+			{ArticleID: 4, At: base.Add(-time.Hour), Views: 9},
+		})
+		if err != nil {
+			t.Fatalf("AddViews returned %v", err)
+		}
+		db.AddViews(ctx, []storage.ViewCount{{ArticleID: 1, At: base.Add(3 * time.Hour), Views: 1}})
+
+		for _, tc := range []struct {
+			since time.Time
+			want  string
+		}{
+			{base.Add(-2 * time.Hour), "[4:9 1:4 2:3 3:3]"},
+			{base.Add(30 * time.Minute), "[1:4 2:3 3:3]"},
+			{base.Add(time.Hour), "[2:3 3:3 1:1]"},
+			{base.Add(4 * time.Hour), "[]"},
+		} {
+			counts, err := db.GetViewCounts(ctx, tc.since)
+			if err != nil {
+				t.Fatalf("GetViewCounts(%v) returned %v", tc.since, err)
+			}
+			if got := formatViewCounts(counts); got != tc.want {
+				t.Fatalf("GetViewCounts(%v) = %v, want %v", tc.since, got, tc.want)
+			}
+		}
+
+		if n, err := db.DeleteViewsBefore(ctx, base.Add(time.Hour)); err != nil || n != 12 {
+			t.Fatalf("DeleteViewsBefore = %v, %v, want 12", n, err)
+		}
+		if n, err := db.DeleteArticleViews(ctx, 2); err != nil || n != 3 {
+			t.Fatalf("DeleteArticleViews(2) = %v, %v, want 3", n, err)
+		}
+		counts, _ := db.GetViewCounts(ctx, base.Add(-2*time.Hour))
+		if got := formatViewCounts(counts); got != "[3:3 1:1]" {
+			t.Fatalf("GetViewCounts after deletes = %v, want [3:3 1:1]", got)
+		}
+	})
+}
+
+//formatViewCounts renders view totals as articleID:views pairs
+func formatViewCounts(counts []storage.ViewCount) string {
+	s := make([]string, len(counts))
+	for i, c := range counts {
+		s[i] = fmt.Sprintf("%v:%v", c.ArticleID, c.Views)
+	}
+	return fmt.Sprint(s)
+}
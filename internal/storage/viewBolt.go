@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var viewsBucket = []byte("views")
+
+//AddViews adds each count to the hour of its article it falls in, all in one transaction
+func (b *BoltStorage) AddViews(ctx context.Context, counts []ViewCount) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(viewsBucket)
+		for _, c := range counts {
+			k := viewKey(c.At, c.ArticleID)
+			n := c.Views
+			if v := bkt.Get(k); v != nil {
+				n += btoi(v)
+			}
+			if err := bkt.Put(k, itob(n)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//GetViewCounts totals the views of each article from the hour since falls in onwards, walking
+//the views bucket from that hour, most viewed first
+func (b *BoltStorage) GetViewCounts(ctx context.Context, since time.Time) ([]ViewCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	totals := map[int]int{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(viewsBucket).Cursor()
+		for k, v := c.Seek(itob(int(viewHour(since).Unix()))); k != nil; k, v = c.Next() {
+			totals[btoi(k[8:])] += btoi(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]ViewCount, 0, len(totals))
+	for id, n := range totals {
+		if n > 0 {
+			counts = append(counts, ViewCount{ArticleID: id, Views: n})
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Views != counts[j].Views {
+			return counts[i].Views > counts[j].Views
+		}
+		return counts[i].ArticleID < counts[j].ArticleID
+	})
+	return counts, nil
+}
+
+//DeleteViewsBefore drops the hours ending at or before t, which lead the views bucket, and
+//returns how many views they held
+func (b *BoltStorage) DeleteViewsBefore(ctx context.Context, t time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	n := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		past := itob(int(t.Add(-time.Hour).Unix()))
+		var keys [][]byte
+		c := tx.Bucket(viewsBucket).Cursor()
+		for k, v := c.First(); k != nil && bytes.Compare(k[:8], past) <= 0; k, v = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+			n += btoi(v)
+		}
+		for _, k := range keys {
+			if err := tx.Bucket(viewsBucket).Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+//DeleteArticleViews drops every view of an article and returns how many there were. Views are
+//keyed by hour first, so every hour is looked at
+func (b *BoltStorage) DeleteArticleViews(ctx context.Context, articleID int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	n := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		id := itob(articleID)
+		var keys [][]byte
+		err := tx.Bucket(viewsBucket).ForEach(func(k, v []byte) error {
+			if bytes.Equal(k[8:], id) {
+				keys = append(keys, append([]byte(nil), k...))
+				n += btoi(v)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := tx.Bucket(viewsBucket).Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+//viewKey builds the view key hour|articleID from the start of the hour t falls in, in Unix
+//seconds, so the views bucket is ordered by hour
+func viewKey(t time.Time, articleID int) []byte {
+	return append(itob(int(viewHour(t).Unix())), itob(articleID)...)
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	//viewsTableSuffix is appended to the articles table name to name the table holding view
+	//counts, keyed by articleID and the Unix time of the hour they were made in
+	viewsTableSuffix = "Views"
+
+	//transactWriteLimit is the most items DynamoDB writes in one transaction
+	transactWriteLimit = 100
+)
+
+//viewHourName maps the hour attribute, a reserved word in DynamoDB expressions
+var viewHourName = map[string]string{"#hour": "hour"}
+
+func (d *DynamoStorage) createViewsTable(ctx context.Context) error {
+	_, err := d.client.CreateTable(ctx, &dynamodb.CreateTableInput{
+		TableName: aws.String(d.viewsTable),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("articleID"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("hour"), AttributeType: types.ScalarAttributeTypeN},
+		},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("articleID"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("hour"), KeyType: types.KeyTypeRange},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+	return ignoreInUse(err)
+}
+
+//AddViews adds each count to the item of its article and hour. Counts sharing an item are
+//merged first and the items are written transactWriteLimit to a transaction
+func (d *DynamoStorage) AddViews(ctx context.Context, counts []ViewCount) error {
+	type key struct{ articleID, hour int }
+	merged := make(map[key]int)
+	var keys []key
+	for _, c := range counts {
+		k := key{c.ArticleID, int(viewHour(c.At).Unix())}
+		if _, ok := merged[k]; !ok {
+			keys = append(keys, k)
+		}
+		merged[k] += c.Views
+	}
+
+	for start := 0; start < len(keys); start += transactWriteLimit {
+		batch := keys[start:]
+		if len(batch) > transactWriteLimit {
+			batch = batch[:transactWriteLimit]
+		}
+		items := make([]types.TransactWriteItem, len(batch))
+		for i, k := range batch {
+			items[i] = types.TransactWriteItem{Update: &types.Update{
+				TableName:                 aws.String(d.viewsTable),
+				Key:                       viewItemKey(k.articleID, k.hour),
+				UpdateExpression:          aws.String("ADD views :n"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{":n": numberValue(merged[k])},
+			}}
+		}
+		if err := d.transact(ctx, items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//GetViewCounts totals the views of each article from the hour since falls in onwards by scanning
+//the views table, most viewed first
+func (d *DynamoStorage) GetViewCounts(ctx context.Context, since time.Time) ([]ViewCount, error) {
+	totals := map[int]int{}
+	err := d.scanViews(ctx, "#hour >= :hour", int(viewHour(since).Unix()), func(id, _, views int) error {
+		totals[id] += views
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]ViewCount, 0, len(totals))
+	for id, n := range totals {
+		if n > 0 {
+			counts = append(counts, ViewCount{ArticleID: id, Views: n})
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Views != counts[j].Views {
+			return counts[i].Views > counts[j].Views
+		}
+		return counts[i].ArticleID < counts[j].ArticleID
+	})
+	return counts, nil
+}
+
+//DeleteViewsBefore deletes the items of the hours ending at or before t, found by scanning the
+//views table, and returns how many views they held
+func (d *DynamoStorage) DeleteViewsBefore(ctx context.Context, t time.Time) (int, error) {
+	n := 0
+	err := d.scanViews(ctx, "#hour <= :hour", int(t.Add(-time.Hour).Unix()), func(id, hour, views int) error {
+		if _, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(d.viewsTable),
+			Key:       viewItemKey(id, hour),
+		}); err != nil {
+			return err
+		}
+		n += views
+		return nil
+	})
+	return n, err
+}
+
+//DeleteArticleViews deletes every item of an article from the views table and returns how many
+//views there were
+func (d *DynamoStorage) DeleteArticleViews(ctx context.Context, articleID int) (int, error) {
+	var (
+		n        int
+		startKey map[string]types.AttributeValue
+	)
+	for {
+		out, err := d.client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(d.viewsTable),
+			KeyConditionExpression:    aws.String("articleID = :id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":id": numberValue(articleID)},
+			ConsistentRead:            aws.Bool(true),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return n, err
+		}
+		for _, item := range out.Items {
+			hour, views, err := viewItemCount(item)
+			if err != nil {
+				return n, err
+			}
+			if _, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(d.viewsTable),
+				Key:       viewItemKey(articleID, hour),
+			}); err != nil {
+				return n, err
+			}
+			n += views
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return n, nil
+		}
+		startKey = out.LastEvaluatedKey
+	}
+}
+
+//scanViews calls fn with the article, hour and views of every item of the views table whose
+//hour passes the filter against :hour
+func (d *DynamoStorage) scanViews(ctx context.Context, filter string, hour int, fn func(id, hour, views int) error) error {
+	var startKey map[string]types.AttributeValue
+	for {
+		out, err := d.client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:                 aws.String(d.viewsTable),
+			FilterExpression:          aws.String(filter),
+			ExpressionAttributeNames:  viewHourName,
+			ExpressionAttributeValues: map[string]types.AttributeValue{":hour": numberValue(hour)},
+			ConsistentRead:            aws.Bool(true),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return err
+		}
+		for _, item := range out.Items {
+			id, err := numberAttr(item, "articleID")
+			if err != nil {
+				return err
+			}
+			hour, views, err := viewItemCount(item)
+			if err != nil {
+				return err
+			}
+			if err := fn(id, hour, views); err != nil {
+				return err
+			}
+		}
+		if len(out.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		startKey = out.LastEvaluatedKey
+	}
+}
+
+func viewItemKey(articleID, hour int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"articleID": numberValue(articleID), "hour": numberValue(hour)}
+}
+
+//viewItemCount reads the hour and views of a views table item
+func viewItemCount(item map[string]types.AttributeValue) (int, int, error) {
+	hour, err := numberAttr(item, "hour")
+	if err != nil {
+		return 0, 0, err
+	}
+	views, err := numberAttr(item, "views")
+	return hour, views, err
+}
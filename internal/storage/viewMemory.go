@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+//MemoryViewStorage keeps in memory the number of views of each article per hour. It is safe for
+//concurrent use
+type MemoryViewStorage struct {
+	mu    sync.RWMutex
+	views map[int]map[time.Time]int
+}
+
+//NewMemoryViewStorage creates a MemoryViewStorage without any views
+func NewMemoryViewStorage() *MemoryViewStorage {
+	return &MemoryViewStorage{views: make(map[int]map[time.Time]int)}
+}
+
+//AddViews adds each count to the hour of its article it falls in
+func (m *MemoryViewStorage) AddViews(ctx context.Context, counts []ViewCount) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range counts {
+		hours := m.views[c.ArticleID]
+		if hours == nil {
+			hours = make(map[time.Time]int)
+			m.views[c.ArticleID] = hours
+		}
+		hours[viewHour(c.At)] += c.Views
+	}
+	return nil
+}
+
+//GetViewCounts totals the views of each article from the hour since falls in onwards, most
+//viewed first
+func (m *MemoryViewStorage) GetViewCounts(ctx context.Context, since time.Time) ([]ViewCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	from := viewHour(since)
+	var counts []ViewCount
+	for id, hours := range m.views {
+		c := ViewCount{ArticleID: id}
+		for h, n := range hours {
+			if !h.Before(from) {
+				c.Views += n
+			}
+		}
+		if c.Views > 0 {
+			counts = append(counts, c)
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Views != counts[j].Views {
+			return counts[i].Views > counts[j].Views
+		}
+		return counts[i].ArticleID < counts[j].ArticleID
+	})
+	return counts, nil
+}
+
+//DeleteViewsBefore drops the hours ending at or before t and returns how many views they held
+func (m *MemoryViewStorage) DeleteViewsBefore(ctx context.Context, t time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for id, hours := range m.views {
+		for h, v := range hours {
+			if !h.Add(time.Hour).After(t) {
+				n += v
+				delete(hours, h)
+			}
+		}
+		if len(hours) == 0 {
+			delete(m.views, id)
+		}
+	}
+	return n, nil
+}
+
+//DeleteArticleViews drops every view of an article and returns how many there were
+func (m *MemoryViewStorage) DeleteArticleViews(ctx context.Context, articleID int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, v := range m.views[articleID] {
+		n += v
+	}
+	delete(m.views, articleID)
+	return n, nil
+}
@@ -0,0 +1,14 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/Perezonance/article-management-service/internal/storage"
+	"github.com/Perezonance/article-management-service/internal/storage/storagetest"
+)
+
+func TestMemoryViewStorageConformance(t *testing.T) {
+	storagetest.RunViewConformanceTests(t, func(t *testing.T) storage.ViewStorage {
+		return storage.NewMemoryViewStorage()
+	})
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+//AddViews adds each count to the row of its article and hour, all in one transaction
+func (s *SQLStorage) AddViews(ctx context.Context, counts []ViewCount) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, c := range counts {
+			if _, err := tx.ExecContext(ctx,
+				s.rebind(`INSERT INTO article_views (article_id, hour, views) VALUES (?, ?, ?) ON CONFLICT (article_id, hour) DO UPDATE SET views = article_views.views + excluded.views`),
+				c.ArticleID, viewHour(c.At).Unix(), c.Views,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//GetViewCounts totals the views of each article from the hour since falls in onwards, most
+//viewed first
+func (s *SQLStorage) GetViewCounts(ctx context.Context, since time.Time) ([]ViewCount, error) {
+	rows, err := s.db.QueryContext(ctx,
+		s.rebind(`SELECT article_id, SUM(views) AS total FROM article_views WHERE hour >= ? GROUP BY article_id HAVING SUM(views) > 0 ORDER BY total DESC, article_id`),
+		viewHour(since).Unix(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []ViewCount
+	for rows.Next() {
+		var c ViewCount
+		if err := rows.Scan(&c.ArticleID, &c.Views); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+//DeleteViewsBefore drops the hours ending at or before t and returns how many views they held
+func (s *SQLStorage) DeleteViewsBefore(ctx context.Context, t time.Time) (int, error) {
+	return s.deleteViews(ctx, `hour <= ?`, t.Add(-time.Hour).Unix())
+}
+
+//DeleteArticleViews drops every view of an article and returns how many there were
+func (s *SQLStorage) DeleteArticleViews(ctx context.Context, articleID int) (int, error) {
+	return s.deleteViews(ctx, `article_id = ?`, articleID)
+}
+
+//deleteViews sums and deletes the view rows matching a condition in one transaction
+func (s *SQLStorage) deleteViews(ctx context.Context, where string, arg interface{}) (int, error) {
+	var n sql.NullInt64
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, s.rebind(`SELECT SUM(views) FROM article_views WHERE `+where), arg).Scan(&n); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM article_views WHERE `+where), arg)
+		return err
+	})
+	return int(n.Int64), err
+}
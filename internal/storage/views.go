@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+//ViewStorage defines the behavior for a store of article view counts, kept alongside Storage.
+//Views are counted per article and hour. AddViews adds each count to the hour its At falls in
+//and GetViewCounts totals the views of every article viewed in the hour since falls in or
+//after, most viewed first with ties broken by articleID. DeleteViewsBefore drops the hours
+//ending at or before the given time and DeleteArticleViews every hour of one article, both
+//returning how many views they removed. The store does not know about articles, callers check
+//the articles it returns still exist
+type ViewStorage interface {
+	AddViews(context.Context, []ViewCount) error
+	GetViewCounts(context.Context, time.Time) ([]ViewCount, error)
+	DeleteViewsBefore(context.Context, time.Time) (int, error)
+	DeleteArticleViews(context.Context, int) (int, error)
+}
+
+//ViewCount is a number of views of an article. At is a time in the hour they were made when
+//written and is left zero in the totals read back
+type ViewCount struct {
+	ArticleID int
+	At        time.Time
+	Views     int
+}
+
+//viewHour returns the start of the hour t falls in
+func viewHour(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
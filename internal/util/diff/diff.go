@@ -0,0 +1,114 @@
+package diff
+
+import "strings"
+
+//Op identifies how a line moved between the old and new text
+type Op string
+
+const (
+	//Equal marks a line present in both texts
+	Equal Op = "equal"
+	//Delete marks a line only present in the old text
+	Delete Op = "delete"
+	//Insert marks a line only present in the new text
+	Insert Op = "insert"
+)
+
+//maxCells bounds the longest common subsequence table, larger inputs are reported as a
+//wholesale replacement instead of a minimal diff
+const maxCells = 4 << 20
+
+//Line is a single line of a diff
+type Line struct {
+	Op   Op
+	Text string
+}
+
+//Lines compares old and new line by line and returns the edit script turning old into new,
+//deletions ordered before insertions wherever lines were replaced
+func Lines(old, new string) []Line {
+	a, b := split(old), split(new)
+
+	//Trim the common prefix and suffix so typical edits leave a small table
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	var lines []Line
+	for _, t := range a[:pre] {
+		lines = append(lines, Line{Op: Equal, Text: t})
+	}
+	lines = append(lines, lcs(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, t := range a[len(a)-suf:] {
+		lines = append(lines, Line{Op: Equal, Text: t})
+	}
+	return lines
+}
+
+//lcs diffs a and b through a longest common subsequence table
+func lcs(a, b []string) []Line {
+	if (len(a)+1)*(len(b)+1) > maxCells {
+		return replace(a, b)
+	}
+
+	//table[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	table := make([][]int32, len(a)+1)
+	for i := range table {
+		table[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				table[i][j] = table[i+1][j+1] + 1
+			case table[i+1][j] >= table[i][j+1]:
+				table[i][j] = table[i+1][j]
+			default:
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	var (
+		lines []Line
+		i, j  int
+	)
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			lines = append(lines, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	return append(lines, replace(a[i:], b[j:])...)
+}
+
+func replace(a, b []string) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, t := range a {
+		lines = append(lines, Line{Op: Delete, Text: t})
+	}
+	for _, t := range b {
+		lines = append(lines, Line{Op: Insert, Text: t})
+	}
+	return lines
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
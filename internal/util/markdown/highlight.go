@@ -0,0 +1,199 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+//language describes the lexical features of a programming language needed to highlight it
+type language struct {
+	keywords     map[string]bool
+	lineComments []string
+	blockComment [2]string
+	//quotes lists the characters delimiting strings, backquoted strings span lines and take no
+	//escapes
+	quotes string
+	//foldCase matches keywords regardless of case
+	foldCase bool
+}
+
+var (
+	goLang = &language{
+		keywords:     words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var true false nil iota"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+	}
+	jsLang = &language{
+		keywords:     words("async await break case catch class const continue debugger default delete do else enum export extends false finally for function if implements import in instanceof interface let new null of return super switch this throw true try type typeof undefined var void while with yield"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+	}
+	pythonLang = &language{
+		keywords:     words("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield None True False"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	}
+	shellLang = &language{
+		keywords:     words("case do done elif else esac exit export fi for function if in local readonly return then until while"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	}
+	sqlLang = &language{
+		keywords:     words("all alter and as asc between by case create default delete desc distinct drop else end exists foreign from group having in index inner insert into is join key left like limit not null offset on or order outer primary references right select set table then union update values when where"),
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "'\"",
+		foldCase:     true,
+	}
+	jsonLang = &language{
+		keywords: words("true false null"),
+		quotes:   "\"",
+	}
+	javaLang = &language{
+		keywords:     words("abstract boolean break byte case catch char class continue default do double else enum extends false final finally float for if implements import instanceof int interface long new null package private protected public return short static super switch this throw throws true try void volatile while"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+	}
+	cLang = &language{
+		keywords:     words("auto break case char class const continue default delete do double else enum extern false float for goto if inline int long namespace new nullptr private protected public register return short signed sizeof static struct switch template true typedef typename union unsigned virtual void volatile while"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'",
+	}
+	rustLang = &language{
+		keywords:     words("as async await break const continue crate else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"",
+	}
+
+	//languages maps the names fenced code blocks may give, in lower case, to their language
+	languages = map[string]*language{
+		"go": goLang, "golang": goLang,
+		"js": jsLang, "javascript": jsLang, "ts": jsLang, "typescript": jsLang,
+		"py": pythonLang, "python": pythonLang,
+		"sh": shellLang, "bash": shellLang, "shell": shellLang,
+		"sql":  sqlLang,
+		"json": jsonLang,
+		"java": javaLang,
+		"c":    cLang, "cpp": cLang, "c++": cLang,
+		"rs": rustLang, "rust": rustLang,
+	}
+)
+
+//highlight renders code as HTML, wrapping the comments, strings, numbers and keywords of known
+//languages in spans classed hl-comment, hl-string, hl-number and hl-keyword. Code in other
+//languages is only escaped
+func highlight(lang, code string) string {
+	l, ok := languages[strings.ToLower(lang)]
+	if !ok {
+		return html.EscapeString(code)
+	}
+	var b strings.Builder
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		if n := l.commentLen(rest); n > 0 {
+			span(&b, "hl-comment", rest[:n])
+			i += n
+			continue
+		}
+		c := rest[0]
+		n := 1
+		switch {
+		case strings.IndexByte(l.quotes, c) >= 0:
+			n = stringLen(rest)
+			span(&b, "hl-string", rest[:n])
+		case isDigit(c) && (i == 0 || !isIdent(code[i-1])):
+			for n < len(rest) && (isIdent(rest[n]) || rest[n] == '.') {
+				n++
+			}
+			span(&b, "hl-number", rest[:n])
+		case isIdent(c):
+			for n < len(rest) && isIdent(rest[n]) {
+				n++
+			}
+			word := rest[:n]
+			if l.foldCase {
+				word = strings.ToLower(word)
+			}
+			if l.keywords[word] {
+				span(&b, "hl-keyword", rest[:n])
+			} else {
+				b.WriteString(rest[:n])
+			}
+		default:
+			b.WriteString(escapeByte(c))
+		}
+		i += n
+	}
+	return b.String()
+}
+
+//commentLen returns the length of the comment starting s, 0 when s does not start with one. A
+//block comment left open runs to the end of the code
+func (l *language) commentLen(s string) int {
+	for _, lc := range l.lineComments {
+		if strings.HasPrefix(s, lc) {
+			if end := strings.IndexByte(s, '\n'); end >= 0 {
+				return end
+			}
+			return len(s)
+		}
+	}
+	if open := l.blockComment[0]; open != "" && strings.HasPrefix(s, open) {
+		if end := strings.Index(s[len(open):], l.blockComment[1]); end >= 0 {
+			return len(open) + end + len(l.blockComment[1])
+		}
+		return len(s)
+	}
+	return 0
+}
+
+//stringLen returns the length of the string literal starting s. A string left open runs to the
+//end of its line
+func stringLen(s string) int {
+	q := s[0]
+	for j := 1; j < len(s); j++ {
+		switch {
+		case s[j] == '\\' && q != '`':
+			j++
+		case s[j] == q:
+			return j + 1
+		case s[j] == '\n' && q != '`':
+			return j
+		}
+	}
+	return len(s)
+}
+
+//languageClass returns the language named by a code block as it goes in its class attribute,
+//"" when it is absent or holds characters outside letters, digits and +#._-
+func languageClass(lang string) string {
+	lang = strings.ToLower(lang)
+	for i := 0; i < len(lang); i++ {
+		if !isAlnum(lang[i]) && strings.IndexByte("+#._-", lang[i]) < 0 {
+			return ""
+		}
+	}
+	return lang
+}
+
+func span(b *strings.Builder, class, text string) {
+	b.WriteString(`<span class="` + class + `">` + html.EscapeString(text) + "</span>")
+}
+
+func isIdent(c byte) bool {
+	return isAlnum(c) || c == '_' || c == '$'
+}
+
+//words turns a space separated list into a set
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
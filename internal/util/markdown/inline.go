@@ -0,0 +1,472 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+var (
+	//linkSchemes are the URL schemes links may use, relative URLs are always allowed
+	linkSchemes = []string{"http", "https", "mailto"}
+	//imageSchemes are the URL schemes images may be loaded from
+	imageSchemes = []string{"http", "https"}
+)
+
+//inline renders the code spans, emphasis, links, images and line breaks of a span of text,
+//escaping everything else
+func inline(s string) string {
+	return renderInline(s, true)
+}
+
+//renderInline is inline with links left out when links is unset, as within the text of another
+//link
+func renderInline(s string, links bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				b.WriteString("<br>\n")
+				i += 2
+				continue
+			}
+			if i+1 < len(s) && isPunct(s[i+1]) {
+				b.WriteString(escapeByte(s[i+1]))
+				i += 2
+				continue
+			}
+		case '`':
+			if code, n := codeSpan(s[i:]); n > 0 {
+				b.WriteString(code)
+				i += n
+				continue
+			}
+			n := runLen(s[i:], '`')
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '!':
+			if img, n := image(s[i:]); n > 0 {
+				b.WriteString(img)
+				i += n
+				continue
+			}
+		case '[':
+			if links {
+				if a, n := link(s[i:]); n > 0 {
+					b.WriteString(a)
+					i += n
+					continue
+				}
+			}
+		case '<':
+			if links {
+				if a, n := autolink(s[i:]); n > 0 {
+					b.WriteString(a)
+					i += n
+					continue
+				}
+			}
+		case '*', '_', '~':
+			if em, n := emphasis(s, i, links); n > 0 {
+				b.WriteString(em)
+				i += n
+				continue
+			}
+			n := runLen(s[i:], c)
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '&':
+			if n := entityLen(s[i:]); n > 0 {
+				b.WriteString(s[i : i+n])
+				i += n
+				continue
+			}
+		}
+		b.WriteString(escapeByte(c))
+		i++
+	}
+	return b.String()
+}
+
+//codeSpan renders the code span opened by the run of backticks starting s, returning the HTML
+//and the number of bytes it spans or 0 when no run of the same length closes it
+func codeSpan(s string) (string, int) {
+	n := runLen(s, '`')
+	for j := n; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := runLen(s[j:], '`')
+		if m == n {
+			code := strings.ReplaceAll(s[n:j], "\n", " ")
+			if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			return "<code>" + html.EscapeString(code) + "</code>", j + m
+		}
+		j += m
+	}
+	return "", 0
+}
+
+//emphasis renders the emphasis opened by the run of *, _ or ~ at s[i], returning the HTML and
+//the number of bytes it spans or 0 when the run opens nothing. One delimiter makes <em>, two
+//<strong> and three both, while ~~ strikes text through. Delimiters of the run left over are
+//written as they are
+func emphasis(s string, i int, links bool) (string, int) {
+	c := s[i]
+	n := runLen(s[i:], c)
+	if i+n == len(s) || isSpace(s[i+n]) || (c == '_' && i > 0 && isAlnum(s[i-1])) {
+		return "", 0
+	}
+	if c == '~' {
+		if n != 2 {
+			return "", 0
+		}
+		if j := closer(s, i+n, c, 2); j >= 0 {
+			return "<del>" + renderInline(s[i+n:j], links) + "</del>", j + 2 - i
+		}
+		return "", 0
+	}
+	for want := 3; want >= 1; want-- {
+		if want > n {
+			continue
+		}
+		j := closer(s, i+n, c, want)
+		if j < 0 {
+			continue
+		}
+		inner := renderInline(s[i+n:j], links)
+		switch want {
+		case 3:
+			inner = "<em><strong>" + inner + "</strong></em>"
+		case 2:
+			inner = "<strong>" + inner + "</strong>"
+		default:
+			inner = "<em>" + inner + "</em>"
+		}
+		return s[i:i+n-want] + inner, j + want - i
+	}
+	return "", 0
+}
+
+//closer returns the index of the first run of exactly want delimiters c from s[from] that can
+//close emphasis, skipping escaped characters and code spans, or -1 when there is none
+func closer(s string, from int, c byte, want int) int {
+	for j := from; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			if _, n := codeSpan(s[j:]); n > 0 {
+				j += n
+				continue
+			}
+		case c:
+			n := runLen(s[j:], c)
+			if n == want && j > from && !isSpace(s[j-1]) && (c != '_' || j+n == len(s) || !isAlnum(s[j+n])) {
+				return j
+			}
+			j += n
+			continue
+		}
+		j++
+	}
+	return -1
+}
+
+//link renders the inline link [text](destination "title") starting s. Links are written by
+//authors rather than the site so every one is marked nofollow. A link to an unsafe URL is reduced
+//to its text
+func link(s string) (string, int) {
+	text, dest, title, n, ok := linkParts(s)
+	if !ok {
+		return "", 0
+	}
+	inner := renderInline(text, false)
+	href, ok := safeURL(dest, linkSchemes)
+	if !ok {
+		return inner, n
+	}
+	a := `<a href="` + html.EscapeString(href) + `"`
+	if title != "" {
+		a += ` title="` + html.EscapeString(title) + `"`
+	}
+	return a + ` rel="nofollow">` + inner + "</a>", n
+}
+
+//image renders the image ![alt](source "title") starting s. An image from an unsafe URL is
+//reduced to its alt text
+func image(s string) (string, int) {
+	if len(s) < 2 || s[1] != '[' {
+		return "", 0
+	}
+	text, dest, title, n, ok := linkParts(s[1:])
+	if !ok {
+		return "", 0
+	}
+	alt := plainText(renderInline(text, false))
+	src, ok := safeURL(dest, imageSchemes)
+	if !ok {
+		return html.EscapeString(alt), n + 1
+	}
+	img := `<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(alt) + `"`
+	if title != "" {
+		img += ` title="` + html.EscapeString(title) + `"`
+	}
+	return img + ">", n + 1
+}
+
+//linkParts splits the [text](destination "title") starting s into its parts, reporting the
+//number of bytes it spans
+func linkParts(s string) (text, dest, title string, n int, ok bool) {
+	end := closeBracket(s)
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return "", "", "", 0, false
+	}
+	text = s[1:end]
+	i := skipSpace(s, end+2)
+
+	//The destination is either wrapped in <> or runs up to a space or an unbalanced )
+	if i < len(s) && s[i] == '<' {
+		j := strings.IndexAny(s[i+1:], "<>\n")
+		if j < 0 || s[i+1+j] != '>' {
+			return "", "", "", 0, false
+		}
+		dest, i = s[i+1:i+1+j], i+j+2
+	} else {
+		start, depth := i, 0
+	scan:
+		for ; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '(':
+				depth++
+			case ')':
+				if depth == 0 {
+					break scan
+				}
+				depth--
+			case ' ', '\n':
+				break scan
+			}
+		}
+		if i > len(s) {
+			i = len(s)
+		}
+		dest = s[start:i]
+	}
+
+	j := skipSpace(s, i)
+	if j < len(s) && j > i && strings.IndexByte(`"'(`, s[j]) >= 0 {
+		closing := s[j]
+		if closing == '(' {
+			closing = ')'
+		}
+		k := j + 1
+		for ; k < len(s) && s[k] != closing; k++ {
+			if s[k] == '\\' {
+				k++
+			}
+		}
+		if k >= len(s) {
+			return "", "", "", 0, false
+		}
+		title, j = s[j+1:k], skipSpace(s, k+1)
+	}
+	if j >= len(s) || s[j] != ')' {
+		return "", "", "", 0, false
+	}
+	return text, unescape(dest), unescape(title), j + 1, true
+}
+
+//closeBracket returns the index of the ] matching the [ starting s, or -1 when it is not closed
+func closeBracket(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			if _, n := codeSpan(s[i:]); n > 0 {
+				i += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+//autolink renders the <URL> or <email> starting s as a link to it
+func autolink(s string) (string, int) {
+	end := strings.IndexByte(s, '>')
+	if end < 2 {
+		return "", 0
+	}
+	target := s[1:end]
+	if strings.ContainsAny(target, " <\n\t") {
+		return "", 0
+	}
+	href := target
+	if !hasScheme(target) {
+		at := strings.IndexByte(target, '@')
+		if at < 1 || at == len(target)-1 || strings.ContainsAny(target, ":/") {
+			return "", 0
+		}
+		href = "mailto:" + target
+	}
+	href, ok := safeURL(href, linkSchemes)
+	if !ok {
+		return "", 0
+	}
+	return `<a href="` + html.EscapeString(href) + `" rel="nofollow">` + html.EscapeString(target) + "</a>", end + 1
+}
+
+//safeURL cleans a link destination and reports whether it is relative or uses one of the
+//allowed schemes. Control characters are dropped before the scheme is checked, as browsers do,
+//so they cannot hide a javascript: URL
+func safeURL(raw string, schemes []string) (string, bool) {
+	u := strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.ReplaceAll(strings.TrimSpace(raw), " ", "%20"))
+	i := strings.IndexAny(u, ":/?#")
+	if i <= 0 || u[i] != ':' {
+		return u, true
+	}
+	scheme := strings.ToLower(u[:i])
+	for _, s := range schemes {
+		if scheme == s {
+			return u, true
+		}
+	}
+	return "", false
+}
+
+//hasScheme reports whether a URL starts with a scheme rather than being relative
+func hasScheme(u string) bool {
+	i := strings.IndexAny(u, ":/?#")
+	return i > 0 && u[i] == ':'
+}
+
+//entityLen returns the length of the HTML character reference starting s, 0 when s does not
+//start with a known one. References are passed through since they cannot form markup
+func entityLen(s string) int {
+	end := strings.IndexByte(s, ';')
+	if end < 2 || end > 32 {
+		return 0
+	}
+	ref := s[1:end]
+	if ref[0] == '#' {
+		digits, hex := ref[1:], false
+		if digits != "" && (digits[0] == 'x' || digits[0] == 'X') {
+			digits, hex = digits[1:], true
+		}
+		if digits == "" || len(digits) > 7 {
+			return 0
+		}
+		for i := 0; i < len(digits); i++ {
+			d := digits[i]
+			if !isDigit(d) && !(hex && ((d >= 'a' && d <= 'f') || (d >= 'A' && d <= 'F'))) {
+				return 0
+			}
+		}
+		return end + 1
+	}
+	for i := 0; i < len(ref); i++ {
+		if !isAlnum(ref[i]) {
+			return 0
+		}
+	}
+	if html.UnescapeString(s[:end+1]) == s[:end+1] {
+		return 0
+	}
+	return end + 1
+}
+
+//plainText strips the tags from rendered HTML and decodes its character references
+func plainText(h string) string {
+	var (
+		b   strings.Builder
+		tag bool
+	)
+	for _, c := range h {
+		switch {
+		case c == '<':
+			tag = true
+		case c == '>':
+			tag = false
+		case !tag:
+			b.WriteRune(c)
+		}
+	}
+	return html.UnescapeString(b.String())
+}
+
+//unescape removes the backslashes escaping punctuation
+func unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+//escapeByte escapes a byte of text for HTML, bytes of multi-byte characters are kept as they are
+func escapeByte(c byte) string {
+	switch c {
+	case '<':
+		return "&lt;"
+	case '>':
+		return "&gt;"
+	case '&':
+		return "&amp;"
+	case '"':
+		return "&#34;"
+	case '\'':
+		return "&#39;"
+	}
+	return string([]byte{c})
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isPunct(c byte) bool {
+	return c < 0x80 && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
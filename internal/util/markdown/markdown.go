@@ -0,0 +1,531 @@
+package markdown
+
+import (
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//headingIDPrefix starts every heading id so that ids taken from user content cannot clobber the
+//globals or elements of the page the HTML is embedded in
+const headingIDPrefix = "heading-"
+
+//Render converts Markdown source to HTML. Raw HTML in the source is escaped rather than passed
+//through and link and image URLs are restricted to safe schemes, so the output can be embedded
+//in a page as is. Headings get unique ids along with an anchor linking to them and fenced code
+//blocks naming a known language are highlighted
+func Render(src string) string {
+	r := &renderer{ids: make(map[string]bool)}
+	r.blocks(splitLines(src), false)
+	return r.b.String()
+}
+
+//renderer accumulates the HTML of a document along with the heading ids handed out so far
+type renderer struct {
+	b   strings.Builder
+	ids map[string]bool
+	//text reports whether the last block written was the bare text of a tight list item
+	text bool
+}
+
+//blocks renders lines as a sequence of block elements. Paragraphs of tight list items are
+//written without their <p> tags
+func (r *renderer) blocks(lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		if isBlank(lines[i]) {
+			i++
+			continue
+		}
+		n := 0
+		for _, block := range []func([]string) int{r.fencedCode, r.indentedCode, r.atxHeading, r.thematicBreak, r.blockquote, r.list} {
+			if n = block(lines[i:]); n > 0 {
+				break
+			}
+		}
+		r.text = false
+		if n == 0 {
+			n = r.paragraph(lines[i:], tight)
+			r.text = tight
+		}
+		i += n
+	}
+}
+
+//fencedCode renders a code block opened by ``` or ~~~ and returns the number of lines it spans,
+//0 when lines do not start with one. A block left open runs to the end of the lines
+func (r *renderer) fencedCode(lines []string) int {
+	ind, ch, n, info, ok := fenceOpen(lines[0])
+	if !ok {
+		return 0
+	}
+	var code []string
+	i := 1
+	for ; i < len(lines); i++ {
+		if fenceCloses(lines[i], ch, n) {
+			i++
+			break
+		}
+		code = append(code, trimIndent(lines[i], ind))
+	}
+
+	lang := ""
+	if f := strings.Fields(info); len(f) > 0 {
+		lang = unescape(f[0])
+	}
+	r.b.WriteString("<pre><code")
+	if class := languageClass(lang); class != "" {
+		r.b.WriteString(` class="language-` + class + `"`)
+	}
+	r.b.WriteString(">")
+	if len(code) > 0 {
+		r.b.WriteString(highlight(lang, strings.Join(code, "\n")+"\n"))
+	}
+	r.b.WriteString("</code></pre>\n")
+	return i
+}
+
+//indentedCode renders a code block indented by four spaces and returns the number of lines it
+//spans, 0 when lines do not start with one
+func (r *renderer) indentedCode(lines []string) int {
+	if indent(lines[0]) < 4 {
+		return 0
+	}
+	end := 0
+	for i := 0; i < len(lines) && (isBlank(lines[i]) || indent(lines[i]) >= 4); i++ {
+		if !isBlank(lines[i]) {
+			end = i + 1
+		}
+	}
+	code := make([]string, end)
+	for i := range code {
+		code[i] = trimIndent(lines[i], 4)
+	}
+	r.b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")+"\n") + "</code></pre>\n")
+	return end
+}
+
+//atxHeading renders a heading opened by one to six #s
+func (r *renderer) atxHeading(lines []string) int {
+	level, text, ok := atxHeading(lines[0])
+	if !ok {
+		return 0
+	}
+	r.heading(level, text)
+	return 1
+}
+
+//thematicBreak renders a line of three or more -, * or _ as a horizontal rule
+func (r *renderer) thematicBreak(lines []string) int {
+	if !isThematicBreak(lines[0]) {
+		return 0
+	}
+	r.b.WriteString("<hr>\n")
+	return 1
+}
+
+//blockquote renders consecutive lines starting with > along with the lines lazily continuing
+//their last paragraph
+func (r *renderer) blockquote(lines []string) int {
+	if !isQuote(lines[0]) {
+		return 0
+	}
+	var inner []string
+	i := 0
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		if isQuote(l) {
+			inner = append(inner, stripQuote(l))
+			continue
+		}
+		if isBlank(l) || startsBlock(l) || isBlank(inner[len(inner)-1]) {
+			break
+		}
+		inner = append(inner, l)
+	}
+	r.b.WriteString("<blockquote>\n")
+	r.blocks(inner, false)
+	r.b.WriteString("</blockquote>\n")
+	return i
+}
+
+//list renders consecutive items of a bullet or ordered list. The list is loose, its items'
+//paragraphs wrapped in <p> tags, when a blank line separates its items or the blocks inside one
+func (r *renderer) list(lines []string) int {
+	first, ok := parseListMarker(lines[0])
+	if !ok {
+		return 0
+	}
+	var (
+		items [][]string
+		loose bool
+		i     int
+	)
+	for i < len(lines) {
+		m, ok := parseListMarker(lines[i])
+		if !ok || m.ordered != first.ordered || m.delim != first.delim {
+			break
+		}
+		if len(items) > 0 && isBlank(lines[i-1]) {
+			loose = true
+		}
+		item := []string{""}
+		if !m.empty {
+			item[0] = lines[i][m.width:]
+		}
+		i++
+	collect:
+		for ; i < len(lines); i++ {
+			l := lines[i]
+			switch {
+			case isBlank(l):
+				item = append(item, "")
+			case indent(l) >= m.width:
+				if isBlank(item[len(item)-1]) && len(item) > 1 {
+					loose = true
+				}
+				item = append(item, l[m.width:])
+			case isListItem(l):
+				//A marker outside the item opens the next item, or a list of another type, even
+				//where it could not interrupt a paragraph such as 2. after 1.
+				break collect
+			case !isBlank(item[len(item)-1]) && !startsBlock(l):
+				item = append(item, l)
+			default:
+				break collect
+			}
+		}
+		for len(item) > 1 && isBlank(item[len(item)-1]) {
+			item = item[:len(item)-1]
+		}
+		items = append(items, item)
+	}
+
+	switch {
+	case !first.ordered:
+		r.b.WriteString("<ul>\n")
+	case first.start != 1:
+		r.b.WriteString(`<ol start="` + strconv.Itoa(first.start) + `">` + "\n")
+	default:
+		r.b.WriteString("<ol>\n")
+	}
+	for _, item := range items {
+		sub := &renderer{ids: r.ids}
+		sub.blocks(item, !loose)
+		content := sub.b.String()
+		if sub.text {
+			content = strings.TrimSuffix(content, "\n")
+		}
+		if loose {
+			content = "\n" + content
+		}
+		r.b.WriteString("<li>" + content + "</li>\n")
+	}
+	if first.ordered {
+		r.b.WriteString("</ol>\n")
+	} else {
+		r.b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+//paragraph renders lines up to the next blank line or block as a paragraph, or as a heading
+//when they are underlined with = or -
+func (r *renderer) paragraph(lines []string, tight bool) int {
+	var text []string
+	i := 0
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		if isBlank(l) {
+			break
+		}
+		if i > 0 {
+			if level := setextLevel(l); level > 0 {
+				r.heading(level, joinParagraph(text))
+				return i + 1
+			}
+			if startsBlock(l) {
+				break
+			}
+		}
+		text = append(text, strings.TrimLeft(l, " "))
+	}
+
+	content := inline(joinParagraph(text))
+	if tight {
+		r.b.WriteString(content + "\n")
+	} else {
+		r.b.WriteString("<p>" + content + "</p>\n")
+	}
+	return i
+}
+
+//heading writes a heading along with an anchor linking to its id
+func (r *renderer) heading(level int, text string) {
+	content := inline(text)
+	id := r.headingID(plainText(content))
+	tag := "h" + strconv.Itoa(level)
+	r.b.WriteString("<" + tag + ` id="` + id + `">` + content)
+	r.b.WriteString(`<a class="anchor" href="#` + id + `" aria-label="Link to this section">#</a></` + tag + ">\n")
+}
+
+//headingID derives the id of a heading from its text, lower case letters and digits with runs
+//of spaces, hyphens and underscores turned into a single hyphen. Ids already handed out get a
+//numbered suffix
+func (r *renderer) headingID(text string) string {
+	var (
+		b    strings.Builder
+		dash bool
+	)
+	for _, c := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(c)
+		case c == '-' || c == '_' || unicode.IsSpace(c):
+			dash = true
+		}
+	}
+	base := b.String()
+	if base == "" {
+		base = "section"
+	}
+	id := base
+	for n := 1; r.ids[id]; n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	r.ids[id] = true
+	return headingIDPrefix + id
+}
+
+//listMarker describes the marker opening a list item
+type listMarker struct {
+	ordered bool
+	//delim is the bullet of a bullet list or the . or ) following the numbers of an ordered one
+	delim byte
+	start int
+	//width is the number of columns up to the item's content, its continuation lines are
+	//indented at least as far
+	width int
+	empty bool
+}
+
+//parseListMarker reports whether a line opens a list item and describes its marker
+func parseListMarker(l string) (listMarker, bool) {
+	ind := indent(l)
+	if ind > 3 || ind == len(l) {
+		return listMarker{}, false
+	}
+	rest := l[ind:]
+	var m listMarker
+	n := 0
+	if strings.IndexByte("-+*", rest[0]) >= 0 {
+		m.delim, n = rest[0], 1
+	} else {
+		for n < len(rest) && n < 9 && rest[n] >= '0' && rest[n] <= '9' {
+			n++
+		}
+		if n == 0 || n == len(rest) || (rest[n] != '.' && rest[n] != ')') {
+			return listMarker{}, false
+		}
+		m.ordered, m.delim = true, rest[n]
+		m.start, _ = strconv.Atoi(rest[:n])
+		n++
+	}
+	after := rest[n:]
+	if after != "" && after[0] != ' ' {
+		return listMarker{}, false
+	}
+	spaces := len(after) - len(strings.TrimLeft(after, " "))
+	m.empty = spaces == len(after)
+	if spaces > 4 || m.empty {
+		spaces = 1
+	}
+	m.width = ind + n + spaces
+	return m, true
+}
+
+//isListItem reports whether a line opens a list item of any type
+func isListItem(l string) bool {
+	_, ok := parseListMarker(l)
+	return ok
+}
+
+//startsBlock reports whether a line opens a block that interrupts a paragraph. Lists only do
+//when the item has content and, for ordered lists, starts at 1
+func startsBlock(l string) bool {
+	if _, _, _, _, ok := fenceOpen(l); ok {
+		return true
+	}
+	if _, _, ok := atxHeading(l); ok {
+		return true
+	}
+	if isThematicBreak(l) || isQuote(l) {
+		return true
+	}
+	m, ok := parseListMarker(l)
+	return ok && !m.empty && (!m.ordered || m.start == 1)
+}
+
+//fenceOpen reports whether a line opens a fenced code block and returns its indentation, fence
+//character and length and the info string naming its language
+func fenceOpen(l string) (int, byte, int, string, bool) {
+	ind := indent(l)
+	if ind > 3 || ind == len(l) {
+		return 0, 0, 0, "", false
+	}
+	ch := l[ind]
+	if ch != '`' && ch != '~' {
+		return 0, 0, 0, "", false
+	}
+	n := runLen(l[ind:], ch)
+	info := strings.TrimSpace(l[ind+n:])
+	if n < 3 || (ch == '`' && strings.IndexByte(info, '`') >= 0) {
+		return 0, 0, 0, "", false
+	}
+	return ind, ch, n, info, true
+}
+
+//fenceCloses reports whether a line closes a code block opened by n fence characters ch
+func fenceCloses(l string, ch byte, n int) bool {
+	ind := indent(l)
+	if ind > 3 || ind == len(l) || l[ind] != ch {
+		return false
+	}
+	m := runLen(l[ind:], ch)
+	return m >= n && strings.TrimSpace(l[ind+m:]) == ""
+}
+
+//atxHeading reports whether a line is a heading opened by #s and returns its level and text
+//without the optional closing #s
+func atxHeading(l string) (int, string, bool) {
+	ind := indent(l)
+	if ind > 3 {
+		return 0, "", false
+	}
+	rest := l[ind:]
+	level := runLen(rest, '#')
+	if level == 0 || level > 6 || (level < len(rest) && rest[level] != ' ') {
+		return 0, "", false
+	}
+	text := strings.TrimSpace(rest[level:])
+	if t := strings.TrimRight(text, "#"); t == "" || strings.HasSuffix(t, " ") {
+		text = strings.TrimSpace(t)
+	}
+	return level, text, true
+}
+
+//setextLevel returns 1 or 2 for a line underlining a heading with = or -, 0 for other lines
+func setextLevel(l string) int {
+	if indent(l) > 3 {
+		return 0
+	}
+	t := strings.TrimSpace(l)
+	switch {
+	case t == "":
+		return 0
+	case strings.Trim(t, "=") == "":
+		return 1
+	case strings.Trim(t, "-") == "":
+		return 2
+	}
+	return 0
+}
+
+//isThematicBreak reports whether a line is a horizontal rule, three or more of the same -, *
+//or _ optionally separated by spaces
+func isThematicBreak(l string) bool {
+	if indent(l) > 3 {
+		return false
+	}
+	t := strings.ReplaceAll(strings.TrimSpace(l), " ", "")
+	return len(t) >= 3 && strings.IndexByte("-*_", t[0]) >= 0 && strings.Trim(t, t[:1]) == ""
+}
+
+func isQuote(l string) bool {
+	ind := indent(l)
+	return ind <= 3 && ind < len(l) && l[ind] == '>'
+}
+
+//stripQuote removes the > opening a quoted line along with one space following it
+func stripQuote(l string) string {
+	l = l[indent(l)+1:]
+	return strings.TrimPrefix(l, " ")
+}
+
+//joinParagraph joins the lines of a paragraph, turning lines ending in two or more spaces into
+//hard line breaks
+func joinParagraph(lines []string) string {
+	var b strings.Builder
+	for i, l := range lines {
+		t := strings.TrimRight(l, " ")
+		b.WriteString(t)
+		if i == len(lines)-1 {
+			break
+		}
+		if len(l)-len(t) >= 2 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+//splitLines splits source into lines with line endings normalized and leading tabs expanded
+func splitLines(src string) []string {
+	src = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\x00", "�").Replace(src)
+	lines := strings.Split(src, "\n")
+	for i, l := range lines {
+		lines[i] = expandTabs(l)
+	}
+	return lines
+}
+
+//expandTabs replaces the tabs in the leading whitespace of a line with spaces up to the next
+//multiple of four columns
+func expandTabs(l string) string {
+	if !strings.HasPrefix(strings.TrimLeft(l, " "), "\t") {
+		return l
+	}
+	var b strings.Builder
+	for i := 0; i < len(l); i++ {
+		switch l[i] {
+		case ' ':
+			b.WriteByte(' ')
+		case '\t':
+			b.WriteString(strings.Repeat(" ", 4-b.Len()%4))
+		default:
+			b.WriteString(l[i:])
+			return b.String()
+		}
+	}
+	return b.String()
+}
+
+func indent(l string) int {
+	return len(l) - len(strings.TrimLeft(l, " "))
+}
+
+//trimIndent removes up to n leading spaces from a line
+func trimIndent(l string, n int) string {
+	if ind := indent(l); ind < n {
+		n = ind
+	}
+	return l[n:]
+}
+
+func isBlank(l string) bool {
+	return strings.TrimSpace(l) == ""
+}
+
+//runLen returns the number of times c repeats at the start of s
+func runLen(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,62 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderLists(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"1. x\n2. y", "<ol>\n<li>x</li>\n<li>y</li>\n</ol>\n"},
+		{"1) x\n2) y", "<ol>\n<li>x</li>\n<li>y</li>\n</ol>\n"},
+		{"3. x\n4. y", "<ol start=\"3\">\n<li>x</li>\n<li>y</li>\n</ol>\n"},
+		{"1. x\n2.\n3. z", "<ol>\n<li>x</li>\n<li></li>\n<li>z</li>\n</ol>\n"},
+		{"1. x\n\n2. y", "<ol>\n<li>\n<p>x</p>\n</li>\n<li>\n<p>y</p>\n</li>\n</ol>\n"},
+		{"1. x\ncontinued\n2. y", "<ol>\n<li>x\ncontinued</li>\n<li>y</li>\n</ol>\n"},
+		{"1. x\n   more\n2. y", "<ol>\n<li>x\nmore</li>\n<li>y</li>\n</ol>\n"},
+		{"1. a\n   1. b\n   2. c\n2. d", "<ol>\n<li>a\n<ol>\n<li>b</li>\n<li>c</li>\n</ol>\n</li>\n<li>d</li>\n</ol>\n"},
+		//A change of marker type starts another list
+		{"1. x\n2) y", "<ol>\n<li>x</li>\n</ol>\n<ol start=\"2\">\n<li>y</li>\n</ol>\n"},
+		{"- a\n1. x", "<ul>\n<li>a</li>\n</ul>\n<ol>\n<li>x</li>\n</ol>\n"},
+		//Only ordered lists starting at 1 interrupt a paragraph
+		{"text\n2. y", "<p>text\n2. y</p>\n"},
+	} {
+		if got := Render(tc.in); got != tc.want {
+			t.Errorf("Render(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		want     []string
+		unwanted []string
+	}{
+		{"<script>alert(1)</script>", []string{"&lt;script&gt;"}, []string{"<script>"}},
+		{"[x](javascript:alert(1))", []string{"x"}, []string{"href", "javascript"}},
+		{"[x](JaVa\tscript:alert(1))", nil, []string{"href"}},
+		{"![i](data:image/png;base64,AA)", nil, []string{"<img"}},
+		{"[x](https://e.com/\" onmouseover=\"a)", nil, []string{"\" onmouseover"}},
+		{"# Hello *World*\n## Hello World", []string{`<h1 id="heading-hello-world">`, `id="heading-hello-world-1"`, `href="#heading-hello-world"`, "<em>World</em>"}, nil},
+		{"```go\nfunc main() { return \"s\" } // c\n```", []string{`class="language-go"`, `<span class="hl-keyword">func</span>`, "hl-string", "hl-comment"}, nil},
+		{"> q\n\n---\n\n`<b>`", []string{"<blockquote>", "<hr>", "<code>&lt;b&gt;</code>"}, nil},
+		{`[x](https://e.com "T") [y](/articles/1)`, []string{`<a href="https://e.com" title="T" rel="nofollow">x</a>`, `<a href="/articles/1" rel="nofollow">y</a>`}, nil},
+		{"<https://e.com> and &amp; &copy; & <", []string{`<a href="https://e.com" rel="nofollow">`, "&amp;", "&copy;", "&lt;"}, nil},
+	} {
+		got := Render(tc.in)
+		for _, w := range tc.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("Render(%q) = %q, want it to contain %q", tc.in, got, w)
+			}
+		}
+		for _, u := range tc.unwanted {
+			if strings.Contains(got, u) {
+				t.Errorf("Render(%q) = %q, want it without %q", tc.in, got, u)
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const doc = `{"title":"t","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`
+
+//equalJSON reports whether two JSON documents decode to the same value
+func equalJSON(t *testing.T, a, b string) bool {
+	t.Helper()
+	var x, y interface{}
+	if err := json.Unmarshal([]byte(a), &x); err != nil {
+		t.Fatalf("decoding %s returned %v", a, err)
+	}
+	if err := json.Unmarshal([]byte(b), &y); err != nil {
+		t.Fatalf("decoding %s returned %v", b, err)
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func TestApply(t *testing.T) {
+	for _, tc := range []struct {
+		name, patch, want string
+		err               error
+	}{
+		{"add member", `[{"op":"add","path":"/body","value":"b"}]`, `{"title":"t","body":"b","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"add inserts into array", `[{"op":"add","path":"/tags/1","value":"x"}]`, `{"title":"t","tags":["a","x","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"add appends with -", `[{"op":"add","path":"/tags/-","value":"c"}]`, `{"title":"t","tags":["a","b","c"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"add at array length", `[{"op":"add","path":"/tags/2","value":"c"}]`, `{"title":"t","tags":["a","b","c"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"remove member", `[{"op":"remove","path":"/title"}]`, `{"tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"remove element", `[{"op":"remove","path":"/tags/0"}]`, `{"title":"t","tags":["b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"replace", `[{"op":"replace","path":"/title","value":"n"}]`, `{"title":"n","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"move", `[{"op":"move","from":"/title","path":"/body"}]`, `{"body":"t","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"copy", `[{"op":"copy","from":"/tags/1","path":"/title"}]`, `{"title":"b","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"test then replace", `[{"op":"test","path":"/title","value":"t"},{"op":"replace","path":"/title","value":"n"}]`, `{"title":"n","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"escaped ~1", `[{"op":"replace","path":"/meta/a~1b","value":3}]`, `{"title":"t","tags":["a","b"],"meta":{"a/b":3,"m~n":2}}`, nil},
+		{"escaped ~0", `[{"op":"remove","path":"/meta/m~0n"}]`, `{"title":"t","tags":["a","b"],"meta":{"a/b":1}}`, nil},
+		{"test mismatch", `[{"op":"test","path":"/title","value":"x"}]`, "", ErrTestFailed},
+		{"test after remove", `[{"op":"remove","path":"/title"},{"op":"test","path":"/title","value":"t"}]`, "", ErrPathNotFound},
+		{"index out of range", `[{"op":"replace","path":"/tags/2","value":"x"}]`, "", ErrPathNotFound},
+		{"add past array length", `[{"op":"add","path":"/tags/3","value":"x"}]`, "", ErrPathNotFound},
+		{"- outside add", `[{"op":"remove","path":"/tags/-"}]`, "", ErrInvalidPatch},
+		{"leading zero index", `[{"op":"remove","path":"/tags/01"}]`, "", ErrInvalidPatch},
+		{"signed index", `[{"op":"remove","path":"/tags/+1"}]`, "", ErrInvalidPatch},
+		{"negative zero index", `[{"op":"remove","path":"/tags/-0"}]`, "", ErrInvalidPatch},
+		{"missing member", `[{"op":"replace","path":"/body","value":"b"}]`, "", ErrPathNotFound},
+		{"move into own child", `[{"op":"move","from":"/meta","path":"/meta/x"}]`, "", ErrInvalidPatch},
+		{"unknown op", `[{"op":"frobnicate","path":"/title"}]`, "", ErrInvalidPatch},
+		{"missing value", `[{"op":"add","path":"/title"}]`, "", ErrInvalidPatch},
+		{"relative path", `[{"op":"remove","path":"title"}]`, "", ErrInvalidPatch},
+		{"not an array", `{"op":"remove","path":"/title"}`, "", ErrInvalidPatch},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Apply([]byte(doc), []byte(tc.patch))
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("Apply returned %s, %v, want %v", got, err, tc.err)
+				}
+				return
+			}
+			if err != nil || !equalJSON(t, string(got), tc.want) {
+				t.Fatalf("Apply returned %s, %v, want %s", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	for _, tc := range []struct {
+		name, patch, want string
+		err               error
+	}{
+		{"replace member", `{"title":"n"}`, `{"title":"n","tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"null deletes member", `{"title":null}`, `{"tags":["a","b"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"null deletes nested member", `{"meta":{"m~n":null,"c":3}}`, `{"title":"t","tags":["a","b"],"meta":{"a/b":1,"c":3}}`, nil},
+		{"arrays are replaced", `{"tags":["c"]}`, `{"title":"t","tags":["c"],"meta":{"a/b":1,"m~n":2}}`, nil},
+		{"array patch", `["x"]`, "", ErrInvalidPatch},
+		{"string patch", `"x"`, "", ErrInvalidPatch},
+		{"null patch", `null`, "", ErrInvalidPatch},
+		{"malformed", `{`, "", ErrInvalidPatch},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Merge([]byte(doc), []byte(tc.patch))
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("Merge returned %s, %v, want %v", got, err, tc.err)
+				}
+				return
+			}
+			if err != nil || !equalJSON(t, string(got), tc.want) {
+				t.Fatalf("Merge returned %s, %v, want %s", got, err, tc.want)
+			}
+		})
+	}
+}
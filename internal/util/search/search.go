@@ -0,0 +1,391 @@
+package search
+
+import (
+	"errors"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//ErrInvalidQuery is returned for a query without any searchable words or with an unterminated
+//phrase
+var ErrInvalidQuery = errors.New("search query is invalid")
+
+const (
+	//k1 and b are the BM25 term frequency saturation and length normalization parameters
+	k1 = 1.2
+	b  = 0.75
+	//snippetLen is the number of bytes of a field kept around its first match in a highlight
+	snippetLen = 160
+)
+
+//Field is a named piece of text of a document, a match in it scores Boost times a plain match
+//with a zero Boost counting as 1
+type Field struct {
+	Name  string
+	Text  string
+	Boost float64
+}
+
+//Hit is a document matching a query
+type Hit struct {
+	ID    int
+	Score float64
+	//Highlights holds a snippet of every field with a match, HTML escaped with the matched
+	//words wrapped in <mark> tags
+	Highlights map[string]string
+}
+
+//Index is an in-memory inverted index over documents made of text fields. It is safe for
+//concurrent use
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[int]map[string]*field
+	postings map[string]map[int]struct{}
+	//lengths and counts hold the total number of words and the number of documents of each field
+	lengths map[string]int
+	counts  map[string]int
+}
+
+type field struct {
+	text      string
+	boost     float64
+	tokens    []token
+	positions map[string][]int
+}
+
+type token struct {
+	term       string
+	start, end int
+}
+
+//clause is one part of a query, a single word or a phrase of words that must follow each
+//other. With prefix set the last word matches every word it begins
+type clause struct {
+	terms  []string
+	prefix bool
+}
+
+//New creates an empty index
+func New() *Index {
+	return &Index{
+		docs:     map[int]map[string]*field{},
+		postings: map[string]map[int]struct{}{},
+		lengths:  map[string]int{},
+		counts:   map[string]int{},
+	}
+}
+
+//Len returns the number of documents in the index
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+//Put indexes a document under id, replacing any document already there
+func (ix *Index) Put(id int, fields ...Field) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+
+	doc := make(map[string]*field, len(fields))
+	for _, f := range fields {
+		fd := &field{text: f.Text, boost: f.Boost, tokens: tokenize(f.Text), positions: map[string][]int{}}
+		if fd.boost == 0 {
+			fd.boost = 1
+		}
+		for i, t := range fd.tokens {
+			fd.positions[t.term] = append(fd.positions[t.term], i)
+			if ix.postings[t.term] == nil {
+				ix.postings[t.term] = map[int]struct{}{}
+			}
+			ix.postings[t.term][id] = struct{}{}
+		}
+		ix.lengths[f.Name] += len(fd.tokens)
+		ix.counts[f.Name]++
+		doc[f.Name] = fd
+	}
+	ix.docs[id] = doc
+}
+
+//Remove drops the document under id from the index
+func (ix *Index) Remove(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id int) {
+	for name, fd := range ix.docs[id] {
+		for term := range fd.positions {
+			delete(ix.postings[term], id)
+			if len(ix.postings[term]) == 0 {
+				delete(ix.postings, term)
+			}
+		}
+		ix.lengths[name] -= len(fd.tokens)
+		ix.counts[name]--
+	}
+	delete(ix.docs, id)
+}
+
+//Search returns the documents accepted by keep that match every word and phrase of q, best
+//match first. Words are separated by spaces, a phrase is wrapped in double quotes and a word
+//ending in * matches every word it begins. Documents are ranked with BM25 over each field
+func (ix *Index) Search(q string, keep func(id int) bool) ([]Hit, error) {
+	clauses, err := parse(q)
+	if err != nil {
+		return nil, err
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	scores := map[int]float64{}
+	marks := map[int]map[string]map[int]bool{}
+	for i, c := range clauses {
+		//Every document matching the clause is counted toward its idf, even those already
+		//ruled out by an earlier clause
+		matched := map[int]map[string][]int{}
+		for id := range ix.candidates(c) {
+			for name, fd := range ix.docs[id] {
+				if starts := fd.match(c); len(starts) > 0 {
+					if matched[id] == nil {
+						matched[id] = map[string][]int{}
+					}
+					matched[id][name] = starts
+				}
+			}
+		}
+
+		idf := math.Log(1 + (float64(len(ix.docs))-float64(len(matched))+0.5)/(float64(len(matched))+0.5))
+		for id, fields := range matched {
+			if _, ok := scores[id]; !ok && i > 0 {
+				continue
+			}
+			if marks[id] == nil {
+				marks[id] = map[string]map[int]bool{}
+			}
+			for name, starts := range fields {
+				fd := ix.docs[id][name]
+				tf, dl := float64(len(starts)), float64(len(fd.tokens))
+				avgdl := float64(ix.lengths[name]) / float64(ix.counts[name])
+				scores[id] += fd.boost * idf * tf * (k1 + 1) / (tf + k1*(1-b+b*dl/avgdl))
+
+				if marks[id][name] == nil {
+					marks[id][name] = map[int]bool{}
+				}
+				for _, s := range starts {
+					for p := s; p < s+len(c.terms); p++ {
+						marks[id][name][p] = true
+					}
+				}
+			}
+		}
+		//Only the documents matching every clause so far stay in the running
+		for id := range scores {
+			if matched[id] == nil {
+				delete(scores, id)
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		if keep != nil && !keep(id) {
+			continue
+		}
+		h := Hit{ID: id, Score: score, Highlights: map[string]string{}}
+		for name, m := range marks[id] {
+			h.Highlights[name] = ix.docs[id][name].highlight(m)
+		}
+		hits = append(hits, h)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits, nil
+}
+
+//candidates returns the documents containing the first word of a clause, the caller checks
+//the rest of it
+func (ix *Index) candidates(c clause) map[int]struct{} {
+	if !c.prefix || len(c.terms) > 1 {
+		return ix.postings[c.terms[0]]
+	}
+	res := map[int]struct{}{}
+	for term, ids := range ix.postings {
+		if strings.HasPrefix(term, c.terms[0]) {
+			for id := range ids {
+				res[id] = struct{}{}
+			}
+		}
+	}
+	return res
+}
+
+//match returns the position of the first word of every occurrence of a clause in the field
+func (fd *field) match(c clause) []int {
+	last := len(c.terms) - 1
+	firsts := fd.positions[c.terms[0]]
+	if c.prefix && last == 0 {
+		firsts = nil
+		for term, ps := range fd.positions {
+			if strings.HasPrefix(term, c.terms[0]) {
+				firsts = append(firsts, ps...)
+			}
+		}
+		sort.Ints(firsts)
+	}
+
+	var starts []int
+	for _, i := range firsts {
+		if i+last >= len(fd.tokens) {
+			break
+		}
+		ok := true
+		for j := 1; j <= last && ok; j++ {
+			t := fd.tokens[i+j].term
+			if j == last && c.prefix {
+				ok = strings.HasPrefix(t, c.terms[j])
+			} else {
+				ok = t == c.terms[j]
+			}
+		}
+		if ok {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+//highlight cuts a snippet of the field around its first marked word and wraps every marked
+//word in it in <mark> tags
+func (fd *field) highlight(marked map[int]bool) string {
+	first := len(fd.tokens)
+	for p := range marked {
+		if p < first {
+			first = p
+		}
+	}
+
+	start, end := 0, len(fd.text)
+	if end > snippetLen {
+		//Open the snippet a little ahead of the first match, on a word boundary
+		start = fd.tokens[first].start - snippetLen/4
+		if start < 0 {
+			start = 0
+		}
+		for _, t := range fd.tokens {
+			if t.start >= start {
+				start = t.start
+				break
+			}
+		}
+		end = start + snippetLen
+		if end >= len(fd.text) {
+			end = len(fd.text)
+		} else {
+			cut := fd.tokens[first].end
+			for _, t := range fd.tokens {
+				if t.end > end {
+					break
+				}
+				if t.end > cut {
+					cut = t.end
+				}
+			}
+			end = cut
+		}
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	pos := start
+	for i, t := range fd.tokens {
+		if t.start < start || t.end > end || !marked[i] {
+			continue
+		}
+		sb.WriteString(html.EscapeString(fd.text[pos:t.start]))
+		sb.WriteString("<mark>" + html.EscapeString(fd.text[t.start:t.end]) + "</mark>")
+		pos = t.end
+	}
+	sb.WriteString(html.EscapeString(fd.text[pos:end]))
+	if end < len(fd.text) {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
+
+//parse splits a query into its words and quoted phrases. A word that breaks into several
+//terms, like e-mail, is matched as a phrase
+func parse(q string) ([]clause, error) {
+	var clauses []clause
+	for q = strings.TrimSpace(q); q != ""; q = strings.TrimSpace(q) {
+		if q[0] == '"' {
+			end := strings.IndexByte(q[1:], '"')
+			if end < 0 {
+				return nil, ErrInvalidQuery
+			}
+			if terms := terms(q[1 : end+1]); len(terms) > 0 {
+				clauses = append(clauses, clause{terms: terms})
+			}
+			q = q[end+2:]
+			continue
+		}
+		word := q
+		if i := strings.IndexFunc(q, func(r rune) bool { return unicode.IsSpace(r) || r == '"' }); i >= 0 {
+			word = q[:i]
+		}
+		q = q[len(word):]
+		if terms := terms(word); len(terms) > 0 {
+			clauses = append(clauses, clause{terms: terms, prefix: strings.HasSuffix(word, "*")})
+		}
+	}
+	if len(clauses) == 0 {
+		return nil, ErrInvalidQuery
+	}
+	return clauses, nil
+}
+
+func terms(text string) []string {
+	toks := tokenize(text)
+	res := make([]string, len(toks))
+	for i, t := range toks {
+		res[i] = t.term
+	}
+	return res
+}
+
+//tokenize splits text into lower cased runs of letters and digits along with their byte offsets
+func tokenize(text string) []token {
+	var (
+		toks  []token
+		start = -1
+	)
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			toks = append(toks, token{term: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		toks = append(toks, token{term: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return toks
+}
@@ -0,0 +1,252 @@
+package search
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []token
+	}{
+		{"", nil},
+		{"  !? ", nil},
+		{"Hello, World!", []token{{"hello", 0, 5}, {"world", 7, 12}}},
+		{"e-mail in 2024", []token{{"e", 0, 1}, {"mail", 2, 6}, {"in", 7, 9}, {"2024", 10, 14}}},
+		{"go1.22", []token{{"go1", 0, 3}, {"22", 4, 6}}},
+		//Offsets are in bytes and letters of every script are kept
+		{"Café ÜBER Straße", []token{{"café", 0, 5}, {"über", 6, 11}, {"straße", 12, 19}}},
+		{"日本語 text", []token{{"日本語", 0, 9}, {"text", 10, 14}}},
+	} {
+		if got := tokenize(tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("tokenize(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []clause
+		err  error
+	}{
+		{"Go", []clause{{terms: []string{"go"}}}, nil},
+		{"  go   fast ", []clause{{terms: []string{"go"}}, {terms: []string{"fast"}}}, nil},
+		{`"Quick brown" fox`, []clause{{terms: []string{"quick", "brown"}}, {terms: []string{"fox"}}}, nil},
+		{`go"fast"`, []clause{{terms: []string{"go"}}, {terms: []string{"fast"}}}, nil},
+		{"data*", []clause{{terms: []string{"data"}, prefix: true}}, nil},
+		//A word breaking into several terms is a phrase, a * only makes its last term a prefix
+		{"e-mail", []clause{{terms: []string{"e", "mail"}}}, nil},
+		{"e-ma*", []clause{{terms: []string{"e", "ma"}, prefix: true}}, nil},
+		//A quoted * is punctuation
+		{`"data*"`, []clause{{terms: []string{"data"}}}, nil},
+		{`"" go`, []clause{{terms: []string{"go"}}}, nil},
+		{"", nil, ErrInvalidQuery},
+		{"* ! ?", nil, ErrInvalidQuery},
+		{`""`, nil, ErrInvalidQuery},
+		{`"unterminated phrase`, nil, ErrInvalidQuery},
+	} {
+		got, err := parse(tc.in)
+		if err != tc.err || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parse(%q) = %+v, %v, want %+v, %v", tc.in, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+//ids returns the ids of the hits in order
+func ids(hits []Hit) []int {
+	res := make([]int, len(hits))
+	for i, h := range hits {
+		res[i] = h.ID
+	}
+	return res
+}
+
+//search runs a query that must be valid and returns the ids of its hits
+func search(t *testing.T, ix *Index, q string) []int {
+	t.Helper()
+	hits, err := ix.Search(q, nil)
+	if err != nil {
+		t.Fatalf("Search(%q) returned %v", q, err)
+	}
+	return ids(hits)
+}
+
+func TestSearchScoresBM25(t *testing.T) {
+	ix := New()
+	ix.Put(1, Field{Name: "body", Text: "apple"})
+	ix.Put(2, Field{Name: "body", Text: "banana"})
+
+	//One of two documents matches so idf is ln(1 + 1.5/1.5), and with a single match in a
+	//document of average length the term frequency part is 1
+	hits, err := ix.Search("apple", nil)
+	if err != nil {
+		t.Fatalf("Search returned %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != 1 || math.Abs(hits[0].Score-math.Ln2) > 1e-9 {
+		t.Fatalf("Search returned %+v, want document 1 scoring ln 2", hits)
+	}
+
+	//A second match is worth less than the first and a longer document weighs every match less,
+	//so the short document stays ahead
+	ix.Put(3, Field{Name: "body", Text: "apple apple cherry"})
+	hits, _ = ix.Search("apple", nil)
+	idf := math.Log(1 + 1.5/2.5)
+	avgdl := 5.0 / 3
+	want := map[int]float64{
+		1: idf * 1 * (k1 + 1) / (1 + k1*(1-b+b*1/avgdl)),
+		3: idf * 2 * (k1 + 1) / (2 + k1*(1-b+b*3/avgdl)),
+	}
+	for _, h := range hits {
+		if math.Abs(h.Score-want[h.ID]) > 1e-9 {
+			t.Fatalf("document %v scored %v, want %v", h.ID, h.Score, want[h.ID])
+		}
+	}
+	if got := ids(hits); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Fatalf("Search ranked %v, want [1 3]", got)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		docs [][]Field
+		q    string
+		want []int
+	}{
+		{
+			name: "shorter document first at the same frequency",
+			docs: [][]Field{
+				{{Name: "body", Text: "go is a language with a garbage collector and goroutines"}},
+				{{Name: "body", Text: "go rocks"}},
+			},
+			q:    "go",
+			want: []int{2, 1},
+		},
+		{
+			name: "rarer word weighs more",
+			docs: [][]Field{
+				{{Name: "body", Text: "common common rare"}},
+				{{Name: "body", Text: "common rare rare"}},
+				{{Name: "body", Text: "common thing"}},
+				{{Name: "body", Text: "common other"}},
+			},
+			q:    "common rare",
+			want: []int{2, 1},
+		},
+		{
+			name: "boosted field wins",
+			docs: [][]Field{
+				{{Name: "title", Text: "cooking", Boost: 2}, {Name: "body", Text: "all about bread"}},
+				{{Name: "title", Text: "bread", Boost: 2}, {Name: "body", Text: "all about cooking"}},
+			},
+			q:    "bread",
+			want: []int{2, 1},
+		},
+		{
+			name: "ties go to the lower id",
+			docs: [][]Field{
+				{{Name: "body", Text: "same text"}},
+				{{Name: "body", Text: "same text"}},
+			},
+			q:    "same",
+			want: []int{1, 2},
+		},
+		{
+			name: "every word must match",
+			docs: [][]Field{
+				{{Name: "body", Text: "red apple"}},
+				{{Name: "body", Text: "green apple"}},
+				{{Name: "title", Text: "red"}, {Name: "body", Text: "apple pie"}},
+			},
+			q:    "red apple",
+			want: []int{1, 3},
+		},
+	} {
+		ix := New()
+		for i, fields := range tc.docs {
+			ix.Put(i+1, fields...)
+		}
+		if got := search(t, ix, tc.q); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: Search(%q) returned %v, want %v", tc.name, tc.q, got, tc.want)
+		}
+	}
+}
+
+func TestSearchPhrasesAndPrefixes(t *testing.T) {
+	ix := New()
+	ix.Put(1, Field{Name: "body", Text: "The quick brown fox"})
+	ix.Put(2, Field{Name: "body", Text: "A brown and quick fox"})
+	ix.Put(3, Field{Name: "body", Text: "Databases update data"})
+	ix.Put(4, Field{Name: "body", Text: "Send an e-mail"})
+
+	for _, tc := range []struct {
+		q    string
+		want []int
+	}{
+		{`"quick brown"`, []int{1}},
+		{`"brown quick"`, nil},
+		{`"quick brown fox" the`, []int{1}},
+		//Inside quotes a * is punctuation
+		{`"brown fo*"`, nil},
+		{"qui*", []int{1, 2}},
+		{"dat*", []int{3}},
+		{"data", []int{3}},
+		{"upd* dat*", []int{3}},
+		{"date*", nil},
+		{"e-mail", []int{4}},
+		{"email", nil},
+		{"e-m*", []int{4}},
+	} {
+		got := search(t, ix, tc.q)
+		if len(got) == 0 {
+			got = nil
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Search(%q) returned %v, want %v", tc.q, got, tc.want)
+		}
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	ix := New()
+	ix.Put(1, Field{Name: "title", Text: "Quick brown <fox>"}, Field{Name: "body", Text: "no match here"})
+
+	hits, err := ix.Search(`"quick brown" fo*`, nil)
+	if err != nil {
+		t.Fatalf("Search returned %v", err)
+	}
+	want := map[string]string{"title": "<mark>Quick</mark> <mark>brown</mark> &lt;<mark>fox</mark>&gt;"}
+	if len(hits) != 1 || !reflect.DeepEqual(hits[0].Highlights, want) {
+		t.Fatalf("Search returned %+v, want highlights %v", hits, want)
+	}
+}
+
+func TestSearchFiltersAndRemoves(t *testing.T) {
+	ix := New()
+	ix.Put(1, Field{Name: "body", Text: "shared word"})
+	ix.Put(2, Field{Name: "body", Text: "shared word"})
+	ix.Put(3, Field{Name: "body", Text: "shared word"})
+
+	hits, err := ix.Search("shared", func(id int) bool { return id != 2 })
+	if err != nil {
+		t.Fatalf("Search returned %v", err)
+	}
+	if got := ids(hits); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Fatalf("Search with a filter returned %v, want [1 3]", got)
+	}
+
+	ix.Remove(1)
+	ix.Put(3, Field{Name: "body", Text: "replaced"})
+	if got := search(t, ix, "shared"); !reflect.DeepEqual(got, []int{2}) {
+		t.Fatalf("Search after a remove and a replace returned %v, want [2]", got)
+	}
+	if ix.Len() != 2 {
+		t.Fatalf("Len returned %v, want 2", ix.Len())
+	}
+	if _, err := ix.Search(`"open`, nil); err != ErrInvalidQuery {
+		t.Fatalf("Search with an unterminated phrase returned %v, want ErrInvalidQuery", err)
+	}
+}
@@ -0,0 +1,127 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//FieldError describes a single field that failed one of its validation rules
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+//Struct checks every field of the struct v against the comma separated rules in its `validate`
+//tag and returns one FieldError per failing field, named by its json key. The rule required
+//rejects zero values and blank strings, min=n and max=n bound the character count of strings
+//and the value of ints, and oneof=a b c limits strings to the space separated values listed
+func Struct(v interface{}) []FieldError {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+
+	var errs []FieldError
+	for i := 0; i < rt.NumField(); i++ {
+		tag := rt.Field(i).Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		if reason := check(rv.Field(i), tag); reason != "" {
+			errs = append(errs, FieldError{Field: jsonName(rt.Field(i)), Reason: reason})
+		}
+	}
+	return errs
+}
+
+//check applies the rules of tag to the field in order and returns the first failure
+func check(f reflect.Value, tag string) string {
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+		switch name {
+		case "required":
+			if isBlank(f) {
+				return "is required"
+			}
+		case "min":
+			n := mustAtoi(rule, arg)
+			if f.Kind() == reflect.String && utf8.RuneCountInString(f.String()) < n {
+				return fmt.Sprintf("must be at least %v characters", n)
+			}
+			if isInt(f) && f.Int() < int64(n) {
+				return fmt.Sprintf("must be at least %v", n)
+			}
+		case "max":
+			n := mustAtoi(rule, arg)
+			if f.Kind() == reflect.String && utf8.RuneCountInString(f.String()) > n {
+				return fmt.Sprintf("must be at most %v characters", n)
+			}
+			if isInt(f) && f.Int() > int64(n) {
+				return fmt.Sprintf("must be at most %v", n)
+			}
+		case "oneof":
+			if f.Kind() == reflect.String && !contains(strings.Fields(arg), f.String()) {
+				return fmt.Sprintf("must be one of %v", strings.Join(strings.Fields(arg), ", "))
+			}
+		default:
+			panic(fmt.Sprintf("validator: unknown rule %q", rule))
+		}
+	}
+	return ""
+}
+
+func isBlank(f reflect.Value) bool {
+	if f.Kind() == reflect.String {
+		return strings.TrimSpace(f.String()) == ""
+	}
+	return f.IsZero()
+}
+
+func isInt(f reflect.Value) bool {
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func mustAtoi(rule, arg string) int {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		panic(fmt.Sprintf("validator: rule %q needs an integer argument", rule))
+	}
+	return n
+}
+
+//jsonName returns the name a field is encoded under so errors match the request payload
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+//UnknownField returns the name of the field a json.Decoder with DisallowUnknownFields rejected
+//err for, reporting whether err is such a rejection. encoding/json has no typed error for
+//unknown fields, only this message prefix
+func UnknownField(err error) (string, bool) {
+	field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+	if field == err.Error() {
+		return "", false
+	}
+	return strings.Trim(field, `"`), true
+}
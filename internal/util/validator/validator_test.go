@@ -0,0 +1,93 @@
+package validator
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type sample struct {
+	ID     int    `json:"id" validate:"required,min=1,max=10"`
+	Name   string `json:"name,omitempty" validate:"required,min=2,max=5"`
+	Status string `json:"status" validate:"oneof=draft published"`
+	Note   string `validate:"max=3"`
+	Free   string `json:"free"`
+}
+
+func TestStruct(t *testing.T) {
+	valid := sample{ID: 1, Name: "ab", Status: "draft"}
+	for _, tc := range []struct {
+		name string
+		edit func(*sample)
+		want []FieldError
+	}{
+		{"valid", func(s *sample) {}, nil},
+		{"int at the bounds", func(s *sample) { s.ID = 10 }, nil},
+		{"string at the bounds", func(s *sample) { s.Name = "abcde" }, nil},
+		{"required int", func(s *sample) { s.ID = 0 }, []FieldError{{"id", "is required"}}},
+		{"required string", func(s *sample) { s.Name = "" }, []FieldError{{"name", "is required"}}},
+		{"required blank string", func(s *sample) { s.Name = " \t\n" }, []FieldError{{"name", "is required"}}},
+		{"min int", func(s *sample) { s.ID = -3 }, []FieldError{{"id", "must be at least 1"}}},
+		{"max int", func(s *sample) { s.ID = 11 }, []FieldError{{"id", "must be at most 10"}}},
+		{"min string", func(s *sample) { s.Name = "a" }, []FieldError{{"name", "must be at least 2 characters"}}},
+		{"max string", func(s *sample) { s.Name = "abcdef" }, []FieldError{{"name", "must be at most 5 characters"}}},
+		//Lengths are counted in characters, not bytes
+		{"max counts characters", func(s *sample) { s.Name = "ééééé" }, nil},
+		{"oneof", func(s *sample) { s.Status = "archived" }, []FieldError{{"status", "must be one of draft, published"}}},
+		{"oneof empty", func(s *sample) { s.Status = "" }, []FieldError{{"status", "must be one of draft, published"}}},
+		{"untagged json name", func(s *sample) { s.Note = "long" }, []FieldError{{"Note", "must be at most 3 characters"}}},
+		{"no rules", func(s *sample) { s.Free = strings.Repeat("x", 1000) }, nil},
+		{"every failing field in order", func(s *sample) { s.ID, s.Name, s.Status = 0, "", "x" }, []FieldError{
+			{"id", "is required"},
+			{"name", "is required"},
+			{"status", "must be one of draft, published"},
+		}},
+	} {
+		s := valid
+		tc.edit(&s)
+		if got := Struct(s); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: Struct returned %v, want %v", tc.name, got, tc.want)
+		}
+		if got := Struct(&s); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: Struct of a pointer returned %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestStructPanicsOnBadRules(t *testing.T) {
+	for _, v := range []interface{}{
+		struct {
+			A string `validate:"email"`
+		}{},
+		struct {
+			A string `validate:"max=ten"`
+		}{},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Struct(%T) did not panic", v)
+				}
+			}()
+			Struct(v)
+		}()
+	}
+}
+
+func TestUnknownField(t *testing.T) {
+	var v struct {
+		A int `json:"a"`
+	}
+	dec := json.NewDecoder(strings.NewReader(`{"a":1,"extra":2}`))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&v)
+	if field, ok := UnknownField(err); !ok || field != "extra" {
+		t.Fatalf("UnknownField(%v) = %q, %v, want extra", err, field, ok)
+	}
+
+	err = json.Unmarshal([]byte(`{"a":"x"}`), &v)
+	if field, ok := UnknownField(err); ok {
+		t.Fatalf("UnknownField(%v) = %q, want no field", err, field)
+	}
+}